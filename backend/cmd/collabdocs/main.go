@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,7 +14,9 @@ import (
 	"collabdocs/internal/app/usecase"
 	"collabdocs/internal/infrastructure/db"
 	"collabdocs/internal/infrastructure/hub"
+	"collabdocs/internal/infrastructure/oidc"
 	"collabdocs/internal/infrastructure/repo"
+	"collabdocs/internal/infrastructure/webhook"
 	"collabdocs/pkg/config"
 	"collabdocs/pkg/logger"
 	"github.com/go-playground/validator/v10"
@@ -47,13 +50,58 @@ func main() {
 	commentRepo := repo.NewCommentRepo(pool)
 	snapshotRepo := repo.NewSnapshotRepo(pool)
 	updateRepo := repo.NewUpdateRepo(pool)
-
-	docService := usecase.NewDocumentService(docRepo, validate)
-	commentService := usecase.NewCommentService(commentRepo, validate)
-	snapshotService := usecase.NewSnapshotService(snapshotRepo, updateRepo, validate)
+	searchRepo := repo.NewSearchRepo(pool)
+	folderRepo := repo.NewFolderRepo(pool)
+	tagRepo := repo.NewTagRepo(pool)
+	propertyRepo := repo.NewPropertyRepo(pool)
+	userRepo := repo.NewUserRepo(pool)
+	sessionRepo := repo.NewSessionRepo(pool)
+	aclRepo := repo.NewACLRepo(pool)
+	groupRepo := repo.NewGroupRepo(pool)
+	shareLinkRepo := repo.NewShareLinkRepo(pool)
+	apiKeyRepo := repo.NewAPIKeyRepo(pool)
+	notificationRepo := repo.NewNotificationRepo(pool)
+	suggestionRepo := repo.NewSuggestionRepo(pool)
+	webhookRepo := repo.NewWebhookRepo(pool)
 
 	h := hub.NewHub()
-	wsHandler := wsadapter.NewHandler(h, snapshotService, log, cfg.WSMaxBinBytes, cfg.WSMaxTextBytes)
+
+	webhookService := usecase.NewWebhookService(webhookRepo, webhook.NewSender(10*time.Second, cfg.WebhookPrivateIPs), aclRepo, validate)
+	docService := usecase.NewDocumentService(docRepo, snapshotRepo, updateRepo, propertyRepo, folderRepo, userRepo, aclRepo, h, webhookService, validate)
+	notificationService := usecase.NewNotificationService(notificationRepo, userRepo, aclRepo, h, validate)
+	commentService := usecase.NewCommentService(commentRepo, aclRepo, h, notificationService, webhookService, validate)
+	snapshotService := usecase.NewSnapshotService(snapshotRepo, updateRepo, searchRepo, commentRepo, aclRepo, webhookService, validate)
+	suggestionService := usecase.NewSuggestionService(suggestionRepo, snapshotService, aclRepo, h, validate)
+	searchService := usecase.NewSearchService(searchRepo, validate)
+	folderService := usecase.NewFolderService(folderRepo, userRepo, validate)
+	propertyService := usecase.NewPropertyService(propertyRepo, folderRepo, validate)
+	tagService := usecase.NewTagService(tagRepo, aclRepo, h, validate)
+	authService := usecase.NewAuthService(userRepo, sessionRepo, validate, cfg.SessionTTL, strings.Split(cfg.AdminUserIDs, ","))
+	accessService := usecase.NewAccessService(aclRepo, userRepo, groupRepo, h, validate)
+	groupService := usecase.NewGroupService(groupRepo, userRepo, aclRepo, h, validate)
+	var shareService *usecase.ShareLinkService
+	if cfg.ShareLinkSecret != "" {
+		shareService = usecase.NewShareLinkService(shareLinkRepo, aclRepo, h, validate, cfg.ShareLinkSecret, cfg.ShareSessionTTL)
+	} else {
+		log.Warn("share links disabled: SHARE_LINK_SECRET is not set")
+	}
+	apiKeyService := usecase.NewAPIKeyService(apiKeyRepo, validate)
+
+	var oidcService *usecase.OIDCService
+	if cfg.OIDCIssuer != "" {
+		provider := oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       strings.Fields(cfg.OIDCScopes),
+			GroupsClaim:  cfg.OIDCGroupsClaim,
+			CacheTTL:     cfg.OIDCCacheTTL,
+		})
+		oidcService = usecase.NewOIDCService(provider, userRepo, groupRepo, aclRepo, h, authService)
+	}
+
+	wsHandler := wsadapter.NewHandler(h, snapshotService, docService, accessService, notificationService, log, cfg.WSMaxBinBytes, cfg.WSMaxTextBytes, strings.Split(cfg.CORSOrigins, ","))
 
 	router := httpadapter.NewRouter(httpadapter.RouterDeps{
 		Logger:          log,
@@ -61,6 +109,21 @@ func main() {
 		DocService:      docService,
 		CommentService:  commentService,
 		SnapshotService: snapshotService,
+		SearchService:   searchService,
+		FolderService:   folderService,
+		TagService:      tagService,
+		PropertyService: propertyService,
+		AuthService:     authService,
+		AccessService:   accessService,
+		GroupService:    groupService,
+		ShareService:    shareService,
+		APIKeyService:   apiKeyService,
+		NotifyService:   notificationService,
+		SuggestService:  suggestionService,
+		WebhookService:  webhookService,
+		OIDCService:     oidcService,
+		OIDCPostLogin:   cfg.OIDCPostLoginURL,
+		CookieSecure:    cfg.CookieSecure,
 		WSHandler:       wsHandler,
 	})
 
@@ -72,6 +135,9 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	go runTrashPurge(ctx, docService, cfg.TrashRetention, cfg.TrashPurgeInterval, log)
+	go runWebhookDelivery(ctx, webhookService, cfg.WebhookInterval, log)
+
 	go func() {
 		log.Info("server started", zap.String("port", cfg.AppPort))
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -92,3 +158,59 @@ func main() {
 		log.Error("shutdown error", zap.Error(err))
 	}
 }
+
+// runTrashPurge periodically hard-deletes documents whose trash retention
+// has expired, until ctx is cancelled. An interval or retention of zero or
+// less disables the purge.
+func runTrashPurge(ctx context.Context, docService *usecase.DocumentService, retention, interval time.Duration, log *zap.Logger) {
+	if interval <= 0 || retention <= 0 {
+		log.Info("trash purge disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := docService.PurgeTrash(ctx, retention)
+			if err != nil {
+				log.Error("trash purge failed", zap.Error(err))
+				continue
+			}
+			if purged > 0 {
+				log.Info("trash purged", zap.Int64("documents", purged))
+			}
+		}
+	}
+}
+
+// runWebhookDelivery periodically sends the webhook deliveries that are due,
+// until ctx is cancelled. Each tick sends batches until none are left. An
+// interval of zero or less disables delivery.
+func runWebhookDelivery(ctx context.Context, webhookService *usecase.WebhookService, interval time.Duration, log *zap.Logger) {
+	if interval <= 0 {
+		log.Info("webhook delivery disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				sent, err := webhookService.DeliverDue(ctx)
+				if err != nil {
+					log.Error("webhook delivery failed", zap.Error(err))
+					break
+				}
+				if sent == 0 {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"collabdocs/internal/app/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+type AccessHandler struct {
+	service *usecase.AccessService
+}
+
+func NewAccessHandler(service *usecase.AccessService) *AccessHandler {
+	return &AccessHandler{service: service}
+}
+
+type grantAccessRequest struct {
+	UserID  string `json:"userId"`
+	Email   string `json:"email"`
+	GroupID string `json:"groupId"`
+	Role    string `json:"role"`
+}
+
+func (h *AccessHandler) List(w http.ResponseWriter, r *http.Request) {
+	grants, err := h.service.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"grants": grants})
+}
+
+func (h *AccessHandler) Grant(w http.ResponseWriter, r *http.Request) {
+	var req grantAccessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	grant, err := h.service.Grant(r.Context(), usecase.GrantAccessInput{
+		DocID:   chi.URLParam(r, "id"),
+		UserID:  req.UserID,
+		Email:   req.Email,
+		GroupID: req.GroupID,
+		Role:    req.Role,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, grant)
+}
+
+func (h *AccessHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Revoke(r.Context(), usecase.RevokeAccessInput{
+		DocID:   chi.URLParam(r, "id"),
+		GrantID: chi.URLParam(r, "grantId"),
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+func (h *AccessHandler) ClaimOwnerless(w http.ResponseWriter, r *http.Request) {
+	claimed, err := h.service.ClaimOwnerless(r.Context())
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"claimed": claimed})
+}
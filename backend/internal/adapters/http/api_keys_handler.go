@@ -0,0 +1,69 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"collabdocs/internal/app/usecase"
+	"collabdocs/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+type APIKeysHandler struct {
+	service *usecase.APIKeyService
+}
+
+func NewAPIKeysHandler(service *usecase.APIKeyService) *APIKeysHandler {
+	return &APIKeysHandler{service: service}
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	DocIDs    []string   `json:"docIds"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type createAPIKeyResponse struct {
+	domain.APIKey
+	Secret string `json:"secret"`
+}
+
+func (h *APIKeysHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	result, err := h.service.Create(r.Context(), usecase.CreateAPIKeyInput{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		DocIDs:    req.DocIDs,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, createAPIKeyResponse{APIKey: result.Key, Secret: result.Secret})
+}
+
+func (h *APIKeysHandler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.List(r.Context())
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+func (h *APIKeysHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	key, err := h.service.Revoke(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, key)
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"collabdocs/internal/adapters/ws"
+	"collabdocs/internal/app/usecase"
+	"collabdocs/internal/domain"
+)
+
+const sessionCookie = "collabdocs_session"
+
+// requestToken returns the session token from the Authorization header or the
+// session cookie. Tokens are never read from the query string, where they
+// would end up in logs and browser history.
+func requestToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// Authenticate resolves the request's session and stores the user in the
+// context. Requests without a valid session pass through anonymously, and API
+// keys are left to APIKeyAuth.
+func Authenticate(auth *usecase.AuthService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := requestToken(r)
+			if token == "" || strings.HasPrefix(token, usecase.APIKeyPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			user, err := auth.Authenticate(r.Context(), token)
+			if err != nil && err != domain.ErrUnauthorized {
+				writeDomainError(w, err)
+				return
+			}
+			if err == nil {
+				r = r.WithContext(domain.WithUser(r.Context(), user))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKeyAuth resolves an API key sent as a bearer token and stores the key
+// and the user it acts as in the context. Keys are only read from the
+// Authorization header; unknown, revoked and expired keys are rejected with
+// 401.
+func APIKeyAuth(keys *usecase.APIKeyService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			token = strings.TrimSpace(token)
+			if !ok || !strings.HasPrefix(token, usecase.APIKeyPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key, user, err := keys.Authenticate(r.Context(), token)
+			if err != nil {
+				writeDomainError(w, err)
+				return
+			}
+			ctx := domain.WithAPIKey(domain.WithUser(r.Context(), user), key)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RejectAPIKey refuses requests made with an API key, for routes keys have no
+// scope for.
+func RejectAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := domain.APIKeyFromContext(r.Context()); ok {
+			writeDomainError(w, domain.ErrForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ShareSession resolves a share session sent in the X-Share-Token header, or
+// on WebSocket upgrades as a subprotocol after ws.ShareProtocol, and stores
+// the access it grants in the context. Tokens are never read from the query
+// string. Each such request is logged against the link; invalid sessions are
+// rejected with 401. When share links are disabled, shares is nil and the
+// token is ignored.
+func ShareSession(shares *usecase.ShareLinkService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if shares == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get("X-Share-Token")
+			if token == "" {
+				token = ws.ShareToken(r)
+			}
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			access, err := shares.Resolve(r.Context(), token, usecase.ShareRequest{
+				Action:    r.Method + " " + r.URL.Path,
+				IP:        clientIP(r),
+				UserAgent: r.UserAgent(),
+			})
+			if err != nil {
+				writeDomainError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(domain.WithShareAccess(r.Context(), access)))
+		})
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RequireUserOrShare rejects requests that carry neither a session nor a
+// share session. The usecases decide what a share session may reach.
+func RequireUserOrShare(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, signedIn := domain.UserFromContext(r.Context())
+		_, shared := domain.ShareAccessFromContext(r.Context())
+		if !signedIn && !shared {
+			writeDomainError(w, domain.ErrUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireUser rejects anonymous requests with 401.
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := domain.UserFromContext(r.Context()); !ok {
+			writeDomainError(w, domain.ErrUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
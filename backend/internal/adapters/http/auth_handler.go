@@ -0,0 +1,115 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"collabdocs/internal/app/usecase"
+	"collabdocs/internal/domain"
+)
+
+type AuthHandler struct {
+	service      *usecase.AuthService
+	cookieSecure bool
+}
+
+func NewAuthHandler(service *usecase.AuthService, cookieSecure bool) *AuthHandler {
+	return &AuthHandler{service: service, cookieSecure: cookieSecure}
+}
+
+type registerRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	User      domain.User `json:"user"`
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expiresAt"`
+}
+
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req registerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	user, err := h.service.Register(r.Context(), usecase.RegisterInput{
+		Email:    req.Email,
+		Name:     req.Name,
+		Password: req.Password,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+}
+
+// Login opens a session. The token is set as an HTTP-only cookie for browsers
+// and returned in the body for clients using bearer authentication.
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	res, err := h.service.Login(r.Context(), usecase.LoginInput{Email: req.Email, Password: req.Password})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	h.writeSession(w, res)
+}
+
+func (h *AuthHandler) writeSession(w http.ResponseWriter, res usecase.LoginResult) {
+	setSessionCookie(w, res, h.cookieSecure)
+	writeJSON(w, http.StatusOK, loginResponse{User: res.User, Token: res.Token, ExpiresAt: res.ExpiresAt})
+}
+
+func setSessionCookie(w http.ResponseWriter, res usecase.LoginResult, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    res.Token,
+		Path:     "/",
+		Expires:  res.ExpiresAt,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Logout(r.Context(), requestToken(r)); err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.cookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "logged_out"})
+}
+
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	user, ok := domain.UserFromContext(r.Context())
+	if !ok {
+		writeDomainError(w, domain.ErrUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+}
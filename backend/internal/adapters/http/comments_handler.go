@@ -1,10 +1,13 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
 
 	"collabdocs/internal/app/usecase"
+	"collabdocs/internal/domain"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -17,9 +20,11 @@ func NewCommentsHandler(service *usecase.CommentService) *CommentsHandler {
 }
 
 type createCommentRequest struct {
-	AuthorName string `json:"authorName"`
-	FromPos    int    `json:"fromPos"`
-	ToPos      int    `json:"toPos"`
+	FromPos int `json:"fromPos"`
+	ToPos   int `json:"toPos"`
+	// FromAnchor and ToAnchor are base64 encoded Yjs relative positions.
+	FromAnchor []byte `json:"fromAnchor"`
+	ToAnchor   []byte `json:"toAnchor"`
 	Text       string `json:"text"`
 }
 
@@ -29,13 +34,51 @@ type updateCommentRequest struct {
 }
 
 func (h *CommentsHandler) List(w http.ResponseWriter, r *http.Request) {
-	docID := chi.URLParam(r, "id")
-	comments, err := h.service.ListByDoc(r.Context(), docID)
+	query := r.URL.Query()
+	input := usecase.ListCommentsInput{
+		DocID:    chi.URLParam(r, "id"),
+		AuthorID: query.Get("authorId"),
+		Query:    query.Get("q"),
+		Cursor:   query.Get("cursor"),
+	}
+	var err error
+	if input.Resolved, err = boolParam(query.Get("resolved")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid resolved")
+		return
+	}
+	if input.CreatedAfter, err = timeParam(query.Get("createdAfter")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid createdAfter")
+		return
+	}
+	if input.FromPos, err = optionalIntParam(query.Get("from")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid from")
+		return
+	}
+	if input.ToPos, err = optionalIntParam(query.Get("to")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid to")
+		return
+	}
+	if input.Limit, err = intParam(query.Get("limit")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid limit")
+		return
+	}
+
+	page, err := h.service.ListByDoc(r.Context(), input)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *CommentsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	comment, err := h.service.Get(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "commentId"))
 	if err != nil {
 		writeDomainError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"comments": comments})
+	setETag(w, comment.Version)
+	writeJSON(w, http.StatusOK, map[string]any{"comment": comment})
 }
 
 func (h *CommentsHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -48,21 +91,28 @@ func (h *CommentsHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	comment, err := h.service.Create(r.Context(), usecase.CreateCommentInput{
 		DocID:      docID,
-		AuthorName: req.AuthorName,
 		FromPos:    req.FromPos,
 		ToPos:      req.ToPos,
+		FromAnchor: req.FromAnchor,
+		ToAnchor:   req.ToAnchor,
 		Text:       req.Text,
 	})
 	if err != nil {
 		writeDomainError(w, err)
 		return
 	}
+	setETag(w, comment.Version)
 	writeJSON(w, http.StatusOK, map[string]any{"comment": comment})
 }
 
 func (h *CommentsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	docID := chi.URLParam(r, "id")
 	commentID := chi.URLParam(r, "commentId")
+	ifVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid If-Match")
+		return
+	}
 	var req updateCommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
@@ -74,10 +124,131 @@ func (h *CommentsHandler) Update(w http.ResponseWriter, r *http.Request) {
 		CommentID: commentID,
 		Resolved:  req.Resolved,
 		Text:      req.Text,
+		IfVersion: ifVersion,
 	})
 	if err != nil {
 		writeDomainError(w, err)
 		return
 	}
+	setETag(w, comment.Version)
 	writeJSON(w, http.StatusOK, map[string]any{"comment": comment})
 }
+
+type replyRequest struct {
+	Text string `json:"text"`
+}
+
+func (h *CommentsHandler) ListReplies(w http.ResponseWriter, r *http.Request) {
+	replies, err := h.service.ListReplies(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "commentId"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"replies": replies})
+}
+
+func (h *CommentsHandler) CreateReply(w http.ResponseWriter, r *http.Request) {
+	var req replyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	reply, err := h.service.CreateReply(r.Context(), usecase.CreateReplyInput{
+		DocID:     chi.URLParam(r, "id"),
+		CommentID: chi.URLParam(r, "commentId"),
+		Text:      req.Text,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	setETag(w, reply.Version)
+	writeJSON(w, http.StatusOK, map[string]any{"reply": reply})
+}
+
+func (h *CommentsHandler) UpdateReply(w http.ResponseWriter, r *http.Request) {
+	ifVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid If-Match")
+		return
+	}
+	var req replyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	reply, err := h.service.UpdateReply(r.Context(), usecase.UpdateReplyInput{
+		DocID:     chi.URLParam(r, "id"),
+		CommentID: chi.URLParam(r, "commentId"),
+		ReplyID:   chi.URLParam(r, "replyId"),
+		Text:      req.Text,
+		IfVersion: ifVersion,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	setETag(w, reply.Version)
+	writeJSON(w, http.StatusOK, map[string]any{"reply": reply})
+}
+
+func (h *CommentsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Delete(r.Context(), usecase.DeleteCommentInput{
+		DocID:     chi.URLParam(r, "id"),
+		CommentID: chi.URLParam(r, "commentId"),
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (h *CommentsHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Purge(r.Context(), usecase.DeleteCommentInput{
+		DocID:     chi.URLParam(r, "id"),
+		CommentID: chi.URLParam(r, "commentId"),
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "purged"})
+}
+
+func (h *CommentsHandler) AddReaction(w http.ResponseWriter, r *http.Request) {
+	h.react(w, r, h.service.AddReaction)
+}
+
+func (h *CommentsHandler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	h.react(w, r, h.service.RemoveReaction)
+}
+
+func (h *CommentsHandler) react(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, input usecase.ReactionInput) ([]domain.Reaction, error)) {
+	emoji, err := url.PathUnescape(chi.URLParam(r, "emoji"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid emoji")
+		return
+	}
+	reactions, err := change(r.Context(), usecase.ReactionInput{
+		DocID:     chi.URLParam(r, "id"),
+		CommentID: chi.URLParam(r, "commentId"),
+		Emoji:     emoji,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reactions": reactions})
+}
+
+func (h *CommentsHandler) History(w http.ResponseWriter, r *http.Request) {
+	history, err := h.service.History(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "commentId"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
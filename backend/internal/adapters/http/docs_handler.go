@@ -2,7 +2,12 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"collabdocs/internal/app/usecase"
 	"collabdocs/internal/domain"
@@ -18,11 +23,30 @@ func NewDocsHandler(service *usecase.DocumentService) *DocsHandler {
 }
 
 type createDocRequest struct {
-	Title string `json:"title"`
+	Title      string            `json:"title"`
+	FolderID   string            `json:"folderId"`
+	TemplateID string            `json:"templateId"`
+	Author     string            `json:"author"`
+	Variables  map[string]string `json:"variables"`
 }
 
 type updateDocRequest struct {
-	Title string `json:"title"`
+	Title      *string        `json:"title"`
+	Properties map[string]any `json:"properties"`
+}
+
+type moveDocRequest struct {
+	FolderID string `json:"folderId"`
+}
+
+type docStateRequest struct {
+	Reason        string `json:"reason"`
+	AllowComments bool   `json:"allowComments"`
+}
+
+type duplicateDocRequest struct {
+	Title           string `json:"title"`
+	IncludeComments bool   `json:"includeComments"`
 }
 
 func (h *DocsHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -32,15 +56,115 @@ func (h *DocsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	doc, err := h.service.Create(r.Context(), usecase.CreateDocumentInput{Title: req.Title})
+	doc, err := h.service.Create(r.Context(), usecase.CreateDocumentInput{
+		Title:      req.Title,
+		FolderID:   req.FolderID,
+		TemplateID: req.TemplateID,
+		Author:     req.Author,
+		Variables:  req.Variables,
+	})
 	if err != nil {
 		writeDomainError(w, err)
 		return
 	}
 
+	setETag(w, doc.Version)
 	writeJSON(w, http.StatusOK, doc)
 }
 
+func (h *DocsHandler) List(w http.ResponseWriter, r *http.Request) {
+	input, ok := listInputFromQuery(w, r)
+	if !ok {
+		return
+	}
+	h.list(w, r, input)
+}
+
+func (h *DocsHandler) Trash(w http.ResponseWriter, r *http.Request) {
+	input, ok := listInputFromQuery(w, r)
+	if !ok {
+		return
+	}
+	input.Trashed = true
+	h.list(w, r, input)
+}
+
+func (h *DocsHandler) Templates(w http.ResponseWriter, r *http.Request) {
+	input, ok := listInputFromQuery(w, r)
+	if !ok {
+		return
+	}
+	isTemplate := true
+	input.IsTemplate = &isTemplate
+	h.list(w, r, input)
+}
+
+func (h *DocsHandler) list(w http.ResponseWriter, r *http.Request, input usecase.ListDocumentsInput) {
+	page, err := h.service.List(r.Context(), input)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// listInputFromQuery parses listing filters from the query string and writes
+// a 400 response when one is malformed.
+func listInputFromQuery(w http.ResponseWriter, r *http.Request) (usecase.ListDocumentsInput, bool) {
+	query := r.URL.Query()
+	input := usecase.ListDocumentsInput{
+		Sort:        query.Get("sort"),
+		Order:       query.Get("order"),
+		FolderID:    query.Get("folderId"),
+		Tags:        query["tag"],
+		TagMode:     query.Get("tagMode"),
+		Properties:  propertyFiltersFromQuery(query),
+		TitlePrefix: query.Get("titlePrefix"),
+		Cursor:      query.Get("cursor"),
+	}
+	var err error
+	if input.IsTemplate, err = boolParam(query.Get("template")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid template")
+		return input, false
+	}
+	if input.Limit, err = intParam(query.Get("limit")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid limit")
+		return input, false
+	}
+	times := []struct {
+		name string
+		dst  **time.Time
+	}{
+		{"createdAfter", &input.CreatedAfter},
+		{"createdBefore", &input.CreatedBefore},
+		{"updatedAfter", &input.UpdatedAfter},
+		{"updatedBefore", &input.UpdatedBefore},
+	}
+	for _, t := range times {
+		if *t.dst, err = timeParam(query.Get(t.name)); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_input", "Invalid "+t.name)
+			return input, false
+		}
+	}
+	return input, true
+}
+
+// propertyFiltersFromQuery collects prop.<key>[.<op>]=<value> parameters.
+func propertyFiltersFromQuery(query url.Values) []usecase.PropertyFilterInput {
+	var filters []usecase.PropertyFilterInput
+	for name, values := range query {
+		rest, ok := strings.CutPrefix(name, "prop.")
+		if !ok {
+			continue
+		}
+		key, op, _ := strings.Cut(rest, ".")
+		for _, v := range values {
+			filters = append(filters, usecase.PropertyFilterInput{Key: key, Op: op, Value: v})
+		}
+	}
+	return filters
+}
+
 func (h *DocsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	doc, err := h.service.Get(r.Context(), id)
@@ -48,40 +172,210 @@ func (h *DocsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		writeDomainError(w, err)
 		return
 	}
+	setETag(w, doc.Version)
 	writeJSON(w, http.StatusOK, doc)
 }
 
 func (h *DocsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	ifVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid If-Match")
+		return
+	}
 	var req updateDocRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
 		return
 	}
 
-	doc, err := h.service.UpdateTitle(r.Context(), usecase.UpdateDocumentInput{ID: id, Title: req.Title})
+	doc, err := h.service.Update(r.Context(), usecase.UpdateDocumentInput{
+		ID:         id,
+		Title:      req.Title,
+		Properties: req.Properties,
+		IfVersion:  ifVersion,
+	})
 	if err != nil {
 		writeDomainError(w, err)
 		return
 	}
+	setETag(w, doc.Version)
 	writeJSON(w, http.StatusOK, doc)
 }
 
 func (h *DocsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	if err := h.service.Delete(r.Context(), usecase.DeleteDocumentInput{ID: id}); err != nil {
+	ifVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid If-Match")
+		return
+	}
+	if err := h.service.Delete(r.Context(), usecase.DeleteDocumentInput{ID: id, IfVersion: ifVersion}); err != nil {
 		writeDomainError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+func (h *DocsHandler) Duplicate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req duplicateDocRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	doc, err := h.service.Duplicate(r.Context(), usecase.DuplicateDocumentInput{
+		ID:              id,
+		Title:           req.Title,
+		IncludeComments: req.IncludeComments,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	setETag(w, doc.Version)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (h *DocsHandler) Move(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req moveDocRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	doc, err := h.service.Move(r.Context(), usecase.MoveDocumentInput{ID: id, FolderID: req.FolderID})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	setETag(w, doc.Version)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (h *DocsHandler) MarkTemplate(w http.ResponseWriter, r *http.Request) {
+	h.setTemplate(w, r, true)
+}
+
+func (h *DocsHandler) UnmarkTemplate(w http.ResponseWriter, r *http.Request) {
+	h.setTemplate(w, r, false)
+}
+
+func (h *DocsHandler) setTemplate(w http.ResponseWriter, r *http.Request, isTemplate bool) {
+	id := chi.URLParam(r, "id")
+	doc, err := h.service.SetTemplate(r.Context(), usecase.SetTemplateInput{ID: id, IsTemplate: isTemplate})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	setETag(w, doc.Version)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (h *DocsHandler) Lock(w http.ResponseWriter, r *http.Request) {
+	var req docStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	doc, err := h.service.Lock(r.Context(), usecase.LockDocumentInput{
+		ID:            chi.URLParam(r, "id"),
+		AllowComments: req.AllowComments,
+		Reason:        req.Reason,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	setETag(w, doc.Version)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (h *DocsHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	var req docStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	doc, err := h.service.Archive(r.Context(), usecase.ArchiveDocumentInput{
+		ID:     chi.URLParam(r, "id"),
+		Reason: req.Reason,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	setETag(w, doc.Version)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (h *DocsHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	var req docStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	doc, err := h.service.Unlock(r.Context(), usecase.UnlockDocumentInput{
+		ID:     chi.URLParam(r, "id"),
+		Reason: req.Reason,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	setETag(w, doc.Version)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (h *DocsHandler) Audit(w http.ResponseWriter, r *http.Request) {
+	events, err := h.service.ListStateEvents(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"events": events})
+}
+
+func (h *DocsHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	doc, err := h.service.Restore(r.Context(), usecase.RestoreDocumentInput{ID: id})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	setETag(w, doc.Version)
+	writeJSON(w, http.StatusOK, doc)
+}
+
 func writeDomainError(w http.ResponseWriter, err error) {
+	var conflict *domain.VersionConflictError
+	if errors.As(err, &conflict) {
+		setETag(w, conflict.Version)
+		writeJSON(w, http.StatusConflict, conflictResponse{
+			Error:   errorPayload{Code: "version_conflict", Message: "Resource has been modified"},
+			Current: conflict.Current,
+		})
+		return
+	}
+
 	switch err {
 	case domain.ErrInvalidInput:
 		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid input")
 	case domain.ErrNotFound:
 		writeError(w, http.StatusNotFound, "not_found", "Not found")
+	case domain.ErrUnauthorized:
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+	case domain.ErrForbidden:
+		writeError(w, http.StatusForbidden, "forbidden", "Not allowed")
+	case domain.ErrConflict:
+		writeError(w, http.StatusConflict, "conflict", "Conflict")
+	case domain.ErrReadOnly:
+		writeError(w, http.StatusConflict, "read_only", "Document is read-only")
 	default:
 		writeError(w, http.StatusInternalServerError, "internal_error", "Internal server error")
 	}
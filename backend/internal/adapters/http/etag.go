@@ -0,0 +1,38 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errInvalidIfMatch = errors.New("invalid If-Match")
+
+type conflictResponse struct {
+	Error   errorPayload `json:"error"`
+	Current any          `json:"current"`
+}
+
+// setETag exposes a resource version as a strong entity tag.
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", `"`+strconv.FormatInt(version, 10)+`"`)
+}
+
+// ifMatchVersion reads the version a write is conditional on. An absent
+// header or "*" means the write is unconditional. Weak tags and tag lists are
+// rejected, as If-Match requires strong comparison against a single version.
+func ifMatchVersion(r *http.Request) (*int64, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return nil, nil
+	}
+	if len(value) < 3 || value[0] != '"' || value[len(value)-1] != '"' {
+		return nil, errInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(value[1:len(value)-1], 10, 64)
+	if err != nil || version < 1 {
+		return nil, errInvalidIfMatch
+	}
+	return &version, nil
+}
@@ -0,0 +1,203 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"collabdocs/internal/app/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+type FoldersHandler struct {
+	service    *usecase.FolderService
+	docService *usecase.DocumentService
+}
+
+func NewFoldersHandler(service *usecase.FolderService, docService *usecase.DocumentService) *FoldersHandler {
+	return &FoldersHandler{service: service, docService: docService}
+}
+
+type createWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type createFolderRequest struct {
+	ParentID string `json:"parentId"`
+	Name     string `json:"name"`
+}
+
+type renameFolderRequest struct {
+	Name string `json:"name"`
+}
+
+type moveFolderRequest struct {
+	ParentID string `json:"parentId"`
+}
+
+type addWorkspaceMemberRequest struct {
+	UserID string `json:"userId"`
+	Email  string `json:"email"`
+}
+
+func (h *FoldersHandler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	var req createWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	workspace, err := h.service.CreateWorkspace(r.Context(), usecase.CreateWorkspaceInput{Name: req.Name})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, workspace)
+}
+
+func (h *FoldersHandler) ListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	workspaces, err := h.service.ListWorkspaces(r.Context())
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"workspaces": workspaces})
+}
+
+func (h *FoldersHandler) GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	workspace, err := h.service.GetWorkspace(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, workspace)
+}
+
+func (h *FoldersHandler) Members(w http.ResponseWriter, r *http.Request) {
+	members, err := h.service.ListMembers(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"members": members})
+}
+
+func (h *FoldersHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	var req addWorkspaceMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	err := h.service.AddMember(r.Context(), usecase.AddWorkspaceMemberInput{
+		WorkspaceID: chi.URLParam(r, "id"),
+		UserID:      req.UserID,
+		Email:       req.Email,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "added"})
+}
+
+func (h *FoldersHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	err := h.service.RemoveMember(r.Context(), usecase.RemoveWorkspaceMemberInput{
+		WorkspaceID: chi.URLParam(r, "id"),
+		UserID:      chi.URLParam(r, "userId"),
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
+
+func (h *FoldersHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req createFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	folder, err := h.service.CreateFolder(r.Context(), usecase.CreateFolderInput{ParentID: req.ParentID, Name: req.Name})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, folder)
+}
+
+func (h *FoldersHandler) Get(w http.ResponseWriter, r *http.Request) {
+	folder, err := h.service.GetFolder(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, folder)
+}
+
+// Contents lists the subfolders of a folder and a page of its documents.
+// Document listing accepts the same query parameters as GET /docs.
+func (h *FoldersHandler) Contents(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	input, ok := listInputFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	folder, err := h.service.GetFolder(r.Context(), id)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	folders, err := h.service.ListSubfolders(r.Context(), id)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	input.FolderID = folder.ID
+	docs, err := h.docService.List(r.Context(), input)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"folder": folder, "folders": folders, "documents": docs})
+}
+
+func (h *FoldersHandler) Rename(w http.ResponseWriter, r *http.Request) {
+	var req renameFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	folder, err := h.service.RenameFolder(r.Context(), usecase.RenameFolderInput{ID: chi.URLParam(r, "id"), Name: req.Name})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, folder)
+}
+
+func (h *FoldersHandler) Move(w http.ResponseWriter, r *http.Request) {
+	var req moveFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	folder, err := h.service.MoveFolder(r.Context(), usecase.MoveFolderInput{ID: chi.URLParam(r, "id"), ParentID: req.ParentID})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, folder)
+}
+
+func (h *FoldersHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteFolder(r.Context(), usecase.DeleteFolderInput{ID: chi.URLParam(r, "id")}); err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
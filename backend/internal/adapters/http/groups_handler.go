@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"collabdocs/internal/app/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+type GroupsHandler struct {
+	service *usecase.GroupService
+}
+
+func NewGroupsHandler(service *usecase.GroupService) *GroupsHandler {
+	return &GroupsHandler{service: service}
+}
+
+type createGroupRequest struct {
+	Name string `json:"name"`
+}
+
+type addGroupMemberRequest struct {
+	UserID string `json:"userId"`
+	Email  string `json:"email"`
+}
+
+func (h *GroupsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req createGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	group, err := h.service.Create(r.Context(), usecase.CreateGroupInput{Name: req.Name})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, group)
+}
+
+func (h *GroupsHandler) List(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.service.List(r.Context())
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"groups": groups})
+}
+
+func (h *GroupsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	group, members, err := h.service.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"group": group, "members": members})
+}
+
+func (h *GroupsHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	var req addGroupMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	err := h.service.AddMember(r.Context(), usecase.AddGroupMemberInput{
+		GroupID: chi.URLParam(r, "id"),
+		UserID:  req.UserID,
+		Email:   req.Email,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "added"})
+}
+
+func (h *GroupsHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	err := h.service.RemoveMember(r.Context(), usecase.RemoveGroupMemberInput{
+		GroupID: chi.URLParam(r, "id"),
+		UserID:  chi.URLParam(r, "userId"),
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"net/http"
+
+	"collabdocs/internal/app/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+type NotificationsHandler struct {
+	service *usecase.NotificationService
+}
+
+func NewNotificationsHandler(service *usecase.NotificationService) *NotificationsHandler {
+	return &NotificationsHandler{service: service}
+}
+
+func (h *NotificationsHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	input := usecase.ListNotificationsInput{Cursor: query.Get("cursor")}
+	unread, err := boolParam(query.Get("unread"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid unread")
+		return
+	}
+	input.UnreadOnly = unread != nil && *unread
+	if input.Limit, err = intParam(query.Get("limit")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid limit")
+		return
+	}
+
+	page, err := h.service.List(r.Context(), input)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *NotificationsHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	n, err := h.service.MarkRead(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, n)
+}
+
+func (h *NotificationsHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.MarkAllRead(r.Context()); err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"collabdocs/internal/app/usecase"
+	"collabdocs/internal/domain"
+	"go.uber.org/zap"
+)
+
+// oidcCookie keeps the state, nonce and PKCE verifier of a sign-in in
+// progress until the provider redirects back.
+const oidcCookie = "collabdocs_oidc"
+
+const oidcCookiePath = "/auth/oidc"
+
+type OIDCHandler struct {
+	service      *usecase.OIDCService
+	cookieSecure bool
+	// postLoginURL is where the browser lands after signing in.
+	postLoginURL string
+	log          *zap.Logger
+}
+
+func NewOIDCHandler(service *usecase.OIDCService, cookieSecure bool, postLoginURL string, log *zap.Logger) *OIDCHandler {
+	return &OIDCHandler{service: service, cookieSecure: cookieSecure, postLoginURL: postLoginURL, log: log}
+}
+
+type pendingLogin struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	Redirect string `json:"r,omitempty"`
+	Link     string `json:"l,omitempty"`
+}
+
+// Login sends the browser to the identity provider. An optional redirect
+// query parameter names a path to return to after signing in. A signed-in
+// user links the provider account to themselves instead.
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	redirect := r.URL.Query().Get("redirect")
+	if redirect != "" && !isLocalPath(redirect) {
+		writeDomainError(w, domain.ErrInvalidInput)
+		return
+	}
+	login, err := h.service.Begin(r.Context())
+	if err != nil {
+		h.log.Error("oidc login failed", zap.Error(err))
+		writeDomainError(w, err)
+		return
+	}
+
+	value, err := json.Marshal(pendingLogin{State: login.State, Nonce: login.Nonce, Verifier: login.Verifier, Redirect: redirect, Link: login.LinkUserID})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     oidcCookiePath,
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   h.cookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, login.URL, http.StatusFound)
+}
+
+// Callback completes the sign-in the provider redirected back from, opens a
+// session and sends the browser on to the app.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	var pending pendingLogin
+	if cookie, err := r.Cookie(oidcCookie); err == nil {
+		if raw, err := base64.RawURLEncoding.DecodeString(cookie.Value); err == nil {
+			_ = json.Unmarshal(raw, &pending)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    "",
+		Path:     oidcCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.cookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := r.URL.Query()
+	res, err := h.service.Complete(r.Context(), usecase.OIDCCallbackInput{
+		Code:  query.Get("code"),
+		State: query.Get("state"),
+		Error: query.Get("error"),
+		Login: usecase.OIDCLogin{State: pending.State, Nonce: pending.Nonce, Verifier: pending.Verifier, LinkUserID: pending.Link},
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrUnauthorized) {
+			h.log.Warn("oidc sign-in rejected", zap.Error(err))
+			err = domain.ErrUnauthorized
+		} else if err != domain.ErrConflict {
+			h.log.Error("oidc sign-in failed", zap.Error(err))
+		}
+		writeDomainError(w, err)
+		return
+	}
+	setSessionCookie(w, res, h.cookieSecure)
+
+	target := h.postLoginURL
+	if isLocalPath(pending.Redirect) {
+		target = strings.TrimSuffix(target, "/") + pending.Redirect
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// isLocalPath accepts absolute paths only, so redirects cannot leave the app.
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "/\\")
+}
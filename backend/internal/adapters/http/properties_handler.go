@@ -0,0 +1,91 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"collabdocs/internal/app/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+type PropertiesHandler struct {
+	service *usecase.PropertyService
+}
+
+func NewPropertiesHandler(service *usecase.PropertyService) *PropertiesHandler {
+	return &PropertiesHandler{service: service}
+}
+
+type createPropertyRequest struct {
+	Key     string   `json:"key"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Options []string `json:"options"`
+}
+
+type updatePropertyRequest struct {
+	Name    string   `json:"name"`
+	Options []string `json:"options"`
+}
+
+func (h *PropertiesHandler) List(w http.ResponseWriter, r *http.Request) {
+	defs, err := h.service.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"properties": defs})
+}
+
+func (h *PropertiesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req createPropertyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	def, err := h.service.Create(r.Context(), usecase.CreatePropertyInput{
+		WorkspaceID: chi.URLParam(r, "id"),
+		Key:         req.Key,
+		Name:        req.Name,
+		Type:        req.Type,
+		Options:     req.Options,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, def)
+}
+
+func (h *PropertiesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req updatePropertyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	def, err := h.service.Update(r.Context(), usecase.UpdatePropertyInput{
+		WorkspaceID: chi.URLParam(r, "id"),
+		Key:         chi.URLParam(r, "key"),
+		Name:        req.Name,
+		Options:     req.Options,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, def)
+}
+
+func (h *PropertiesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Delete(r.Context(), usecase.DeletePropertyInput{
+		WorkspaceID: chi.URLParam(r, "id"),
+		Key:         chi.URLParam(r, "key"),
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
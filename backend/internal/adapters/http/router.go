@@ -20,7 +20,24 @@ type RouterDeps struct {
 	DocService      *usecase.DocumentService
 	CommentService  *usecase.CommentService
 	SnapshotService *usecase.SnapshotService
-	WSHandler       *ws.Handler
+	SearchService   *usecase.SearchService
+	FolderService   *usecase.FolderService
+	TagService      *usecase.TagService
+	PropertyService *usecase.PropertyService
+	AuthService     *usecase.AuthService
+	AccessService   *usecase.AccessService
+	GroupService    *usecase.GroupService
+	// ShareService is nil when share links are disabled.
+	ShareService   *usecase.ShareLinkService
+	APIKeyService  *usecase.APIKeyService
+	NotifyService  *usecase.NotificationService
+	SuggestService *usecase.SuggestionService
+	WebhookService *usecase.WebhookService
+	// OIDCService is nil when single sign-on is not configured.
+	OIDCService   *usecase.OIDCService
+	OIDCPostLogin string
+	CookieSecure  bool
+	WSHandler     *ws.Handler
 }
 
 func NewRouter(deps RouterDeps) http.Handler {
@@ -33,8 +50,9 @@ func NewRouter(deps RouterDeps) http.Handler {
 	origins := strings.Split(deps.CORSOrigins, ",")
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   origins,
-		AllowedMethods:   []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "X-Share-Token"},
+		ExposedHeaders:   []string{"ETag"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -50,23 +68,163 @@ func NewRouter(deps RouterDeps) http.Handler {
 
 	docsHandler := NewDocsHandler(deps.DocService)
 	commentsHandler := NewCommentsHandler(deps.CommentService)
+	searchHandler := NewSearchHandler(deps.SearchService)
+	foldersHandler := NewFoldersHandler(deps.FolderService, deps.DocService)
+	tagsHandler := NewTagsHandler(deps.TagService)
+	propertiesHandler := NewPropertiesHandler(deps.PropertyService)
+	authHandler := NewAuthHandler(deps.AuthService, deps.CookieSecure)
+	accessHandler := NewAccessHandler(deps.AccessService)
+	groupsHandler := NewGroupsHandler(deps.GroupService)
+	snapshotsHandler := NewSnapshotsHandler(deps.SnapshotService)
+	apiKeysHandler := NewAPIKeysHandler(deps.APIKeyService)
+	notificationsHandler := NewNotificationsHandler(deps.NotifyService)
+	suggestionsHandler := NewSuggestionsHandler(deps.SuggestService)
+	webhooksHandler := NewWebhooksHandler(deps.WebhookService)
 
 	rest := chi.NewRouter()
 	rest.Use(middleware.Timeout(15 * time.Second))
+	rest.Use(APIKeyAuth(deps.APIKeyService))
+	rest.Use(Authenticate(deps.AuthService))
 
-	rest.Route("/docs", func(r chi.Router) {
-		r.Post("/", docsHandler.Create)
-		r.Get("/{id}", docsHandler.Get)
-		r.Patch("/{id}", docsHandler.Update)
-		r.Delete("/{id}", docsHandler.Delete)
+	rest.Route("/auth", func(r chi.Router) {
+		r.Post("/register", authHandler.Register)
+		r.Post("/login", authHandler.Login)
+		r.Post("/logout", authHandler.Logout)
+		r.With(RequireUser).Get("/me", authHandler.Me)
+		if deps.OIDCService != nil {
+			oidcHandler := NewOIDCHandler(deps.OIDCService, deps.CookieSecure, deps.OIDCPostLogin, deps.Logger)
+			r.Get("/oidc/login", oidcHandler.Login)
+			r.Get("/oidc/callback", oidcHandler.Callback)
+		}
+	})
+	var shareHandler *ShareLinksHandler
+	if deps.ShareService != nil {
+		shareHandler = NewShareLinksHandler(deps.ShareService)
+		rest.Post("/share/open", shareHandler.Open)
+	}
+
+	// Document routes are also reachable with a share session or an API key;
+	// the usecases limit them to the shared document and the link's role, or
+	// to the key's scopes and documents.
+	rest.Group(func(rest chi.Router) {
+		rest.Use(ShareSession(deps.ShareService))
+		rest.Use(RequireUserOrShare)
+
+		rest.Route("/docs", func(r chi.Router) {
+			r.Get("/", docsHandler.List)
+			r.Post("/", docsHandler.Create)
+			r.Get("/search", searchHandler.Search)
+			r.Get("/trash", docsHandler.Trash)
+			r.Get("/{id}", docsHandler.Get)
+			r.Patch("/{id}", docsHandler.Update)
+			r.Delete("/{id}", docsHandler.Delete)
+			r.Post("/{id}/restore", docsHandler.Restore)
+			r.Post("/{id}/duplicate", docsHandler.Duplicate)
+			r.Post("/{id}/move", docsHandler.Move)
+			r.Put("/{id}/template", docsHandler.MarkTemplate)
+			r.Delete("/{id}/template", docsHandler.UnmarkTemplate)
+			r.Post("/{id}/lock", docsHandler.Lock)
+			r.Post("/{id}/archive", docsHandler.Archive)
+			r.Post("/{id}/unlock", docsHandler.Unlock)
+			r.Get("/{id}/audit", docsHandler.Audit)
+			r.Get("/{id}/snapshot", snapshotsHandler.Get)
+			r.Post("/{id}/tags", tagsHandler.Add)
+			r.Delete("/{id}/tags/{tag}", tagsHandler.Remove)
+			r.Get("/{id}/access", accessHandler.List)
+			r.Post("/{id}/access", accessHandler.Grant)
+			r.Delete("/{id}/access/{grantId}", accessHandler.Revoke)
+			if shareHandler != nil {
+				r.Get("/{id}/links", shareHandler.List)
+				r.Post("/{id}/links", shareHandler.Create)
+				r.Delete("/{id}/links/{linkId}", shareHandler.Revoke)
+				r.Get("/{id}/links/{linkId}/access", shareHandler.Access)
+			}
+
+			r.Get("/{id}/comments", commentsHandler.List)
+			r.Post("/{id}/comments", commentsHandler.Create)
+			r.Get("/{id}/comments/{commentId}", commentsHandler.Get)
+			r.Patch("/{id}/comments/{commentId}", commentsHandler.Update)
+			r.Delete("/{id}/comments/{commentId}", commentsHandler.Delete)
+			r.Post("/{id}/comments/{commentId}/purge", commentsHandler.Purge)
+			r.Get("/{id}/comments/{commentId}/history", commentsHandler.History)
+			r.Get("/{id}/comments/{commentId}/replies", commentsHandler.ListReplies)
+			r.Post("/{id}/comments/{commentId}/replies", commentsHandler.CreateReply)
+			r.Patch("/{id}/comments/{commentId}/replies/{replyId}", commentsHandler.UpdateReply)
+			r.Put("/{id}/comments/{commentId}/reactions/{emoji}", commentsHandler.AddReaction)
+			r.Delete("/{id}/comments/{commentId}/reactions/{emoji}", commentsHandler.RemoveReaction)
+
+			r.Get("/{id}/suggestions", suggestionsHandler.List)
+			r.Post("/{id}/suggestions", suggestionsHandler.Create)
+			r.Get("/{id}/suggestions/{suggestionId}", suggestionsHandler.Get)
+			r.Post("/{id}/suggestions/{suggestionId}/accept", suggestionsHandler.Accept)
+			r.Post("/{id}/suggestions/{suggestionId}/reject", suggestionsHandler.Reject)
+		})
+	})
+
+	rest.Group(func(rest chi.Router) {
+		rest.Use(RequireUser)
+		rest.Use(RejectAPIKey)
+
+		rest.Get("/templates", docsHandler.Templates)
+		rest.Get("/tags", tagsHandler.List)
+
+		rest.Route("/workspaces", func(r chi.Router) {
+			r.Get("/", foldersHandler.ListWorkspaces)
+			r.Post("/", foldersHandler.CreateWorkspace)
+			r.Get("/{id}", foldersHandler.GetWorkspace)
+			r.Get("/{id}/members", foldersHandler.Members)
+			r.Post("/{id}/members", foldersHandler.AddMember)
+			r.Delete("/{id}/members/{userId}", foldersHandler.RemoveMember)
+			r.Get("/{id}/properties", propertiesHandler.List)
+			r.Post("/{id}/properties", propertiesHandler.Create)
+			r.Patch("/{id}/properties/{key}", propertiesHandler.Update)
+			r.Delete("/{id}/properties/{key}", propertiesHandler.Delete)
+		})
+
+		rest.Post("/admin/claim-ownerless", accessHandler.ClaimOwnerless)
+
+		rest.Route("/api-keys", func(r chi.Router) {
+			r.Get("/", apiKeysHandler.List)
+			r.Post("/", apiKeysHandler.Create)
+			r.Delete("/{id}", apiKeysHandler.Revoke)
+		})
+
+		rest.Route("/webhooks", func(r chi.Router) {
+			r.Get("/", webhooksHandler.List)
+			r.Post("/", webhooksHandler.Create)
+			r.Get("/{id}", webhooksHandler.Get)
+			r.Delete("/{id}", webhooksHandler.Delete)
+			r.Get("/{id}/deliveries", webhooksHandler.Deliveries)
+			r.Post("/{id}/deliveries/{deliveryId}/redeliver", webhooksHandler.Redeliver)
+		})
+
+		rest.Route("/notifications", func(r chi.Router) {
+			r.Get("/", notificationsHandler.List)
+			r.Post("/read-all", notificationsHandler.MarkAllRead)
+			r.Post("/{id}/read", notificationsHandler.MarkRead)
+		})
+
+		rest.Route("/groups", func(r chi.Router) {
+			r.Get("/", groupsHandler.List)
+			r.Post("/", groupsHandler.Create)
+			r.Get("/{id}", groupsHandler.Get)
+			r.Post("/{id}/members", groupsHandler.AddMember)
+			r.Delete("/{id}/members/{userId}", groupsHandler.RemoveMember)
+		})
 
-		r.Get("/{id}/comments", commentsHandler.List)
-		r.Post("/{id}/comments", commentsHandler.Create)
-		r.Patch("/{id}/comments/{commentId}", commentsHandler.Update)
+		rest.Route("/folders", func(r chi.Router) {
+			r.Post("/", foldersHandler.Create)
+			r.Get("/{id}", foldersHandler.Get)
+			r.Get("/{id}/contents", foldersHandler.Contents)
+			r.Patch("/{id}", foldersHandler.Rename)
+			r.Post("/{id}/move", foldersHandler.Move)
+			r.Delete("/{id}", foldersHandler.Delete)
+		})
 	})
 
 	r.Mount("/", rest)
-	r.Get("/ws", deps.WSHandler.Handle)
+	r.With(Authenticate(deps.AuthService), ShareSession(deps.ShareService), RequireUserOrShare).Get("/ws", deps.WSHandler.Handle)
+	r.With(Authenticate(deps.AuthService), RequireUser).Get("/ws/notifications", deps.WSHandler.HandleNotifications)
 
 	return r
 }
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"collabdocs/internal/app/usecase"
+)
+
+type SearchHandler struct {
+	service *usecase.SearchService
+}
+
+func NewSearchHandler(service *usecase.SearchService) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := intParam(query.Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid limit")
+		return
+	}
+	offset, err := intParam(query.Get("offset"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid offset")
+		return
+	}
+	updatedAfter, err := timeParam(query.Get("updatedAfter"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid updatedAfter")
+		return
+	}
+	updatedBefore, err := timeParam(query.Get("updatedBefore"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid updatedBefore")
+		return
+	}
+
+	page, err := h.service.Search(r.Context(), usecase.SearchDocumentsInput{
+		Query:         query.Get("q"),
+		UpdatedAfter:  updatedAfter,
+		UpdatedBefore: updatedBefore,
+		Tags:          query["tag"],
+		TagMode:       query.Get("tagMode"),
+		Properties:    propertyFiltersFromQuery(query),
+		Limit:         limit,
+		Offset:        offset,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func intParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func optionalIntParam(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func boolParam(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func timeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
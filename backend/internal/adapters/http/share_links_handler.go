@@ -0,0 +1,114 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"collabdocs/internal/app/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+type ShareLinksHandler struct {
+	service *usecase.ShareLinkService
+}
+
+func NewShareLinksHandler(service *usecase.ShareLinkService) *ShareLinksHandler {
+	return &ShareLinksHandler{service: service}
+}
+
+type createShareLinkRequest struct {
+	Role      string     `json:"role"`
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	MaxUses   *int       `json:"maxUses"`
+}
+
+type openShareLinkRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (h *ShareLinksHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req createShareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	link, err := h.service.Create(r.Context(), usecase.CreateShareLinkInput{
+		DocID:     chi.URLParam(r, "id"),
+		Role:      req.Role,
+		Password:  req.Password,
+		ExpiresAt: req.ExpiresAt,
+		MaxUses:   req.MaxUses,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, link)
+}
+
+func (h *ShareLinksHandler) List(w http.ResponseWriter, r *http.Request) {
+	links, err := h.service.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"links": links})
+}
+
+func (h *ShareLinksHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	link, err := h.service.Revoke(r.Context(), usecase.RevokeShareLinkInput{
+		DocID:  chi.URLParam(r, "id"),
+		LinkID: chi.URLParam(r, "linkId"),
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, link)
+}
+
+func (h *ShareLinksHandler) Access(w http.ResponseWriter, r *http.Request) {
+	input := usecase.ListShareLinkAccessInput{
+		DocID:  chi.URLParam(r, "id"),
+		LinkID: chi.URLParam(r, "linkId"),
+	}
+	var err error
+	if input.Limit, err = intParam(r.URL.Query().Get("limit")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid limit")
+		return
+	}
+
+	entries, err := h.service.ListAccess(r.Context(), input)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"access": entries})
+}
+
+// Open redeems a link token for a share session, sent back as X-Share-Token
+// on REST requests or as a subprotocol after ws.ShareProtocol on the
+// WebSocket.
+func (h *ShareLinksHandler) Open(w http.ResponseWriter, r *http.Request) {
+	var req openShareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	session, err := h.service.Open(r.Context(), usecase.OpenShareLinkInput{
+		Token:     req.Token,
+		Password:  req.Password,
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, session)
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net/http"
+
+	"collabdocs/internal/app/usecase"
+	"collabdocs/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+type SnapshotsHandler struct {
+	service *usecase.SnapshotService
+}
+
+func NewSnapshotsHandler(service *usecase.SnapshotService) *SnapshotsHandler {
+	return &SnapshotsHandler{service: service}
+}
+
+// Get returns the document's latest Yjs snapshot as raw bytes.
+func (h *SnapshotsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := h.service.GetSnapshot(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	if snapshot == nil {
+		writeDomainError(w, domain.ErrNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(snapshot)
+}
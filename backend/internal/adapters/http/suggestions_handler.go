@@ -0,0 +1,86 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"collabdocs/internal/app/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+type SuggestionsHandler struct {
+	service *usecase.SuggestionService
+}
+
+func NewSuggestionsHandler(service *usecase.SuggestionService) *SuggestionsHandler {
+	return &SuggestionsHandler{service: service}
+}
+
+type createSuggestionRequest struct {
+	FromPos int `json:"fromPos"`
+	ToPos   int `json:"toPos"`
+	// Update is a base64 encoded Yjs update.
+	Update      []byte `json:"update"`
+	Description string `json:"description"`
+}
+
+func (h *SuggestionsHandler) List(w http.ResponseWriter, r *http.Request) {
+	suggestions, err := h.service.List(r.Context(), chi.URLParam(r, "id"), r.URL.Query().Get("status"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"suggestions": suggestions})
+}
+
+func (h *SuggestionsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	suggestion, err := h.service.Get(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "suggestionId"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"suggestion": suggestion})
+}
+
+func (h *SuggestionsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req createSuggestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	suggestion, err := h.service.Create(r.Context(), usecase.CreateSuggestionInput{
+		DocID:       chi.URLParam(r, "id"),
+		FromPos:     req.FromPos,
+		ToPos:       req.ToPos,
+		Update:      req.Update,
+		Description: req.Description,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"suggestion": suggestion})
+}
+
+func (h *SuggestionsHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	suggestion, err := h.service.Accept(r.Context(), decideSuggestionInput(r))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"suggestion": suggestion})
+}
+
+func (h *SuggestionsHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	suggestion, err := h.service.Reject(r.Context(), decideSuggestionInput(r))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"suggestion": suggestion})
+}
+
+func decideSuggestionInput(r *http.Request) usecase.DecideSuggestionInput {
+	return usecase.DecideSuggestionInput{DocID: chi.URLParam(r, "id"), SuggestionID: chi.URLParam(r, "suggestionId")}
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"collabdocs/internal/app/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+type TagsHandler struct {
+	service *usecase.TagService
+}
+
+func NewTagsHandler(service *usecase.TagService) *TagsHandler {
+	return &TagsHandler{service: service}
+}
+
+type addTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+func (h *TagsHandler) List(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.service.ListTags(r.Context())
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+func (h *TagsHandler) Add(w http.ResponseWriter, r *http.Request) {
+	var req addTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	tags, err := h.service.AddTags(r.Context(), usecase.AddTagsInput{
+		DocID: chi.URLParam(r, "id"),
+		Tags:  req.Tags,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+func (h *TagsHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.service.RemoveTag(r.Context(), usecase.RemoveTagInput{
+		DocID: chi.URLParam(r, "id"),
+		Tag:   chi.URLParam(r, "tag"),
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"collabdocs/internal/app/usecase"
+	"collabdocs/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+type WebhooksHandler struct {
+	service *usecase.WebhookService
+}
+
+func NewWebhooksHandler(service *usecase.WebhookService) *WebhooksHandler {
+	return &WebhooksHandler{service: service}
+}
+
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	DocID  string   `json:"docId"`
+}
+
+type createWebhookResponse struct {
+	domain.Webhook
+	Secret string `json:"secret"`
+}
+
+func (h *WebhooksHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req createWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body")
+		return
+	}
+
+	result, err := h.service.Create(r.Context(), usecase.CreateWebhookInput{
+		URL:    req.URL,
+		Events: req.Events,
+		DocID:  req.DocID,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, createWebhookResponse{Webhook: result.Webhook, Secret: result.Secret})
+}
+
+func (h *WebhooksHandler) List(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.service.List(r.Context())
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"webhooks": webhooks})
+}
+
+func (h *WebhooksHandler) Get(w http.ResponseWriter, r *http.Request) {
+	webhook, err := h.service.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhook)
+}
+
+func (h *WebhooksHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (h *WebhooksHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	limit, err := intParam(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_input", "Invalid limit")
+		return
+	}
+	deliveries, err := h.service.Deliveries(r.Context(), chi.URLParam(r, "id"), limit)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deliveries": deliveries})
+}
+
+func (h *WebhooksHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.service.Redeliver(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, delivery)
+}
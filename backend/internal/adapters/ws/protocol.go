@@ -3,6 +3,7 @@ package ws
 type PresencePayload struct {
 	Type   string `json:"type"`
 	Name   string `json:"name"`
+	UserID string `json:"userId,omitempty"`
 	Color  string `json:"color"`
 	Typing bool   `json:"typing"`
 	Cursor *struct {
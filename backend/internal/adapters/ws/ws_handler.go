@@ -1,8 +1,10 @@
 package ws
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -15,39 +17,66 @@ import (
 	"go.uber.org/zap"
 )
 
+// ShareProtocol is the WebSocket subprotocol share link holders offer,
+// followed by their share session token, since browsers cannot set headers
+// on upgrades. Only ShareProtocol is selected, so the token is not echoed.
+const ShareProtocol = "collabdocs.share"
+
 type Handler struct {
 	hub          ports.Hub
 	snapshotSvc  *usecase.SnapshotService
+	docSvc       *usecase.DocumentService
+	accessSvc    *usecase.AccessService
+	notifySvc    *usecase.NotificationService
 	log          *zap.Logger
 	maxBinBytes  int64
 	maxTextBytes int64
 	upgrader     websocket.Upgrader
 }
 
-func NewHandler(hub ports.Hub, snapshotSvc *usecase.SnapshotService, log *zap.Logger, maxBin, maxText int64) *Handler {
+func NewHandler(hub ports.Hub, snapshotSvc *usecase.SnapshotService, docSvc *usecase.DocumentService, accessSvc *usecase.AccessService, notifySvc *usecase.NotificationService, log *zap.Logger, maxBin, maxText int64, origins []string) *Handler {
 	return &Handler{
 		hub:         hub,
 		snapshotSvc: snapshotSvc,
+		docSvc:      docSvc,
+		accessSvc:   accessSvc,
+		notifySvc:   notifySvc,
 		log:         log,
 		maxBinBytes: maxBin,
 		maxTextBytes: maxText,
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool { return true },
+			CheckOrigin:  allowOrigins(origins),
+			Subprotocols: []string{ShareProtocol},
 		},
 	}
 }
 
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 	docID := r.URL.Query().Get("docId")
-	name := strings.TrimSpace(r.URL.Query().Get("name"))
 	if _, err := uuid.Parse(docID); err != nil {
 		http.Error(w, "invalid docId", http.StatusBadRequest)
 		return
 	}
-	if name == "" {
-		http.Error(w, "name required", http.StatusBadRequest)
+	// The role is fixed for the connection; revoking or lowering it
+	// disconnects the user so they rejoin with the new one.
+	role, err := h.accessSvc.Role(r.Context(), docID)
+	if errors.Is(err, domain.ErrUnauthorized) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if errors.Is(err, domain.ErrNotFound) {
+		http.Error(w, "document not found", http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		h.log.Error("ws access check failed", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	canEdit := role.Includes(domain.RoleEditor)
+	identity := connIdentity(r.Context(), docID)
+	// Writes reuse the role instead of querying the ACL for every frame
+	ctx := domain.WithDocAccess(r.Context(), domain.DocAccess{DocID: docID, Role: role})
 
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -58,7 +87,7 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 
 	clientID := uuid.New().String()
 	room := h.hub.GetRoom(docID)
-	client := hub.NewWSClient(clientID, conn)
+	client := hub.NewWSClient(clientID, identity.principal, conn)
 	room.Register(client)
 	defer room.Unregister(clientID)
 
@@ -77,6 +106,11 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Tell the client up front when the document is read-only
+	if doc, ok := h.document(r.Context(), docID); ok && doc.ReadOnly() {
+		h.sendState(client, doc)
+	}
+
 	for {
 		msgType, data, err := conn.ReadMessage()
 		if err != nil {
@@ -88,8 +122,18 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 				_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseMessageTooBig, "binary message too large"))
 				break
 			}
+			if !canEdit {
+				continue
+			}
 			if h.snapshotSvc != nil {
-				_ = h.snapshotSvc.AppendUpdate(r.Context(), docID, data)
+				err := h.snapshotSvc.AppendUpdate(ctx, docID, data)
+				if errors.Is(err, domain.ErrReadOnly) {
+					h.rejectReadOnly(r.Context(), client, docID)
+					continue
+				}
+				if denied(err) {
+					continue
+				}
 			}
 			room.Broadcast(clientID, websocket.BinaryMessage, data)
 			continue
@@ -108,6 +152,9 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 			msgTypeValue, _ := raw["type"].(string)
 			switch msgTypeValue {
 			case "snapshot":
+				if !canEdit {
+					continue
+				}
 				var payload SnapshotPayload
 				if err := json.Unmarshal(data, &payload); err != nil {
 					continue
@@ -120,11 +167,26 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 					continue
 				}
 				if h.snapshotSvc != nil {
-					_ = h.snapshotSvc.UpsertSnapshot(r.Context(), docID, snapshot)
+					err := h.snapshotSvc.UpsertSnapshot(ctx, docID, snapshot)
+					if errors.Is(err, domain.ErrReadOnly) {
+						h.rejectReadOnly(r.Context(), client, docID)
+						continue
+					}
+					if denied(err) {
+						continue
+					}
 				}
 				room.Broadcast(clientID, websocket.TextMessage, data)
-			case "presence", "comment:add", "comment:update":
-				room.Broadcast(clientID, websocket.TextMessage, data)
+			case "presence":
+				// Presence identity always comes from the session, never the client
+				var payload PresencePayload
+				if err := json.Unmarshal(data, &payload); err != nil {
+					continue
+				}
+				payload.Name = identity.name
+				payload.UserID = identity.userID
+				out, _ := json.Marshal(payload)
+				room.Broadcast(clientID, websocket.TextMessage, out)
 			default:
 				// ignore unknown
 			}
@@ -132,11 +194,123 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// On disconnect, broadcast presence typing false
-	leave := PresencePayload{Type: "presence", Name: name, Color: "#000000", Typing: false}
+	leave := PresencePayload{Type: "presence", Name: identity.name, UserID: identity.userID, Color: "#000000", Typing: false}
 	leaveData, _ := json.Marshal(leave)
 	room.Broadcast(clientID, websocket.TextMessage, leaveData)
 }
 
+// HandleNotifications serves the signed-in user's own channel. The server
+// pushes unread notification counts on it; messages from the client are
+// ignored.
+func (h *Handler) HandleNotifications(w http.ResponseWriter, r *http.Request) {
+	user, ok := domain.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.log.Error("ws upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	clientID := uuid.New().String()
+	room := h.hub.UserRoom(user.ID)
+	client := hub.NewWSClient(clientID, user.ID, conn)
+	room.Register(client)
+	defer room.Unregister(clientID)
+
+	conn.SetReadLimit(h.maxTextBytes)
+
+	// Start the client off with the current count
+	if h.notifySvc != nil {
+		if unread, err := h.notifySvc.UnreadCount(r.Context()); err == nil {
+			data, _ := json.Marshal(usecase.NewUnreadEvent(unread, nil))
+			_ = client.Send(websocket.TextMessage, data)
+		}
+	}
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+}
+
+// identity is who a connection acts as.
+type identity struct {
+	// principal is the user ID, or the share link's principal when the
+	// connection was authorized by a share session.
+	principal string
+	userID    string
+	name      string
+}
+
+func connIdentity(ctx context.Context, docID string) identity {
+	id := identity{name: domain.GuestName}
+	if user, ok := domain.UserFromContext(ctx); ok {
+		id = identity{principal: user.ID, userID: user.ID, name: user.Name}
+	}
+	if share, ok := domain.ShareAccessFromContext(ctx); ok && share.DocID == docID {
+		id.principal = share.Principal()
+	}
+	return id
+}
+
+// denied reports whether a write failed because the user lost access.
+func denied(err error) bool {
+	return errors.Is(err, domain.ErrForbidden) || errors.Is(err, domain.ErrNotFound)
+}
+
+func (h *Handler) document(ctx context.Context, docID string) (domain.Document, bool) {
+	if h.docSvc == nil {
+		return domain.Document{}, false
+	}
+	doc, err := h.docSvc.Get(ctx, docID)
+	return doc, err == nil
+}
+
+// rejectReadOnly answers a write to a read-only document with its current
+// state so the client can stop editing.
+func (h *Handler) rejectReadOnly(ctx context.Context, client *hub.WSClient, docID string) {
+	if doc, ok := h.document(ctx, docID); ok {
+		h.sendState(client, doc)
+	}
+}
+
+func (h *Handler) sendState(client *hub.WSClient, doc domain.Document) {
+	data, _ := json.Marshal(usecase.NewDocStateEvent(doc))
+	_ = client.Send(websocket.TextMessage, data)
+}
+
+// ShareToken returns the share session token offered after ShareProtocol in
+// the upgrade's Sec-WebSocket-Protocol header, if any.
+func ShareToken(r *http.Request) string {
+	protocols := websocket.Subprotocols(r)
+	for i, p := range protocols {
+		if p == ShareProtocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+// allowOrigins rejects cross-site upgrades now that the session cookie is
+// enough to authenticate. Requests without an Origin header come from
+// non-browser clients and are let through.
+func allowOrigins(origins []string) func(r *http.Request) bool {
+	allowed := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		allowed[strings.TrimSpace(o)] = true
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || allowed["*"] || allowed[origin]
+	}
+}
+
 func max(a, b int64) int64 {
 	if a > b {
 		return a
@@ -144,4 +318,3 @@ func max(a, b int64) int64 {
 	return b
 }
 
-var _ = domain.ErrInvalidInput
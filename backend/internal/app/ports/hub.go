@@ -4,6 +4,8 @@ type Hub interface {
 	Run()
 	Shutdown()
 	GetRoom(docID string) Room
+	// UserRoom is the room of a user's own connections.
+	UserRoom(userID string) Room
 }
 
 type Room interface {
@@ -14,6 +16,7 @@ type Room interface {
 
 type Client interface {
 	ID() string
+	Principal() string
 	Send(messageType int, payload []byte) error
 	Close() error
 }
@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"context"
+
+	"collabdocs/internal/domain"
+)
+
+// IdentityProvider signs users in through an external OpenID provider with
+// the authorization code flow and PKCE.
+type IdentityProvider interface {
+	AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error)
+	// Exchange redeems a code and returns the claims of the validated ID
+	// token. Rejected codes and invalid tokens wrap domain.ErrUnauthorized.
+	Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (domain.IdentityClaims, error)
+}
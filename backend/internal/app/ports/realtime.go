@@ -0,0 +1,20 @@
+package ports
+
+// Realtime pushes server-originated events to the clients connected to a
+// document's room.
+type Realtime interface {
+	BroadcastJSON(docID string, payload any)
+	// BroadcastUpdate sends a Yjs update made on the server to the room as
+	// if a client had made it.
+	BroadcastUpdate(docID string, update []byte)
+	// Disconnect closes every connection a principal has open on the
+	// document. Principals are user IDs, or ShareAccess.Principal for
+	// connections made through a share link.
+	Disconnect(docID string, principal string)
+}
+
+// UserRealtime pushes events to a user's own channel, which is open while
+// they are signed in whatever documents they are viewing.
+type UserRealtime interface {
+	SendToUser(userID string, payload any)
+}
@@ -2,21 +2,51 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"collabdocs/internal/domain"
 )
 
 type DocumentRepository interface {
-	Create(ctx context.Context, doc domain.Document) (domain.Document, error)
+	Create(ctx context.Context, doc domain.Document, ownerID string) (domain.Document, error)
+	CreateWithSnapshot(ctx context.Context, doc domain.Document, ownerID string, snapshot []byte, contentText string) (domain.Document, error)
 	GetByID(ctx context.Context, id string) (domain.Document, error)
-	UpdateTitle(ctx context.Context, id string, title string) (domain.Document, error)
-	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, query domain.DocumentListQuery) ([]domain.Document, int, error)
+	Update(ctx context.Context, id string, update domain.DocumentUpdate) (domain.Document, error)
+	SetTemplate(ctx context.Context, id string, isTemplate bool) (domain.Document, error)
+	MoveToFolder(ctx context.Context, id string, folderID string) (domain.Document, error)
+	Delete(ctx context.Context, id string, ifVersion *int64) error
+	SetState(ctx context.Context, event domain.DocumentStateEvent) (domain.Document, error)
+	ListStateEvents(ctx context.Context, docID string) ([]domain.DocumentStateEvent, error)
+	Restore(ctx context.Context, id string) (domain.Document, error)
+	Duplicate(ctx context.Context, sourceID string, doc domain.Document, ownerID string, includeComments bool, snapshot []byte, contentText *string) (domain.Document, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
 }
 
 type CommentRepository interface {
-	ListByDocID(ctx context.Context, docID string) ([]domain.Comment, error)
+	ListByDocID(ctx context.Context, query domain.CommentListQuery) ([]domain.Comment, error)
+	CountByStatus(ctx context.Context, query domain.CommentListQuery) (domain.CommentCounts, error)
+	ListReplies(ctx context.Context, docID string, parentID string) ([]domain.Comment, error)
+	GetByID(ctx context.Context, docID string, commentID string) (domain.Comment, error)
 	Create(ctx context.Context, comment domain.Comment) (domain.Comment, error)
-	Update(ctx context.Context, docID string, commentID string, resolved *bool, text *string) (domain.Comment, error)
+	Update(ctx context.Context, docID string, commentID string, update domain.CommentUpdate) (domain.Comment, error)
+	ListRevisions(ctx context.Context, docID string, commentID string) ([]domain.CommentRevision, error)
+	Delete(ctx context.Context, docID string, commentID string, deletedBy *string) (domain.Comment, error)
+	Purge(ctx context.Context, docID string, commentID string) (domain.Comment, error)
+	ListAnchors(ctx context.Context, docID string) ([]domain.CommentAnchor, error)
+	UpdateAnchors(ctx context.Context, docID string, anchors []domain.CommentAnchor) error
+	AddReaction(ctx context.Context, docID string, commentID string, userID string, emoji string) error
+	RemoveReaction(ctx context.Context, docID string, commentID string, userID string, emoji string) error
+}
+
+type SuggestionRepository interface {
+	ListByDoc(ctx context.Context, docID string, status string) ([]domain.Suggestion, error)
+	GetByID(ctx context.Context, docID string, id string) (domain.Suggestion, error)
+	Create(ctx context.Context, suggestion domain.Suggestion) (domain.Suggestion, error)
+	Decide(ctx context.Context, docID string, id string, decision domain.SuggestionDecision) (domain.Suggestion, error)
+	// Accept decides an open suggestion as accepted and merges its update
+	// into the document's snapshot atomically, returning the new snapshot.
+	Accept(ctx context.Context, docID string, id string, decision domain.SuggestionDecision) (domain.Suggestion, []byte, error)
 }
 
 type SnapshotRepository interface {
@@ -26,4 +56,116 @@ type SnapshotRepository interface {
 
 type UpdateRepository interface {
 	AppendUpdate(ctx context.Context, docID string, update []byte) error
+	ListPending(ctx context.Context, docID string) ([][]byte, error)
+}
+
+type SearchRepository interface {
+	IndexContent(ctx context.Context, docID string, content string) error
+	Search(ctx context.Context, query domain.SearchQuery) ([]domain.SearchResult, int, error)
+}
+
+type FolderRepository interface {
+	CreateWorkspace(ctx context.Context, workspace domain.Workspace, root domain.Folder) (domain.Workspace, error)
+	ListWorkspaces(ctx context.Context, userID string) ([]domain.Workspace, error)
+	GetWorkspace(ctx context.Context, id string) (domain.Workspace, error)
+	IsWorkspaceMember(ctx context.Context, workspaceID string, userID string) (bool, error)
+	ListWorkspaceMembers(ctx context.Context, workspaceID string) ([]domain.User, error)
+	AddWorkspaceMember(ctx context.Context, workspaceID string, userID string) error
+	RemoveWorkspaceMember(ctx context.Context, workspaceID string, userID string) error
+	CreateFolder(ctx context.Context, folder domain.Folder) (domain.Folder, error)
+	GetFolder(ctx context.Context, id string) (domain.Folder, error)
+	ListSubfolders(ctx context.Context, parentID string) ([]domain.Folder, error)
+	RenameFolder(ctx context.Context, id string, name string) (domain.Folder, error)
+	MoveFolder(ctx context.Context, id string, parentID string) (domain.Folder, error)
+	DeleteFolder(ctx context.Context, id string) error
+}
+
+type TagRepository interface {
+	AddTags(ctx context.Context, docID string, names []string) ([]string, error)
+	RemoveTag(ctx context.Context, docID string, name string) ([]string, error)
+	ListTags(ctx context.Context, viewerID string) ([]domain.Tag, error)
+}
+
+type PropertyRepository interface {
+	CreateDefinition(ctx context.Context, def domain.PropertyDefinition) (domain.PropertyDefinition, error)
+	ListDefinitions(ctx context.Context, workspaceID string) ([]domain.PropertyDefinition, error)
+	ListDefinitionsForFolder(ctx context.Context, folderID string) ([]domain.PropertyDefinition, error)
+	UpdateDefinition(ctx context.Context, workspaceID string, key string, name string, options []string) (domain.PropertyDefinition, error)
+	DeleteDefinition(ctx context.Context, workspaceID string, key string) error
+}
+
+type UserRepository interface {
+	Create(ctx context.Context, user domain.User) (domain.User, error)
+	GetByID(ctx context.Context, id string) (domain.User, error)
+	GetByEmail(ctx context.Context, email string) (domain.User, error)
+	GetByIdentity(ctx context.Context, issuer string, subject string) (domain.User, error)
+	CreateWithIdentity(ctx context.Context, user domain.User, issuer string, subject string) (domain.User, error)
+	LinkIdentity(ctx context.Context, userID string, issuer string, subject string) error
+	UpdateName(ctx context.Context, id string, name string) (domain.User, error)
+	ListByMentions(ctx context.Context, handles []string) ([]domain.User, error)
+}
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification domain.Notification) (domain.Notification, error)
+	List(ctx context.Context, query domain.NotificationQuery) ([]domain.Notification, error)
+	CountUnread(ctx context.Context, userID string) (int, error)
+	MarkRead(ctx context.Context, userID string, id string) (domain.Notification, error)
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session domain.Session) error
+	GetUser(ctx context.Context, tokenHash string) (domain.User, error)
+	Delete(ctx context.Context, tokenHash string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key domain.APIKey) (domain.APIKey, error)
+	ListByUser(ctx context.Context, userID string) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, userID string, id string) (domain.APIKey, error)
+	Authenticate(ctx context.Context, secretHash string) (domain.APIKey, domain.User, error)
+}
+
+type ACLRepository interface {
+	Role(ctx context.Context, docID string, userID string) (domain.Role, error)
+	ListGrants(ctx context.Context, docID string) ([]domain.Grant, error)
+	GetGrant(ctx context.Context, docID string, grantID string) (domain.Grant, error)
+	Upsert(ctx context.Context, grant domain.Grant) (domain.Grant, error)
+	Delete(ctx context.Context, docID string, grantID string) (domain.Grant, error)
+	ListGroupDocIDs(ctx context.Context, groupID string) ([]string, error)
+	ClaimOwnerless(ctx context.Context, userID string) (int64, error)
+}
+
+type GroupRepository interface {
+	Create(ctx context.Context, group domain.Group) (domain.Group, error)
+	GetByID(ctx context.Context, id string) (domain.Group, error)
+	UpsertExternal(ctx context.Context, group domain.Group) (domain.Group, error)
+	ListForUser(ctx context.Context, userID string) ([]domain.Group, error)
+	ListMembers(ctx context.Context, groupID string) ([]domain.User, error)
+	AddMember(ctx context.Context, groupID string, userID string) error
+	RemoveMember(ctx context.Context, groupID string, userID string) error
+}
+
+type ShareLinkRepository interface {
+	Create(ctx context.Context, link domain.ShareLink) (domain.ShareLink, error)
+	GetByID(ctx context.Context, id string) (domain.ShareLink, error)
+	ListByDoc(ctx context.Context, docID string) ([]domain.ShareLink, error)
+	Revoke(ctx context.Context, docID string, id string) (domain.ShareLink, error)
+	Consume(ctx context.Context, id string) (domain.ShareLink, error)
+	LogAccess(ctx context.Context, entry domain.ShareLinkAccess) error
+	ListAccess(ctx context.Context, docID string, linkID string, limit int) ([]domain.ShareLinkAccess, error)
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook domain.Webhook) (domain.Webhook, error)
+	ListByUser(ctx context.Context, userID string) ([]domain.Webhook, error)
+	GetByID(ctx context.Context, userID string, id string) (domain.Webhook, error)
+	Delete(ctx context.Context, userID string, id string) error
+	ListSubscribers(ctx context.Context, docID string, event string) ([]domain.Webhook, error)
+	CreateDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, userID string, webhookID string, limit int) ([]domain.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, userID string, webhookID string, id string) (domain.WebhookDelivery, error)
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.DueDelivery, error)
+	RecordAttempt(ctx context.Context, id string, attempt domain.WebhookAttempt) error
 }
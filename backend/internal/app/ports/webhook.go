@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"context"
+	"net/http"
+)
+
+// WebhookSender posts webhook payloads to subscribers.
+type WebhookSender interface {
+	// CheckURL returns an error when url may not receive deliveries, such
+	// as when it points at an internal address.
+	CheckURL(ctx context.Context, url string) error
+	// Send posts body to url with the given headers and returns the
+	// response status code. Failing to get a response returns an error.
+	Send(ctx context.Context, url string, header http.Header, body []byte) (int, error)
+}
@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type AccessService struct {
+	acl      ports.ACLRepository
+	users    ports.UserRepository
+	groups   ports.GroupRepository
+	realtime ports.Realtime
+	validate *validator.Validate
+}
+
+// GrantAccessInput gives a role to one user, by ID or email, or to a group.
+type GrantAccessInput struct {
+	DocID   string `validate:"required,uuid4"`
+	UserID  string `validate:"omitempty,uuid4"`
+	Email   string `validate:"omitempty,email,max=254"`
+	GroupID string `validate:"omitempty,uuid4"`
+	Role    string `validate:"required,oneof=owner editor commenter viewer"`
+}
+
+type RevokeAccessInput struct {
+	DocID   string `validate:"required,uuid4"`
+	GrantID string `validate:"required,uuid4"`
+}
+
+// NewAccessService creates the service. realtime may be nil, in which case
+// users losing access keep their open connections.
+func NewAccessService(acl ports.ACLRepository, users ports.UserRepository, groups ports.GroupRepository, realtime ports.Realtime, validate *validator.Validate) *AccessService {
+	return &AccessService{acl: acl, users: users, groups: groups, realtime: realtime, validate: validate}
+}
+
+// effectiveRole returns the caller's role on a document. A role resolved
+// earlier for the document, by a WebSocket connection, is reused as is. A
+// share link opened for the document grants exactly the link's role;
+// otherwise the signed-in user's grants apply. The empty role means no access.
+func effectiveRole(ctx context.Context, acl ports.ACLRepository, docID string) (domain.Role, domain.User, error) {
+	user, signedIn := domain.UserFromContext(ctx)
+	if access, ok := domain.DocAccessFromContext(ctx); ok && access.DocID == docID {
+		return access.Role, user, nil
+	}
+	if share, ok := domain.ShareAccessFromContext(ctx); ok && share.DocID == docID {
+		return share.Role, user, nil
+	}
+	if !signedIn {
+		return "", domain.User{}, domain.ErrUnauthorized
+	}
+	role, err := acl.Role(ctx, docID, user.ID)
+	return role, user, err
+}
+
+// authorize fails unless the caller holds at least min on the document, and
+// returns the signed-in user, which is the zero User for anonymous share link
+// holders. Callers without any access get ErrNotFound, so documents nobody
+// shared with them are not disclosed. Requests made with an API key also need
+// scope.
+func authorize(ctx context.Context, acl ports.ACLRepository, docID string, min domain.Role, scope string) (domain.User, error) {
+	if err := keyAllows(ctx, scope, docID); err != nil {
+		return domain.User{}, err
+	}
+	role, user, err := effectiveRole(ctx, acl, docID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if role == "" {
+		return domain.User{}, domain.ErrNotFound
+	}
+	if !role.Includes(min) {
+		return domain.User{}, domain.ErrForbidden
+	}
+	return user, nil
+}
+
+// Role returns the caller's role on a document.
+func (s *AccessService) Role(ctx context.Context, docID string) (domain.Role, error) {
+	if err := s.validate.Var(docID, "required,uuid4"); err != nil {
+		return "", domain.ErrInvalidInput
+	}
+	role, _, err := effectiveRole(ctx, s.acl, docID)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", domain.ErrNotFound
+	}
+	return role, nil
+}
+
+// List returns who has access to a document. Any signed-in user who can view
+// the document can see it; share link holders cannot.
+func (s *AccessService) List(ctx context.Context, docID string) ([]domain.Grant, error) {
+	if err := s.validate.Var(docID, "required,uuid4"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if _, err := currentUser(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := authorize(ctx, s.acl, docID, domain.RoleViewer, domain.ScopeDocsRead); err != nil {
+		return nil, err
+	}
+	return s.acl.ListGrants(ctx, docID)
+}
+
+// Grant gives a user or group a role on a document, replacing the role they
+// had. Only owners manage access, and the owner role is for users only.
+func (s *AccessService) Grant(ctx context.Context, input GrantAccessInput) (domain.Grant, error) {
+	input.Email = strings.TrimSpace(input.Email)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Grant{}, domain.ErrInvalidInput
+	}
+	principals := 0
+	for _, v := range []string{input.UserID, input.Email, input.GroupID} {
+		if v != "" {
+			principals++
+		}
+	}
+	if principals != 1 {
+		return domain.Grant{}, domain.ErrInvalidInput
+	}
+	actor, err := authorize(ctx, s.acl, input.DocID, domain.RoleOwner, domain.ScopeDocsManage)
+	if err != nil {
+		return domain.Grant{}, err
+	}
+
+	grant := domain.Grant{
+		ID:        uuid.New().String(),
+		DocID:     input.DocID,
+		Role:      domain.Role(input.Role),
+		GrantedBy: &actor.ID,
+		CreatedAt: utils.NowUTC(),
+	}
+	switch {
+	case input.GroupID != "":
+		if grant.Role == domain.RoleOwner {
+			return domain.Grant{}, domain.ErrInvalidInput
+		}
+		group, err := s.groups.GetByID(ctx, input.GroupID)
+		if err != nil {
+			return domain.Grant{}, err
+		}
+		grant.GroupID = &group.ID
+	case input.Email != "":
+		user, err := s.users.GetByEmail(ctx, input.Email)
+		if err != nil {
+			return domain.Grant{}, err
+		}
+		grant.UserID = &user.ID
+	default:
+		user, err := s.users.GetByID(ctx, input.UserID)
+		if err != nil {
+			return domain.Grant{}, err
+		}
+		grant.UserID = &user.ID
+	}
+
+	before, err := s.roles(ctx, grant)
+	if err != nil {
+		return domain.Grant{}, err
+	}
+	out, err := s.acl.Upsert(ctx, grant)
+	if err != nil {
+		return domain.Grant{}, err
+	}
+	s.disconnectDemoted(ctx, input.DocID, before)
+	return out, nil
+}
+
+// ClaimOwnerless makes the current administrator owner of every document
+// without an owner, such as those created before access control existed, so
+// they can be shared again. It returns how many documents were claimed.
+func (s *AccessService) ClaimOwnerless(ctx context.Context) (int64, error) {
+	admin, err := currentAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.acl.ClaimOwnerless(ctx, admin.ID)
+}
+
+// Revoke removes a grant. Users left with less access than before are
+// disconnected from the document's room and have to rejoin.
+func (s *AccessService) Revoke(ctx context.Context, input RevokeAccessInput) error {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, input.DocID, domain.RoleOwner, domain.ScopeDocsManage); err != nil {
+		return err
+	}
+	grant, err := s.acl.GetGrant(ctx, input.DocID, input.GrantID)
+	if err != nil {
+		return err
+	}
+	before, err := s.roles(ctx, grant)
+	if err != nil {
+		return err
+	}
+	if _, err := s.acl.Delete(ctx, input.DocID, input.GrantID); err != nil {
+		return err
+	}
+	s.disconnectDemoted(ctx, input.DocID, before)
+	return nil
+}
+
+// roles returns the current role on the grant's document of every user the
+// grant applies to.
+func (s *AccessService) roles(ctx context.Context, grant domain.Grant) (map[string]domain.Role, error) {
+	userIDs := make([]string, 0, 1)
+	if grant.UserID != nil {
+		userIDs = append(userIDs, *grant.UserID)
+	}
+	if grant.GroupID != nil {
+		members, err := s.groups.ListMembers(ctx, *grant.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			userIDs = append(userIDs, m.ID)
+		}
+	}
+	return userRoles(ctx, s.acl, grant.DocID, userIDs)
+}
+
+func (s *AccessService) disconnectDemoted(ctx context.Context, docID string, before map[string]domain.Role) {
+	disconnectDemoted(ctx, s.acl, s.realtime, docID, before)
+}
+
+func userRoles(ctx context.Context, acl ports.ACLRepository, docID string, userIDs []string) (map[string]domain.Role, error) {
+	roles := make(map[string]domain.Role, len(userIDs))
+	for _, id := range userIDs {
+		role, err := acl.Role(ctx, docID, id)
+		if err != nil {
+			return nil, err
+		}
+		roles[id] = role
+	}
+	return roles, nil
+}
+
+// disconnectDemoted closes the live connections of users whose role on the
+// document dropped below what they had in before. Their connections were
+// authorized for the old role, so they must reconnect to get the new one.
+func disconnectDemoted(ctx context.Context, acl ports.ACLRepository, realtime ports.Realtime, docID string, before map[string]domain.Role) {
+	if realtime == nil {
+		return
+	}
+	for userID, old := range before {
+		if old == "" {
+			continue
+		}
+		role, err := acl.Role(ctx, docID, userID)
+		if err != nil || !role.Includes(old) {
+			realtime.Disconnect(docID, userID)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key secret, which tells them apart from
+// session tokens in the Authorization header.
+const APIKeyPrefix = "cdk_"
+
+// apiKeyDisplayLength is how much of the secret is kept as the key's prefix.
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+type APIKeyService struct {
+	keys     ports.APIKeyRepository
+	validate *validator.Validate
+}
+
+type CreateAPIKeyInput struct {
+	Name   string   `validate:"required,max=80"`
+	Scopes []string `validate:"required,min=1,dive,oneof=docs:read docs:write docs:manage comments:read comments:write snapshots:read"`
+	// DocIDs restricts the key to these documents; empty means every
+	// document the user can reach.
+	DocIDs    []string `validate:"max=100,dive,uuid4"`
+	ExpiresAt *time.Time
+}
+
+// APIKeyResult carries the secret of a new key. The secret is only ever
+// returned here; the server keeps a hash of it.
+type APIKeyResult struct {
+	Key    domain.APIKey
+	Secret string
+}
+
+func NewAPIKeyService(keys ports.APIKeyRepository, validate *validator.Validate) *APIKeyService {
+	return &APIKeyService{keys: keys, validate: validate}
+}
+
+// Create issues a key acting as the current user. Keys cannot be managed
+// with another key.
+func (s *APIKeyService) Create(ctx context.Context, input CreateAPIKeyInput) (APIKeyResult, error) {
+	user, err := s.keyOwner(ctx)
+	if err != nil {
+		return APIKeyResult{}, err
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if err := s.validate.Struct(input); err != nil {
+		return APIKeyResult{}, domain.ErrInvalidInput
+	}
+	now := utils.NowUTC()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return APIKeyResult{}, domain.ErrInvalidInput
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return APIKeyResult{}, err
+	}
+	secret := APIKeyPrefix + token
+	key, err := s.keys.Create(ctx, domain.APIKey{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		Name:       input.Name,
+		Prefix:     secret[:apiKeyDisplayLength],
+		SecretHash: hashToken(secret),
+		Scopes:     dedupe(input.Scopes),
+		DocIDs:     dedupe(input.DocIDs),
+		CreatedAt:  now,
+		ExpiresAt:  input.ExpiresAt,
+	})
+	if err != nil {
+		return APIKeyResult{}, err
+	}
+	return APIKeyResult{Key: key, Secret: secret}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context) ([]domain.APIKey, error) {
+	user, err := s.keyOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.keys.ListByUser(ctx, user.ID)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, id string) (domain.APIKey, error) {
+	if err := s.validate.Var(id, "required,uuid4"); err != nil {
+		return domain.APIKey{}, domain.ErrInvalidInput
+	}
+	user, err := s.keyOwner(ctx)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	return s.keys.Revoke(ctx, user.ID, id)
+}
+
+// Authenticate resolves a key secret to the key and the user it acts as, and
+// records the use. Unknown, revoked and expired keys return ErrUnauthorized.
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (domain.APIKey, domain.User, error) {
+	if !strings.HasPrefix(secret, APIKeyPrefix) {
+		return domain.APIKey{}, domain.User{}, domain.ErrUnauthorized
+	}
+	key, user, err := s.keys.Authenticate(ctx, hashToken(secret))
+	if err == domain.ErrNotFound {
+		return domain.APIKey{}, domain.User{}, domain.ErrUnauthorized
+	}
+	return key, user, err
+}
+
+// keyOwner returns the signed-in user, refusing requests made with a key.
+func (s *APIKeyService) keyOwner(ctx context.Context) (domain.User, error) {
+	if _, ok := domain.APIKeyFromContext(ctx); ok {
+		return domain.User{}, domain.ErrForbidden
+	}
+	return currentUser(ctx)
+}
+
+// keyAllows checks the scope of the request's API key, if any. Documents
+// outside the key's list get ErrNotFound, like documents without access; an
+// empty docID stands for a document the request is about to create, which
+// keys limited to particular documents cannot do.
+func keyAllows(ctx context.Context, scope string, docID string) error {
+	key, ok := domain.APIKeyFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if !key.HasScope(scope) {
+		return domain.ErrForbidden
+	}
+	if docID == "" {
+		if len(key.DocIDs) > 0 {
+			return domain.ErrForbidden
+		}
+		return nil
+	}
+	if !key.CoversDoc(docID) {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// keyDocIDs returns the documents a listing is limited to by the request's
+// API key, or nil when it is not limited.
+func keyDocIDs(ctx context.Context, scope string) ([]string, error) {
+	key, ok := domain.APIKeyFromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	if !key.HasScope(scope) {
+		return nil, domain.ErrForbidden
+	}
+	if len(key.DocIDs) == 0 {
+		return nil, nil
+	}
+	return key.DocIDs, nil
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyPasswordHash is compared against when a login names an unknown email,
+// so both failure modes take as long as a real check.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("collabdocs"), bcrypt.DefaultCost)
+
+type AuthService struct {
+	users      ports.UserRepository
+	sessions   ports.SessionRepository
+	validate   *validator.Validate
+	sessionTTL time.Duration
+	admins     map[string]bool
+}
+
+type RegisterInput struct {
+	Email    string `validate:"required,email,max=254"`
+	Name     string `validate:"required,max=40"`
+	Password string `validate:"required,min=8,max=72"`
+}
+
+type LoginInput struct {
+	Email    string `validate:"required,max=254"`
+	Password string `validate:"required,max=72"`
+}
+
+// LoginResult carries the bearer token of a new session. The token is only
+// ever returned here; the server keeps a hash of it.
+type LoginResult struct {
+	User      domain.User
+	Token     string
+	ExpiresAt time.Time
+}
+
+// NewAuthService creates the service. Users whose IDs are in adminIDs are
+// administrators.
+func NewAuthService(users ports.UserRepository, sessions ports.SessionRepository, validate *validator.Validate, sessionTTL time.Duration, adminIDs []string) *AuthService {
+	admins := make(map[string]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			admins[id] = true
+		}
+	}
+	return &AuthService{users: users, sessions: sessions, validate: validate, sessionTTL: sessionTTL, admins: admins}
+}
+
+func (s *AuthService) Register(ctx context.Context, input RegisterInput) (domain.User, error) {
+	input.Email = strings.TrimSpace(input.Email)
+	input.Name = strings.TrimSpace(input.Name)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.User{}, domain.ErrInvalidInput
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return domain.User{}, err
+	}
+	now := utils.NowUTC()
+	return s.users.Create(ctx, domain.User{
+		ID:           uuid.New().String(),
+		Email:        input.Email,
+		Name:         input.Name,
+		PasswordHash: string(hash),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+}
+
+// Login checks a password and opens a session. Unknown emails and wrong
+// passwords both return ErrUnauthorized.
+func (s *AuthService) Login(ctx context.Context, input LoginInput) (LoginResult, error) {
+	input.Email = strings.TrimSpace(input.Email)
+	if err := s.validate.Struct(input); err != nil {
+		return LoginResult{}, domain.ErrInvalidInput
+	}
+
+	user, err := s.users.GetByEmail(ctx, input.Email)
+	switch {
+	case err == domain.ErrNotFound:
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(input.Password))
+		return LoginResult{}, domain.ErrUnauthorized
+	case err != nil:
+		return LoginResult{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)) != nil {
+		return LoginResult{}, domain.ErrUnauthorized
+	}
+	return s.StartSession(ctx, user)
+}
+
+// StartSession opens a session for an already authenticated user. Expired
+// sessions are swept at the same time.
+func (s *AuthService) StartSession(ctx context.Context, user domain.User) (LoginResult, error) {
+	if _, err := s.sessions.DeleteExpired(ctx); err != nil {
+		return LoginResult{}, err
+	}
+	token, err := newToken()
+	if err != nil {
+		return LoginResult{}, err
+	}
+	now := utils.NowUTC()
+	session := domain.Session{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.sessionTTL),
+	}
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return LoginResult{}, err
+	}
+	user.IsAdmin = s.admins[user.ID]
+	return LoginResult{User: user, Token: token, ExpiresAt: session.ExpiresAt}, nil
+}
+
+func (s *AuthService) Logout(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	return s.sessions.Delete(ctx, hashToken(token))
+}
+
+// Authenticate resolves a session token to its user.
+func (s *AuthService) Authenticate(ctx context.Context, token string) (domain.User, error) {
+	if token == "" {
+		return domain.User{}, domain.ErrUnauthorized
+	}
+	user, err := s.sessions.GetUser(ctx, hashToken(token))
+	if err == domain.ErrNotFound {
+		return domain.User{}, domain.ErrUnauthorized
+	}
+	user.IsAdmin = s.admins[user.ID]
+	return user, err
+}
+
+// currentUser returns the authenticated user or ErrUnauthorized.
+func currentUser(ctx context.Context) (domain.User, error) {
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.User{}, domain.ErrUnauthorized
+	}
+	return user, nil
+}
+
+// currentAdmin returns the authenticated user if they are an administrator.
+// Requests made with an API key never act as one.
+func currentAdmin(ctx context.Context) (domain.User, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if _, ok := domain.APIKeyFromContext(ctx); ok || !user.IsAdmin {
+		return domain.User{}, domain.ErrForbidden
+	}
+	return user, nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/yjs"
+)
+
+// validAnchor reports whether b is an encoded Yjs relative position.
+func validAnchor(b []byte) bool {
+	_, err := yjs.DecodeRelativePosition(b)
+	return err == nil
+}
+
+// resolveAnchor recomputes a comment range against a snapshot. A range is
+// orphaned once the text it started on has been deleted and nothing is left
+// between its ends. Ranges referring to edits the snapshot does not contain
+// yet are not resolved.
+func resolveAnchor(doc *yjs.Doc, a domain.CommentAnchor) (domain.CommentAnchor, bool) {
+	fromRel, err := yjs.DecodeRelativePosition(a.FromAnchor)
+	if err != nil {
+		return a, false
+	}
+	toRel, err := yjs.DecodeRelativePosition(a.ToAnchor)
+	if err != nil {
+		return a, false
+	}
+	from, ok := doc.ResolvePosition(fromRel)
+	if !ok {
+		return a, false
+	}
+	to, ok := doc.ResolvePosition(toRel)
+	if !ok {
+		return a, false
+	}
+	if to.Pos < from.Pos {
+		to.Pos = from.Pos
+	}
+	a.FromPos = from.Pos
+	a.ToPos = to.Pos
+	a.Orphaned = from.Deleted && to.Pos == from.Pos
+	return a, true
+}
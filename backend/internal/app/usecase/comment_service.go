@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"strings"
+	"time"
 
 	"collabdocs/internal/app/ports"
 	"collabdocs/internal/domain"
@@ -12,38 +13,165 @@ import (
 )
 
 type CommentService struct {
-	repo     ports.CommentRepository
-	validate *validator.Validate
+	repo          ports.CommentRepository
+	acl           ports.ACLRepository
+	realtime      ports.Realtime
+	notifications *NotificationService
+	webhooks      *WebhookService
+	validate      *validator.Validate
 }
 
 type CreateCommentInput struct {
-	DocID      string `validate:"required,uuid4"`
-	AuthorName string `validate:"required,max=40"`
-	FromPos    int    `validate:"min=0"`
-	ToPos      int    `validate:"min=0"`
+	DocID   string `validate:"required,uuid4"`
+	FromPos int    `validate:"min=0"`
+	ToPos   int    `validate:"min=0"`
+	// FromAnchor and ToAnchor are optional encoded Yjs relative positions
+	// of the range; they are given together or not at all.
+	FromAnchor []byte `validate:"omitempty,max=64"`
+	ToAnchor   []byte `validate:"omitempty,max=64"`
 	Text       string `validate:"required,max=2000"`
 }
 
+// ListCommentsInput filters and pages the root comments of a document.
+type ListCommentsInput struct {
+	DocID        string `validate:"required,uuid4"`
+	Resolved     *bool
+	AuthorID     string `validate:"omitempty,uuid4"`
+	CreatedAfter *time.Time
+	FromPos      *int   `validate:"omitempty,min=0"`
+	ToPos        *int   `validate:"omitempty,min=0"`
+	Query        string `validate:"max=200"`
+	Cursor       string `validate:"max=512"`
+	Limit        int    `validate:"min=0,max=200"`
+}
+
+// commentCursor is the decoded form of ListCommentsInput.Cursor.
+type commentCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+const defaultCommentsLimit = 100
+
 type UpdateCommentInput struct {
 	DocID     string `validate:"required,uuid4"`
 	CommentID string `validate:"required,uuid4"`
 	Resolved  *bool
 	Text      *string `validate:"omitempty,max=2000"`
+	// IfVersion makes the update conditional on the comment's version.
+	IfVersion *int64
 }
 
-func NewCommentService(repo ports.CommentRepository, validate *validator.Validate) *CommentService {
-	return &CommentService{repo: repo, validate: validate}
+// CreateReplyInput adds a reply to the thread of root comment CommentID.
+type CreateReplyInput struct {
+	DocID     string `validate:"required,uuid4"`
+	CommentID string `validate:"required,uuid4"`
+	Text      string `validate:"required,max=2000"`
+}
+
+type UpdateReplyInput struct {
+	DocID     string `validate:"required,uuid4"`
+	CommentID string `validate:"required,uuid4"`
+	ReplyID   string `validate:"required,uuid4"`
+	Text      string `validate:"required,max=2000"`
+	// IfVersion makes the update conditional on the reply's version.
+	IfVersion *int64
+}
+
+type DeleteCommentInput struct {
+	DocID     string `validate:"required,uuid4"`
+	CommentID string `validate:"required,uuid4"`
+}
+
+// ReactionInput adds or removes the current user's Emoji reaction to a
+// comment or reply.
+type ReactionInput struct {
+	DocID     string `validate:"required,uuid4"`
+	CommentID string `validate:"required,uuid4"`
+	Emoji     string `validate:"required,max=64"`
 }
 
-func (s *CommentService) ListByDoc(ctx context.Context, docID string) ([]domain.Comment, error) {
+// NewCommentService creates the service. realtime may be nil, in which case
+// reply events are not pushed to connected clients, notifications may be
+// nil, in which case @mentions notify nobody, and webhooks may be nil, in
+// which case no webhook events are raised.
+func NewCommentService(repo ports.CommentRepository, acl ports.ACLRepository, realtime ports.Realtime, notifications *NotificationService, webhooks *WebhookService, validate *validator.Validate) *CommentService {
+	return &CommentService{repo: repo, acl: acl, realtime: realtime, notifications: notifications, webhooks: webhooks, validate: validate}
+}
+
+// ListByDoc returns a page of the document's root comments, newest first,
+// with their reply counts and the counts of open and resolved comments
+// matching the other filters.
+func (s *CommentService) ListByDoc(ctx context.Context, input ListCommentsInput) (domain.CommentPage, error) {
+	input.Query = strings.TrimSpace(input.Query)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.CommentPage{}, domain.ErrInvalidInput
+	}
+	if input.FromPos != nil && input.ToPos != nil && *input.FromPos > *input.ToPos {
+		return domain.CommentPage{}, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, input.DocID, domain.RoleViewer, domain.ScopeCommentsRead); err != nil {
+		return domain.CommentPage{}, err
+	}
+
+	query := domain.CommentListQuery{
+		DocID:        input.DocID,
+		Resolved:     input.Resolved,
+		AuthorID:     input.AuthorID,
+		CreatedAfter: input.CreatedAfter,
+		FromPos:      input.FromPos,
+		ToPos:        input.ToPos,
+		Query:        input.Query,
+		Limit:        input.Limit,
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultCommentsLimit
+	}
+	if input.Cursor != "" {
+		var cur commentCursor
+		if err := decodeCursor(input.Cursor, &cur); err != nil || s.validate.Var(cur.ID, "required,uuid4") != nil {
+			return domain.CommentPage{}, domain.ErrInvalidInput
+		}
+		query.After = &domain.CommentCursor{CreatedAt: cur.CreatedAt, ID: cur.ID}
+	}
+
+	// Fetch one extra row to learn whether another page follows.
+	limit := query.Limit
+	query.Limit++
+	comments, err := s.repo.ListByDocID(ctx, query)
+	if err != nil {
+		return domain.CommentPage{}, err
+	}
+	counts, err := s.repo.CountByStatus(ctx, query)
+	if err != nil {
+		return domain.CommentPage{}, err
+	}
+
+	page := domain.CommentPage{Comments: comments, Counts: counts}
+	if len(comments) > limit {
+		page.Comments = comments[:limit]
+		last := page.Comments[limit-1]
+		page.NextCursor = encodeCursor(commentCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return page, nil
+}
+
+func (s *CommentService) Get(ctx context.Context, docID string, commentID string) (domain.Comment, error) {
 	if err := s.validate.Var(docID, "required,uuid4"); err != nil {
-		return nil, domain.ErrInvalidInput
+		return domain.Comment{}, domain.ErrInvalidInput
+	}
+	if err := s.validate.Var(commentID, "required,uuid4"); err != nil {
+		return domain.Comment{}, domain.ErrInvalidInput
 	}
-	return s.repo.ListByDocID(ctx, docID)
+	if _, err := authorize(ctx, s.acl, docID, domain.RoleViewer, domain.ScopeCommentsRead); err != nil {
+		return domain.Comment{}, err
+	}
+	return s.repo.GetByID(ctx, docID, commentID)
 }
 
+// Create adds a comment authored by the authenticated user, who needs at
+// least the commenter role. Anonymous share link holders comment as guests.
 func (s *CommentService) Create(ctx context.Context, input CreateCommentInput) (domain.Comment, error) {
-	input.AuthorName = strings.TrimSpace(input.AuthorName)
 	input.Text = strings.TrimSpace(input.Text)
 	if err := s.validate.Struct(input); err != nil {
 		return domain.Comment{}, domain.ErrInvalidInput
@@ -51,20 +179,46 @@ func (s *CommentService) Create(ctx context.Context, input CreateCommentInput) (
 	if input.FromPos > input.ToPos {
 		return domain.Comment{}, domain.ErrInvalidInput
 	}
+	if (len(input.FromAnchor) == 0) != (len(input.ToAnchor) == 0) {
+		return domain.Comment{}, domain.ErrInvalidInput
+	}
+	if len(input.FromAnchor) > 0 && (!validAnchor(input.FromAnchor) || !validAnchor(input.ToAnchor)) {
+		return domain.Comment{}, domain.ErrInvalidInput
+	}
+	author, err := authorize(ctx, s.acl, input.DocID, domain.RoleCommenter, domain.ScopeCommentsWrite)
+	if err != nil {
+		return domain.Comment{}, err
+	}
 
 	comment := domain.Comment{
 		ID:         uuid.New().String(),
 		DocID:      input.DocID,
-		AuthorName: input.AuthorName,
+		AuthorName: author.Name,
 		FromPos:    input.FromPos,
 		ToPos:      input.ToPos,
+		FromAnchor: input.FromAnchor,
+		ToAnchor:   input.ToAnchor,
 		Text:       input.Text,
 		Resolved:   false,
 		CreatedAt:  utils.NowUTC(),
 	}
-	return s.repo.Create(ctx, comment)
+	if author.ID != "" {
+		comment.AuthorID = &author.ID
+	} else {
+		comment.AuthorName = domain.GuestName
+	}
+	out, err := s.repo.Create(ctx, comment)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	s.broadcast(eventCommentAdd, out)
+	s.notifyMentions(ctx, out)
+	s.webhooks.publish(ctx, out.DocID, domain.WebhookCommentCreated, CommentData{Comment: out})
+	return out, nil
 }
 
+// Update resolves or reopens a root comment, which any commenter can, or
+// edits its text, which only its author and document owners can.
 func (s *CommentService) Update(ctx context.Context, input UpdateCommentInput) (domain.Comment, error) {
 	if input.Resolved == nil && input.Text == nil {
 		return domain.Comment{}, domain.ErrInvalidInput
@@ -76,5 +230,291 @@ func (s *CommentService) Update(ctx context.Context, input UpdateCommentInput) (
 	if err := s.validate.Struct(input); err != nil {
 		return domain.Comment{}, domain.ErrInvalidInput
 	}
-	return s.repo.Update(ctx, input.DocID, input.CommentID, input.Resolved, input.Text)
+	user, err := authorize(ctx, s.acl, input.DocID, domain.RoleCommenter, domain.ScopeCommentsWrite)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	// Replies follow their thread; only the root is resolved. Reply text
+	// is edited through UpdateReply.
+	current, err := s.repo.GetByID(ctx, input.DocID, input.CommentID)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	if current.IsReply() {
+		return domain.Comment{}, domain.ErrInvalidInput
+	}
+	if input.Text != nil {
+		own := current.AuthorID != nil && user.ID != "" && *current.AuthorID == user.ID
+		if !own {
+			if _, err := authorize(ctx, s.acl, input.DocID, domain.RoleOwner, domain.ScopeCommentsWrite); err != nil {
+				return domain.Comment{}, err
+			}
+		}
+	}
+	update := commentUpdate(user)
+	update.Resolved = input.Resolved
+	update.Text = input.Text
+	update.IfVersion = input.IfVersion
+	out, err := s.repo.Update(ctx, input.DocID, input.CommentID, update)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	s.broadcast(eventCommentUpdate, out)
+	if input.Text != nil {
+		s.notifyMentions(ctx, out)
+	}
+	if input.Resolved != nil && out.Resolved && !current.Resolved {
+		s.webhooks.publish(ctx, out.DocID, domain.WebhookCommentResolved, CommentData{Comment: out})
+	}
+	return out, nil
+}
+
+// commentUpdate starts an update made by user, who is the zero User for
+// anonymous share link holders.
+func commentUpdate(user domain.User) domain.CommentUpdate {
+	if user.ID == "" {
+		return domain.CommentUpdate{Actor: domain.GuestName}
+	}
+	return domain.CommentUpdate{ActorID: &user.ID, Actor: user.Name}
+}
+
+// History returns a comment or reply with every edit of its text, oldest
+// first. Deleted comments have no history to show.
+func (s *CommentService) History(ctx context.Context, docID string, commentID string) (domain.CommentHistory, error) {
+	if err := s.validate.Var(docID, "required,uuid4"); err != nil {
+		return domain.CommentHistory{}, domain.ErrInvalidInput
+	}
+	if err := s.validate.Var(commentID, "required,uuid4"); err != nil {
+		return domain.CommentHistory{}, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, docID, domain.RoleViewer, domain.ScopeCommentsRead); err != nil {
+		return domain.CommentHistory{}, err
+	}
+	comment, err := s.repo.GetByID(ctx, docID, commentID)
+	if err != nil {
+		return domain.CommentHistory{}, err
+	}
+	if comment.DeletedAt != nil {
+		return domain.CommentHistory{}, domain.ErrNotFound
+	}
+	revisions, err := s.repo.ListRevisions(ctx, docID, commentID)
+	if err != nil {
+		return domain.CommentHistory{}, err
+	}
+	return domain.CommentHistory{Comment: comment, Revisions: revisions}, nil
+}
+
+// ListReplies returns a thread's replies, oldest first.
+func (s *CommentService) ListReplies(ctx context.Context, docID string, commentID string) ([]domain.Comment, error) {
+	if err := s.validate.Var(docID, "required,uuid4"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := s.validate.Var(commentID, "required,uuid4"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, docID, domain.RoleViewer, domain.ScopeCommentsRead); err != nil {
+		return nil, err
+	}
+	root, err := s.repo.GetByID(ctx, docID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if root.IsReply() {
+		return nil, domain.ErrNotFound
+	}
+	return s.repo.ListReplies(ctx, docID, commentID)
+}
+
+// CreateReply adds a reply to a root comment and tells connected clients.
+// Replies to replies are rejected with ErrNotFound, as threads have one
+// level.
+func (s *CommentService) CreateReply(ctx context.Context, input CreateReplyInput) (domain.Comment, error) {
+	input.Text = strings.TrimSpace(input.Text)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Comment{}, domain.ErrInvalidInput
+	}
+	author, err := authorize(ctx, s.acl, input.DocID, domain.RoleCommenter, domain.ScopeCommentsWrite)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	now := utils.NowUTC()
+	reply := domain.Comment{
+		ID:         uuid.New().String(),
+		DocID:      input.DocID,
+		ParentID:   &input.CommentID,
+		AuthorName: author.Name,
+		Text:       input.Text,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if author.ID != "" {
+		reply.AuthorID = &author.ID
+	} else {
+		reply.AuthorName = domain.GuestName
+	}
+	out, err := s.repo.Create(ctx, reply)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	s.broadcast(eventCommentReply, out)
+	s.notifyMentions(ctx, out)
+	s.webhooks.publish(ctx, out.DocID, domain.WebhookCommentCreated, CommentData{Comment: out})
+	return out, nil
+}
+
+// UpdateReply edits a reply's text. Only its author can.
+func (s *CommentService) UpdateReply(ctx context.Context, input UpdateReplyInput) (domain.Comment, error) {
+	input.Text = strings.TrimSpace(input.Text)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Comment{}, domain.ErrInvalidInput
+	}
+	user, err := authorize(ctx, s.acl, input.DocID, domain.RoleCommenter, domain.ScopeCommentsWrite)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	reply, err := s.repo.GetByID(ctx, input.DocID, input.ReplyID)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	if reply.ParentID == nil || *reply.ParentID != input.CommentID {
+		return domain.Comment{}, domain.ErrNotFound
+	}
+	if reply.AuthorID == nil || user.ID == "" || *reply.AuthorID != user.ID {
+		return domain.Comment{}, domain.ErrForbidden
+	}
+
+	update := commentUpdate(user)
+	update.Text = &input.Text
+	update.IfVersion = input.IfVersion
+	out, err := s.repo.Update(ctx, input.DocID, input.ReplyID, update)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	s.broadcast(eventCommentReplyEdit, out)
+	s.notifyMentions(ctx, out)
+	return out, nil
+}
+
+// Delete soft-deletes a comment or reply. Authors can delete their own
+// comments; document owners can delete any.
+func (s *CommentService) Delete(ctx context.Context, input DeleteCommentInput) error {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.ErrInvalidInput
+	}
+	user, err := authorize(ctx, s.acl, input.DocID, domain.RoleCommenter, domain.ScopeCommentsWrite)
+	if err != nil {
+		return err
+	}
+	comment, err := s.repo.GetByID(ctx, input.DocID, input.CommentID)
+	if err != nil {
+		return err
+	}
+	if comment.DeletedAt != nil {
+		return domain.ErrNotFound
+	}
+	own := comment.AuthorID != nil && user.ID != "" && *comment.AuthorID == user.ID
+	if !own {
+		if _, err := authorize(ctx, s.acl, input.DocID, domain.RoleOwner, domain.ScopeCommentsWrite); err != nil {
+			return err
+		}
+	}
+
+	var deletedBy *string
+	if user.ID != "" {
+		deletedBy = &user.ID
+	}
+	if _, err := s.repo.Delete(ctx, input.DocID, input.CommentID, deletedBy); err != nil {
+		return err
+	}
+	s.broadcastDeleted(comment, false)
+	return nil
+}
+
+// Purge permanently deletes a comment, deleted or not, and its replies. It
+// is the administrators' moderation tool for spam and works on any document.
+func (s *CommentService) Purge(ctx context.Context, input DeleteCommentInput) error {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.ErrInvalidInput
+	}
+	if _, err := currentAdmin(ctx); err != nil {
+		return err
+	}
+	comment, err := s.repo.Purge(ctx, input.DocID, input.CommentID)
+	if err != nil {
+		return err
+	}
+	s.broadcastDeleted(comment, true)
+	return nil
+}
+
+// AddReaction reacts to a comment as the signed-in user, who needs the
+// commenter role. Reacting twice with the same emoji changes nothing. It
+// returns the comment's reactions.
+func (s *CommentService) AddReaction(ctx context.Context, input ReactionInput) ([]domain.Reaction, error) {
+	return s.react(ctx, input, s.repo.AddReaction)
+}
+
+// RemoveReaction withdraws the signed-in user's reaction, if any, and
+// returns the comment's reactions.
+func (s *CommentService) RemoveReaction(ctx context.Context, input ReactionInput) ([]domain.Reaction, error) {
+	return s.react(ctx, input, s.repo.RemoveReaction)
+}
+
+func (s *CommentService) react(ctx context.Context, input ReactionInput, change func(ctx context.Context, docID string, commentID string, userID string, emoji string) error) ([]domain.Reaction, error) {
+	if err := s.validate.Struct(input); err != nil || !validEmoji(input.Emoji) {
+		return nil, domain.ErrInvalidInput
+	}
+	user, err := authorize(ctx, s.acl, input.DocID, domain.RoleCommenter, domain.ScopeCommentsWrite)
+	if err != nil {
+		return nil, err
+	}
+	// Reactions are kept per user, which anonymous share link holders are not.
+	if user.ID == "" {
+		return nil, domain.ErrForbidden
+	}
+	if err := change(ctx, input.DocID, input.CommentID, user.ID, input.Emoji); err != nil {
+		return nil, err
+	}
+	comment, err := s.repo.GetByID(ctx, input.DocID, input.CommentID)
+	if err != nil {
+		return nil, err
+	}
+	if s.realtime != nil {
+		s.realtime.BroadcastJSON(comment.DocID, CommentReactionEvent{
+			Type:      eventCommentReaction,
+			DocID:     comment.DocID,
+			CommentID: comment.ID,
+			ParentID:  comment.ParentID,
+			Reactions: comment.Reactions,
+		})
+	}
+	return comment.Reactions, nil
+}
+
+func (s *CommentService) broadcastDeleted(comment domain.Comment, purged bool) {
+	if s.realtime == nil {
+		return
+	}
+	s.realtime.BroadcastJSON(comment.DocID, CommentDeletedEvent{
+		Type:      eventCommentDelete,
+		DocID:     comment.DocID,
+		CommentID: comment.ID,
+		ParentID:  comment.ParentID,
+		Purged:    purged,
+	})
+}
+
+func (s *CommentService) broadcast(eventType string, comment domain.Comment) {
+	if s.realtime == nil {
+		return
+	}
+	s.realtime.BroadcastJSON(comment.DocID, CommentEvent{Type: eventType, DocID: comment.DocID, Comment: comment})
+}
+
+func (s *CommentService) notifyMentions(ctx context.Context, comment domain.Comment) {
+	if s.notifications == nil {
+		return
+	}
+	s.notifications.notifyMentions(ctx, comment)
 }
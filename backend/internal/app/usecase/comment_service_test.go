@@ -0,0 +1,237 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// memComments implements the comment repository methods the service uses.
+// Deleted comments are kept with DeletedAt set and, like in the database,
+// GetByID only finds those that are roots with live replies.
+type memComments struct {
+	ports.CommentRepository
+	byID map[string]domain.Comment
+}
+
+func (m *memComments) GetByID(ctx context.Context, docID string, commentID string) (domain.Comment, error) {
+	comment, ok := m.byID[commentID]
+	if !ok || comment.DocID != docID || (comment.DeletedAt != nil && !m.hasLiveReplies(commentID)) {
+		return domain.Comment{}, domain.ErrNotFound
+	}
+	return comment, nil
+}
+
+func (m *memComments) hasLiveReplies(commentID string) bool {
+	for _, c := range m.byID {
+		if c.ParentID != nil && *c.ParentID == commentID && c.DeletedAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memComments) Update(ctx context.Context, docID string, commentID string, update domain.CommentUpdate) (domain.Comment, error) {
+	comment, err := m.GetByID(ctx, docID, commentID)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	if update.Text != nil {
+		comment.Text = *update.Text
+	}
+	if update.Resolved != nil {
+		comment.Resolved = *update.Resolved
+	}
+	comment.Version++
+	m.byID[commentID] = comment
+	return comment, nil
+}
+
+func (m *memComments) Delete(ctx context.Context, docID string, commentID string, deletedBy *string) (domain.Comment, error) {
+	comment, err := m.GetByID(ctx, docID, commentID)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	now := time.Now()
+	comment.DeletedAt = &now
+	m.byID[commentID] = comment
+	return comment, nil
+}
+
+func (m *memComments) Purge(ctx context.Context, docID string, commentID string) (domain.Comment, error) {
+	comment, ok := m.byID[commentID]
+	if !ok || comment.DocID != docID {
+		return domain.Comment{}, domain.ErrNotFound
+	}
+	for id, c := range m.byID {
+		if id == commentID || (c.ParentID != nil && *c.ParentID == commentID) {
+			delete(m.byID, id)
+		}
+	}
+	return comment, nil
+}
+
+// memRealtime records what is broadcast.
+type memRealtime struct {
+	ports.Realtime
+	sent []any
+}
+
+func (m *memRealtime) BroadcastJSON(docID string, payload any) {
+	m.sent = append(m.sent, payload)
+}
+
+// memRoles grants each user in roles that role on every document.
+type memRoles struct {
+	ports.ACLRepository
+	roles map[string]domain.Role
+}
+
+func (m memRoles) Role(ctx context.Context, docID string, userID string) (domain.Role, error) {
+	return m.roles[userID], nil
+}
+
+type commentFixture struct {
+	service  *CommentService
+	comments *memComments
+	realtime *memRealtime
+	docID    string
+	// Users by role: alice wrote the comments, bob comments too, owen
+	// owns the document and ada is an administrator without access to it.
+	alice, bob, owen, ada context.Context
+	root, reply           domain.Comment
+}
+
+func newCommentFixture(t *testing.T) *commentFixture {
+	t.Helper()
+	users := map[string]domain.User{}
+	roles := map[string]domain.Role{}
+	ctxs := map[string]context.Context{}
+	for name, role := range map[string]domain.Role{"alice": domain.RoleCommenter, "bob": domain.RoleCommenter, "owen": domain.RoleOwner} {
+		user := domain.User{ID: uuid.New().String(), Name: name}
+		users[name] = user
+		roles[user.ID] = role
+		ctxs[name] = domain.WithUser(context.Background(), user)
+	}
+	admin := domain.User{ID: uuid.New().String(), Name: "ada", IsAdmin: true}
+
+	docID := uuid.New().String()
+	authorID := users["alice"].ID
+	root := domain.Comment{ID: uuid.New().String(), DocID: docID, AuthorID: &authorID, AuthorName: "alice", Text: "root"}
+	reply := domain.Comment{ID: uuid.New().String(), DocID: docID, ParentID: &root.ID, AuthorID: &authorID, AuthorName: "alice", Text: "reply"}
+	comments := &memComments{byID: map[string]domain.Comment{root.ID: root, reply.ID: reply}}
+	realtime := &memRealtime{}
+	return &commentFixture{
+		service:  NewCommentService(comments, memRoles{roles: roles}, realtime, nil, nil, validator.New()),
+		comments: comments,
+		realtime: realtime,
+		docID:    docID,
+		alice:    ctxs["alice"],
+		bob:      ctxs["bob"],
+		owen:     ctxs["owen"],
+		ada:      domain.WithUser(context.Background(), admin),
+		root:     root,
+		reply:    reply,
+	}
+}
+
+func TestCommentUpdateText(t *testing.T) {
+	f := newCommentFixture(t)
+	text := func(s string) *string { return &s }
+	resolved := true
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		comment domain.Comment
+		input   UpdateCommentInput
+		wantErr error
+	}{
+		{"author edits", f.alice, f.root, UpdateCommentInput{Text: text("by alice")}, nil},
+		{"owner edits", f.owen, f.root, UpdateCommentInput{Text: text("by owen")}, nil},
+		{"another commenter edits", f.bob, f.root, UpdateCommentInput{Text: text("by bob")}, domain.ErrForbidden},
+		{"another commenter resolves", f.bob, f.root, UpdateCommentInput{Resolved: &resolved}, nil},
+		{"another commenter edits while resolving", f.bob, f.root, UpdateCommentInput{Resolved: &resolved, Text: text("by bob")}, domain.ErrForbidden},
+		{"author edits a reply", f.alice, f.reply, UpdateCommentInput{Text: text("by alice")}, domain.ErrInvalidInput},
+		{"another commenter edits a reply", f.bob, f.reply, UpdateCommentInput{Text: text("by bob")}, domain.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := f.comments.byID[tt.comment.ID]
+			tt.input.DocID, tt.input.CommentID = f.docID, tt.comment.ID
+			out, err := f.service.Update(tt.ctx, tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			after := f.comments.byID[tt.comment.ID]
+			if tt.wantErr != nil {
+				if after.Text != before.Text || after.Version != before.Version {
+					t.Errorf("refused update changed the comment to %+v", after)
+				}
+				return
+			}
+			if tt.input.Text != nil && out.Text != *tt.input.Text {
+				t.Errorf("text = %q, want %q", out.Text, *tt.input.Text)
+			}
+		})
+	}
+}
+
+func TestCommentPurge(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     func(f *commentFixture) context.Context
+		delete  bool
+		wantErr error
+	}{
+		{"live comment", func(f *commentFixture) context.Context { return f.ada }, false, nil},
+		{"deleted comment", func(f *commentFixture) context.Context { return f.ada }, true, nil},
+		{"by the doc owner", func(f *commentFixture) context.Context { return f.owen }, false, domain.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCommentFixture(t)
+			input := DeleteCommentInput{DocID: f.docID, CommentID: f.root.ID}
+			if tt.delete {
+				// Without live replies the deleted root is no longer shown.
+				for _, id := range []string{f.reply.ID, f.root.ID} {
+					if err := f.service.Delete(f.alice, DeleteCommentInput{DocID: f.docID, CommentID: id}); err != nil {
+						t.Fatalf("Delete: %v", err)
+					}
+				}
+				if _, err := f.comments.GetByID(context.Background(), f.docID, f.root.ID); !errors.Is(err, domain.ErrNotFound) {
+					t.Fatalf("deleted root still found: %v", err)
+				}
+				f.realtime.sent = nil
+			}
+
+			err := f.service.Purge(tt.ctx(f), input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if _, ok := f.comments.byID[f.root.ID]; !ok {
+					t.Error("refused purge removed the comment")
+				}
+				return
+			}
+			if len(f.comments.byID) != 0 {
+				t.Errorf("%d comments left, want the root and its reply purged", len(f.comments.byID))
+			}
+			want := CommentDeletedEvent{Type: eventCommentDelete, DocID: f.docID, CommentID: f.root.ID, Purged: true}
+			if len(f.realtime.sent) != 1 || f.realtime.sent[0] != any(want) {
+				t.Errorf("broadcast %+v, want %+v", f.realtime.sent, want)
+			}
+		})
+	}
+
+	f := newCommentFixture(t)
+	if err := f.service.Purge(f.ada, DeleteCommentInput{DocID: f.docID, CommentID: uuid.New().String()}); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("unknown comment: err = %v, want ErrNotFound", err)
+	}
+}
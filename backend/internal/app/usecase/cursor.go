@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Cursors are opaque to clients: URL-safe base64 of a small JSON object
+// describing where the previous page ended.
+
+func encodeCursor(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
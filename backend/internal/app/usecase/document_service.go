@@ -3,38 +3,161 @@ package usecase
 import (
 	"context"
 	"strings"
+	"time"
 
 	"collabdocs/internal/app/ports"
 	"collabdocs/internal/domain"
 	"collabdocs/pkg/utils"
+	"collabdocs/pkg/yjs"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 )
 
 type DocumentService struct {
-	repo     ports.DocumentRepository
-	validate *validator.Validate
+	repo       ports.DocumentRepository
+	snapshots  ports.SnapshotRepository
+	updates    ports.UpdateRepository
+	properties ports.PropertyRepository
+	folders    ports.FolderRepository
+	users      ports.UserRepository
+	acl        ports.ACLRepository
+	realtime   ports.Realtime
+	webhooks   *WebhookService
+	validate   *validator.Validate
 }
 
 type CreateDocumentInput struct {
-	Title string `validate:"max=120"`
+	Title    string `validate:"max=120"`
+	FolderID string `validate:"omitempty,uuid4"`
+	// TemplateID starts the document from a template's title and content,
+	// with {{variables}} substituted.
+	TemplateID string            `validate:"omitempty,uuid4"`
+	Author     string            `validate:"max=40"`
+	Variables  map[string]string `validate:"max=50,dive,keys,min=1,max=40,endkeys,max=500"`
 }
 
+// UpdateDocumentInput is a partial update. A nil Title is left unchanged and
+// a null property value clears that property.
 type UpdateDocumentInput struct {
-	ID    string `validate:"required,uuid4"`
-	Title string `validate:"required,max=120"`
+	ID         string `validate:"required,uuid4"`
+	Title      *string
+	Properties map[string]any `validate:"max=50"`
+	// IfVersion makes the update conditional on the document's version.
+	IfVersion *int64
+}
+
+type ListDocumentsInput struct {
+	Sort          string `validate:"omitempty,oneof=title created updated"`
+	Order         string `validate:"omitempty,oneof=asc desc"`
+	Trashed       bool
+	IsTemplate    *bool
+	FolderID      string                `validate:"omitempty,uuid4"`
+	Tags          []string              `validate:"max=20,dive,required,max=40"`
+	TagMode       string                `validate:"omitempty,oneof=any all"`
+	Properties    []PropertyFilterInput `validate:"max=20"`
+	TitlePrefix   string                `validate:"max=120"`
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Cursor        string `validate:"max=512"`
+	Limit         int    `validate:"min=0,max=100"`
+}
+
+// documentCursor is the decoded form of ListDocumentsInput.Cursor. It records
+// the ordering it was issued for so it cannot be replayed against another.
+type documentCursor struct {
+	Sort  domain.DocumentSort `json:"s"`
+	Desc  bool                `json:"d"`
+	Value string              `json:"v"`
+	ID    string              `json:"id"`
+}
+
+const defaultDocumentsLimit = 50
+
+type LockDocumentInput struct {
+	ID            string `validate:"required,uuid4"`
+	AllowComments bool
+	Reason        string `validate:"max=500"`
+}
+
+type ArchiveDocumentInput struct {
+	ID     string `validate:"required,uuid4"`
+	Reason string `validate:"max=500"`
+}
+
+// UnlockDocumentInput reopens a locked or archived document. The reason is
+// mandatory as unlocking undoes a deliberate freeze.
+type UnlockDocumentInput struct {
+	ID     string `validate:"required,uuid4"`
+	Reason string `validate:"required,max=500"`
 }
 
 type DeleteDocumentInput struct {
 	ID string `validate:"required,uuid4"`
+	// IfVersion makes the delete conditional on the document's version.
+	IfVersion *int64
 }
 
-func NewDocumentService(repo ports.DocumentRepository, validate *validator.Validate) *DocumentService {
-	return &DocumentService{repo: repo, validate: validate}
+type DuplicateDocumentInput struct {
+	ID              string `validate:"required,uuid4"`
+	Title           string `validate:"max=120"`
+	IncludeComments bool
 }
 
+type RestoreDocumentInput struct {
+	ID string `validate:"required,uuid4"`
+}
+
+type MoveDocumentInput struct {
+	ID       string `validate:"required,uuid4"`
+	FolderID string `validate:"required,uuid4"`
+}
+
+type SetTemplateInput struct {
+	ID         string `validate:"required,uuid4"`
+	IsTemplate bool
+}
+
+// NewDocumentService creates the service. realtime may be nil, in which case
+// state changes are not pushed to connected clients, and webhooks may be nil,
+// in which case no webhook events are raised.
+func NewDocumentService(repo ports.DocumentRepository, snapshots ports.SnapshotRepository, updates ports.UpdateRepository, properties ports.PropertyRepository, folders ports.FolderRepository, users ports.UserRepository, acl ports.ACLRepository, realtime ports.Realtime, webhooks *WebhookService, validate *validator.Validate) *DocumentService {
+	return &DocumentService{repo: repo, snapshots: snapshots, updates: updates, properties: properties, folders: folders, users: users, acl: acl, realtime: realtime, webhooks: webhooks, validate: validate}
+}
+
+// Create adds a document owned by the current user. It is filed in the given
+// folder, which must be in a workspace the user can use, or else in the root
+// of the default workspace.
 func (s *DocumentService) Create(ctx context.Context, input CreateDocumentInput) (domain.Document, error) {
+	owner, err := currentUser(ctx)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	if err := keyAllows(ctx, domain.ScopeDocsWrite, ""); err != nil {
+		return domain.Document{}, err
+	}
 	input.Title = strings.TrimSpace(input.Title)
+	input.Author = strings.TrimSpace(input.Author)
+	if input.Author == "" {
+		input.Author = owner.Name
+	}
+	if input.FolderID != "" {
+		if err := s.validate.Var(input.FolderID, "uuid4"); err != nil {
+			return domain.Document{}, domain.ErrInvalidInput
+		}
+		if _, err := folderAccess(ctx, s.folders, input.FolderID); err != nil {
+			return domain.Document{}, err
+		}
+	}
+	if input.TemplateID != "" {
+		doc, err := s.createFromTemplate(ctx, owner, input)
+		if err != nil {
+			return domain.Document{}, err
+		}
+		s.publishCreated(ctx, doc)
+		return doc, nil
+	}
 	if input.Title == "" {
 		input.Title = "Untitled Document"
 	}
@@ -44,32 +167,446 @@ func (s *DocumentService) Create(ctx context.Context, input CreateDocumentInput)
 
 	id := uuid.New().String()
 	now := utils.NowUTC()
-	return s.repo.Create(ctx, domain.Document{
+	doc, err := s.repo.Create(ctx, domain.Document{
 		ID:        id,
 		Title:     input.Title,
+		FolderID:  input.FolderID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, owner.ID)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	s.publishCreated(ctx, doc)
+	return doc, nil
+}
+
+// createFromTemplate needs the caller to be able to view the template.
+func (s *DocumentService) createFromTemplate(ctx context.Context, owner domain.User, input CreateDocumentInput) (domain.Document, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Document{}, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, input.TemplateID, domain.RoleViewer, domain.ScopeDocsRead); err != nil {
+		return domain.Document{}, err
+	}
+	tmpl, err := s.repo.GetByID(ctx, input.TemplateID)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	if !tmpl.IsTemplate {
+		return domain.Document{}, domain.ErrInvalidInput
+	}
+
+	now := utils.NowUTC()
+	vars := templateVariables(input.Author, input.Variables, now)
+	if input.Title == "" {
+		input.Title = truncateRunes(renderTemplate(tmpl.Title, vars), 120)
+	}
+	if input.Title == "" {
+		input.Title = "Untitled Document"
+	}
+	doc := domain.Document{
+		ID:        uuid.New().String(),
+		Title:     input.Title,
+		FolderID:  input.FolderID,
 		CreatedAt: now,
 		UpdatedAt: now,
+	}
+
+	snapshot, err := s.snapshots.GetSnapshot(ctx, tmpl.ID)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	if len(snapshot) == 0 {
+		return s.repo.Create(ctx, doc, owner.ID)
+	}
+	content, err := yjs.MapText(snapshot, func(text string) string {
+		return renderTemplate(text, vars)
 	})
+	if err != nil {
+		return domain.Document{}, err
+	}
+	text, err := yjs.ExtractText(content)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	return s.repo.CreateWithSnapshot(ctx, doc, owner.ID, content, text)
 }
 
 func (s *DocumentService) Get(ctx context.Context, id string) (domain.Document, error) {
 	if err := s.validate.Var(id, "required,uuid4"); err != nil {
 		return domain.Document{}, domain.ErrInvalidInput
 	}
+	if _, err := authorize(ctx, s.acl, id, domain.RoleViewer, domain.ScopeDocsRead); err != nil {
+		return domain.Document{}, err
+	}
 	return s.repo.GetByID(ctx, id)
 }
 
-func (s *DocumentService) UpdateTitle(ctx context.Context, input UpdateDocumentInput) (domain.Document, error) {
+// List returns the documents the current user can view.
+func (s *DocumentService) List(ctx context.Context, input ListDocumentsInput) (domain.DocumentPage, error) {
+	viewer, err := currentUser(ctx)
+	if err != nil {
+		return domain.DocumentPage{}, err
+	}
+	ids, err := keyDocIDs(ctx, domain.ScopeDocsRead)
+	if err != nil {
+		return domain.DocumentPage{}, err
+	}
+	input.TitlePrefix = strings.TrimSpace(input.TitlePrefix)
+	for i, tag := range input.Tags {
+		input.Tags[i] = normalizeTag(tag)
+	}
+	if err := s.validate.Struct(input); err != nil {
+		return domain.DocumentPage{}, domain.ErrInvalidInput
+	}
+	properties, err := propertyFilters(s.validate, input.Properties)
+	if err != nil {
+		return domain.DocumentPage{}, err
+	}
+
+	query := domain.DocumentListQuery{
+		ViewerID:      viewer.ID,
+		IDs:           ids,
+		Sort:          domain.DocumentSort(input.Sort),
+		Desc:          input.Order != "asc",
+		Trashed:       input.Trashed,
+		IsTemplate:    input.IsTemplate,
+		FolderID:      input.FolderID,
+		Tags:          input.Tags,
+		AllTags:       input.TagMode == "all",
+		Properties:    properties,
+		TitlePrefix:   input.TitlePrefix,
+		CreatedAfter:  input.CreatedAfter,
+		CreatedBefore: input.CreatedBefore,
+		UpdatedAfter:  input.UpdatedAfter,
+		UpdatedBefore: input.UpdatedBefore,
+		Limit:         input.Limit,
+	}
+	if query.Sort == "" {
+		query.Sort = domain.DocumentSortUpdated
+	}
+	if input.Order == "" && query.Sort == domain.DocumentSortTitle {
+		query.Desc = false
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultDocumentsLimit
+	}
+	if input.Cursor != "" {
+		var cur documentCursor
+		if err := decodeCursor(input.Cursor, &cur); err != nil {
+			return domain.DocumentPage{}, domain.ErrInvalidInput
+		}
+		if cur.Sort != query.Sort || cur.Desc != query.Desc || s.validate.Var(cur.ID, "required,uuid4") != nil {
+			return domain.DocumentPage{}, domain.ErrInvalidInput
+		}
+		query.After = &domain.DocumentCursor{Value: cur.Value, ID: cur.ID}
+	}
+
+	// Fetch one extra row to learn whether another page follows.
+	limit := query.Limit
+	query.Limit++
+	docs, total, err := s.repo.List(ctx, query)
+	if err != nil {
+		return domain.DocumentPage{}, err
+	}
+
+	page := domain.DocumentPage{Documents: docs, Total: total}
+	if len(docs) > limit {
+		page.Documents = docs[:limit]
+		last := page.Documents[limit-1]
+		page.NextCursor = encodeCursor(documentCursor{
+			Sort:  query.Sort,
+			Desc:  query.Desc,
+			Value: documentSortValue(last, query.Sort),
+			ID:    last.ID,
+		})
+	}
+	return page, nil
+}
+
+func documentSortValue(doc domain.Document, sort domain.DocumentSort) string {
+	switch sort {
+	case domain.DocumentSortTitle:
+		return doc.Title
+	case domain.DocumentSortCreated:
+		return doc.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		return doc.UpdatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// Update changes a document's title and custom properties. Property values
+// are checked against the schema of the document's workspace.
+func (s *DocumentService) Update(ctx context.Context, input UpdateDocumentInput) (domain.Document, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Document{}, domain.ErrInvalidInput
+	}
+	if input.Title == nil && len(input.Properties) == 0 {
+		return domain.Document{}, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, input.ID, domain.RoleEditor, domain.ScopeDocsWrite); err != nil {
+		return domain.Document{}, err
+	}
+
+	update := domain.DocumentUpdate{IfVersion: input.IfVersion}
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if err := s.validate.Var(title, "required,max=120"); err != nil {
+			return domain.Document{}, domain.ErrInvalidInput
+		}
+		update.Title = &title
+	}
+	// The current document gives the folder's property definitions and the
+	// previous title for the rename event.
+	doc, err := s.repo.GetByID(ctx, input.ID)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	if len(input.Properties) > 0 {
+		defs, err := s.properties.ListDefinitionsForFolder(ctx, doc.FolderID)
+		if err != nil {
+			return domain.Document{}, err
+		}
+		update.SetProperties, update.UnsetProperties, err = validateProperties(ctx, s.validate, s.users, defs, input.Properties)
+		if err != nil {
+			return domain.Document{}, err
+		}
+	}
+	out, err := s.repo.Update(ctx, input.ID, update)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	if out.Title != doc.Title {
+		s.webhooks.publish(ctx, out.ID, domain.WebhookDocRenamed, DocumentRenamedData{Document: out, PreviousTitle: doc.Title})
+	}
+	return out, nil
+}
+
+// Duplicate creates a copy of a document with its current content: the saved
+// snapshot merged with the updates made since. Without an explicit title the
+// copy is named "Copy of <source title>". Anyone who can view the source can
+// copy it and owns the copy.
+func (s *DocumentService) Duplicate(ctx context.Context, input DuplicateDocumentInput) (domain.Document, error) {
 	input.Title = strings.TrimSpace(input.Title)
 	if err := s.validate.Struct(input); err != nil {
 		return domain.Document{}, domain.ErrInvalidInput
 	}
-	return s.repo.UpdateTitle(ctx, input.ID, input.Title)
+	owner, err := currentUser(ctx)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	if err := keyAllows(ctx, domain.ScopeDocsWrite, ""); err != nil {
+		return domain.Document{}, err
+	}
+	if _, err := authorize(ctx, s.acl, input.ID, domain.RoleViewer, domain.ScopeDocsRead); err != nil {
+		return domain.Document{}, err
+	}
+	source, err := s.repo.GetByID(ctx, input.ID)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	title := input.Title
+	if title == "" {
+		title = truncateRunes("Copy of "+source.Title, 120)
+	}
+	content, text, err := s.currentContent(ctx, source.ID)
+	if err != nil {
+		return domain.Document{}, err
+	}
+
+	now := utils.NowUTC()
+	doc, err := s.repo.Duplicate(ctx, source.ID, domain.Document{
+		ID:        uuid.New().String(),
+		Title:     title,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, owner.ID, input.IncludeComments, content, text)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	s.publishCreated(ctx, doc)
+	return doc, nil
+}
+
+// currentContent returns the document's snapshot with the pending updates
+// merged in, and its text. Content the decoder cannot read is returned as
+// saved, with a nil text.
+func (s *DocumentService) currentContent(ctx context.Context, docID string) ([]byte, *string, error) {
+	snapshot, err := s.snapshots.GetSnapshot(ctx, docID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pending, err := s.updates.ListPending(ctx, docID)
+	if err != nil {
+		return nil, nil, err
+	}
+	content := snapshot
+	if len(pending) > 0 {
+		if len(snapshot) > 0 {
+			pending = append([][]byte{snapshot}, pending...)
+		}
+		if content, err = yjs.MergeUpdates(pending...); err != nil {
+			return snapshot, nil, nil
+		}
+	}
+	if len(content) == 0 {
+		return nil, nil, nil
+	}
+	text, err := yjs.ExtractText(content)
+	if err != nil {
+		return snapshot, nil, nil
+	}
+	return content, &text, nil
+}
+
+func (s *DocumentService) publishCreated(ctx context.Context, doc domain.Document) {
+	s.webhooks.publish(ctx, doc.ID, domain.WebhookDocCreated, DocumentData{Document: doc})
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n]))
+}
+
+// Move files the document in another folder, possibly of another workspace.
+// The caller must be able to use the target folder's workspace.
+func (s *DocumentService) Move(ctx context.Context, input MoveDocumentInput) (domain.Document, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Document{}, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, input.ID, domain.RoleEditor, domain.ScopeDocsWrite); err != nil {
+		return domain.Document{}, err
+	}
+	if _, err := folderAccess(ctx, s.folders, input.FolderID); err != nil {
+		return domain.Document{}, err
+	}
+	return s.repo.MoveToFolder(ctx, input.ID, input.FolderID)
+}
+
+func (s *DocumentService) SetTemplate(ctx context.Context, input SetTemplateInput) (domain.Document, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Document{}, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, input.ID, domain.RoleEditor, domain.ScopeDocsWrite); err != nil {
+		return domain.Document{}, err
+	}
+	return s.repo.SetTemplate(ctx, input.ID, input.IsTemplate)
 }
 
+// Delete moves a document to the trash. Only owners can delete or restore.
 func (s *DocumentService) Delete(ctx context.Context, input DeleteDocumentInput) error {
 	if err := s.validate.Struct(input); err != nil {
 		return domain.ErrInvalidInput
 	}
-	return s.repo.Delete(ctx, input.ID)
+	if _, err := authorize(ctx, s.acl, input.ID, domain.RoleOwner, domain.ScopeDocsManage); err != nil {
+		return err
+	}
+	doc, err := s.repo.GetByID(ctx, input.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, input.ID, input.IfVersion); err != nil {
+		return err
+	}
+	s.webhooks.publish(ctx, doc.ID, domain.WebhookDocDeleted, DocumentData{Document: doc})
+	return nil
+}
+
+func (s *DocumentService) Restore(ctx context.Context, input RestoreDocumentInput) (domain.Document, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Document{}, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, input.ID, domain.RoleOwner, domain.ScopeDocsManage); err != nil {
+		return domain.Document{}, err
+	}
+	return s.repo.Restore(ctx, input.ID)
+}
+
+// PurgeTrash permanently deletes documents that have been in the trash for
+// longer than retention.
+func (s *DocumentService) PurgeTrash(ctx context.Context, retention time.Duration) (int64, error) {
+	if retention <= 0 {
+		return 0, domain.ErrInvalidInput
+	}
+	return s.repo.PurgeDeleted(ctx, utils.NowUTC().Add(-retention))
+}
+
+// Lock makes an active or locked document read-only. Comments stay open when
+// AllowComments is set.
+func (s *DocumentService) Lock(ctx context.Context, input LockDocumentInput) (domain.Document, error) {
+	input.Reason = strings.TrimSpace(input.Reason)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Document{}, domain.ErrInvalidInput
+	}
+	return s.changeState(ctx, input.ID, domain.DocumentLocked, input.AllowComments, input.Reason)
+}
+
+// Archive freezes a document, comments included.
+func (s *DocumentService) Archive(ctx context.Context, input ArchiveDocumentInput) (domain.Document, error) {
+	input.Reason = strings.TrimSpace(input.Reason)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Document{}, domain.ErrInvalidInput
+	}
+	return s.changeState(ctx, input.ID, domain.DocumentArchived, false, input.Reason)
+}
+
+func (s *DocumentService) Unlock(ctx context.Context, input UnlockDocumentInput) (domain.Document, error) {
+	input.Reason = strings.TrimSpace(input.Reason)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Document{}, domain.ErrInvalidInput
+	}
+	return s.changeState(ctx, input.ID, domain.DocumentActive, true, input.Reason)
+}
+
+// changeState moves a document to a new state, records the acting owner and
+// tells connected clients. Archived documents can only be unlocked, and
+// unlocking an active document is rejected with ErrConflict.
+func (s *DocumentService) changeState(ctx context.Context, id string, to domain.DocumentState, allowComments bool, reason string) (domain.Document, error) {
+	actor, err := authorize(ctx, s.acl, id, domain.RoleOwner, domain.ScopeDocsManage)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	doc, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	switch {
+	case to == domain.DocumentActive && doc.State == domain.DocumentActive,
+		to == domain.DocumentLocked && doc.State == domain.DocumentArchived,
+		to == domain.DocumentArchived && doc.State == domain.DocumentArchived:
+		return domain.Document{}, domain.ErrConflict
+	}
+
+	doc, err = s.repo.SetState(ctx, domain.DocumentStateEvent{
+		ID:            uuid.New().String(),
+		DocID:         id,
+		FromState:     doc.State,
+		ToState:       to,
+		AllowComments: allowComments,
+		ActorID:       &actor.ID,
+		Actor:         actor.Name,
+		Reason:        reason,
+		CreatedAt:     utils.NowUTC(),
+	})
+	if err != nil {
+		return domain.Document{}, err
+	}
+	if s.realtime != nil {
+		s.realtime.BroadcastJSON(doc.ID, NewDocStateEvent(doc))
+	}
+	return doc, nil
+}
+
+func (s *DocumentService) ListStateEvents(ctx context.Context, id string) ([]domain.DocumentStateEvent, error) {
+	if err := s.validate.Var(id, "required,uuid4"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, id, domain.RoleViewer, domain.ScopeDocsRead); err != nil {
+		return nil, err
+	}
+	return s.repo.ListStateEvents(ctx, id)
 }
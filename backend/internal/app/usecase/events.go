@@ -0,0 +1,90 @@
+package usecase
+
+import "collabdocs/internal/domain"
+
+// Events pushed to document rooms through ports.Realtime. Like the client
+// messages in the ws adapter, each carries a "type" discriminator.
+
+const (
+	eventTagsUpdate       = "tags:update"
+	eventDocState         = "doc:state"
+	eventCommentAdd       = "comment:add"
+	eventCommentUpdate    = "comment:update"
+	eventCommentReply     = "comment:reply"
+	eventCommentReplyEdit = "comment:reply:update"
+	eventCommentDelete    = "comment:delete"
+	eventCommentReaction  = "comment:reaction"
+	eventSuggestionAdd    = "suggestion:add"
+	eventSuggestionAccept = "suggestion:accept"
+	eventSuggestionReject = "suggestion:reject"
+)
+
+// Events pushed to a user's own channel through ports.UserRealtime.
+const eventNotificationsUnread = "notifications:unread"
+
+type TagsEvent struct {
+	Type  string   `json:"type"`
+	DocID string   `json:"docId"`
+	Tags  []string `json:"tags"`
+}
+
+// CommentEvent carries a comment created or changed through the REST API.
+type CommentEvent struct {
+	Type    string         `json:"type"`
+	DocID   string         `json:"docId"`
+	Comment domain.Comment `json:"comment"`
+}
+
+// CommentDeletedEvent reports a deleted comment. Purged comments are gone
+// with their replies; other deleted roots may stay as tombstones.
+type CommentDeletedEvent struct {
+	Type      string  `json:"type"`
+	DocID     string  `json:"docId"`
+	CommentID string  `json:"commentId"`
+	ParentID  *string `json:"parentId,omitempty"`
+	Purged    bool    `json:"purged"`
+}
+
+// CommentReactionEvent carries all reactions of a comment after one of them
+// changed.
+type CommentReactionEvent struct {
+	Type      string            `json:"type"`
+	DocID     string            `json:"docId"`
+	CommentID string            `json:"commentId"`
+	ParentID  *string           `json:"parentId,omitempty"`
+	Reactions []domain.Reaction `json:"reactions"`
+}
+
+// SuggestionEvent carries a suggestion that was made, accepted or rejected.
+// The change of an accepted suggestion reaches clients as a Yjs update.
+type SuggestionEvent struct {
+	Type       string            `json:"type"`
+	DocID      string            `json:"docId"`
+	Suggestion domain.Suggestion `json:"suggestion"`
+}
+
+// DocStateEvent tells clients whether the document accepts edits.
+type DocStateEvent struct {
+	Type          string               `json:"type"`
+	DocID         string               `json:"docId"`
+	State         domain.DocumentState `json:"state"`
+	AllowComments bool                 `json:"allowComments"`
+}
+
+// NewDocStateEvent describes the current state of doc.
+func NewDocStateEvent(doc domain.Document) DocStateEvent {
+	return DocStateEvent{Type: eventDocState, DocID: doc.ID, State: doc.State, AllowComments: doc.AllowComments}
+}
+
+// UnreadEvent carries a user's unread notification count, and the
+// notification that was just added, if any.
+type UnreadEvent struct {
+	Type         string               `json:"type"`
+	Unread       int                  `json:"unread"`
+	Notification *domain.Notification `json:"notification,omitempty"`
+}
+
+// NewUnreadEvent describes an unread count.
+func NewUnreadEvent(unread int, n *domain.Notification) UnreadEvent {
+	return UnreadEvent{Type: eventNotificationsUnread, Unread: unread, Notification: n}
+}
@@ -0,0 +1,296 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type FolderService struct {
+	repo     ports.FolderRepository
+	users    ports.UserRepository
+	validate *validator.Validate
+}
+
+type CreateWorkspaceInput struct {
+	Name string `validate:"required,max=80"`
+}
+
+type CreateFolderInput struct {
+	ParentID string `validate:"required,uuid4"`
+	Name     string `validate:"required,max=120"`
+}
+
+type RenameFolderInput struct {
+	ID   string `validate:"required,uuid4"`
+	Name string `validate:"required,max=120"`
+}
+
+type MoveFolderInput struct {
+	ID       string `validate:"required,uuid4"`
+	ParentID string `validate:"required,uuid4,nefield=ID"`
+}
+
+type DeleteFolderInput struct {
+	ID string `validate:"required,uuid4"`
+}
+
+// AddWorkspaceMemberInput names the new member by user ID or email.
+type AddWorkspaceMemberInput struct {
+	WorkspaceID string `validate:"required,uuid4"`
+	UserID      string `validate:"omitempty,uuid4"`
+	Email       string `validate:"omitempty,email,max=254"`
+}
+
+type RemoveWorkspaceMemberInput struct {
+	WorkspaceID string `validate:"required,uuid4"`
+	UserID      string `validate:"required,uuid4"`
+}
+
+func NewFolderService(repo ports.FolderRepository, users ports.UserRepository, validate *validator.Validate) *FolderService {
+	return &FolderService{repo: repo, users: users, validate: validate}
+}
+
+// CreateWorkspace makes a workspace owned by the current user, who is its
+// first member.
+func (s *FolderService) CreateWorkspace(ctx context.Context, input CreateWorkspaceInput) (domain.Workspace, error) {
+	owner, err := currentUser(ctx)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Workspace{}, domain.ErrInvalidInput
+	}
+
+	now := utils.NowUTC()
+	workspace := domain.Workspace{
+		ID:        uuid.New().String(),
+		Name:      input.Name,
+		OwnerID:   owner.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	root := domain.Folder{
+		ID:        uuid.New().String(),
+		Name:      input.Name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return s.repo.CreateWorkspace(ctx, workspace, root)
+}
+
+// ListWorkspaces returns the workspaces the current user can use.
+func (s *FolderService) ListWorkspaces(ctx context.Context) ([]domain.Workspace, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListWorkspaces(ctx, user.ID)
+}
+
+func (s *FolderService) GetWorkspace(ctx context.Context, id string) (domain.Workspace, error) {
+	if err := s.validate.Var(id, "required,uuid4"); err != nil {
+		return domain.Workspace{}, domain.ErrInvalidInput
+	}
+	return workspaceAccess(ctx, s.repo, id)
+}
+
+// ListMembers returns the members of a workspace. The default workspace has
+// no member list as it is open to everyone.
+func (s *FolderService) ListMembers(ctx context.Context, workspaceID string) ([]domain.User, error) {
+	if err := s.validate.Var(workspaceID, "required,uuid4"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if _, err := workspaceAccess(ctx, s.repo, workspaceID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListWorkspaceMembers(ctx, workspaceID)
+}
+
+// AddMember adds a user to a workspace the current user owns.
+func (s *FolderService) AddMember(ctx context.Context, input AddWorkspaceMemberInput) error {
+	input.Email = strings.TrimSpace(input.Email)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.ErrInvalidInput
+	}
+	if (input.UserID == "") == (input.Email == "") {
+		return domain.ErrInvalidInput
+	}
+	if _, err := ownedWorkspace(ctx, s.repo, input.WorkspaceID); err != nil {
+		return err
+	}
+	var (
+		user domain.User
+		err  error
+	)
+	if input.Email != "" {
+		user, err = s.users.GetByEmail(ctx, input.Email)
+	} else {
+		user, err = s.users.GetByID(ctx, input.UserID)
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.AddWorkspaceMember(ctx, input.WorkspaceID, user.ID)
+}
+
+// RemoveMember takes a user out of a workspace. The owner can remove anyone
+// but themselves; other members can only leave. Documents filed in the
+// workspace keep their own access lists.
+func (s *FolderService) RemoveMember(ctx context.Context, input RemoveWorkspaceMemberInput) error {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.ErrInvalidInput
+	}
+	user, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	workspace, err := workspaceAccess(ctx, s.repo, input.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	if workspace.IsDefault {
+		return domain.ErrForbidden
+	}
+	if input.UserID == workspace.OwnerID {
+		return domain.ErrConflict
+	}
+	if user.ID != workspace.OwnerID && user.ID != input.UserID {
+		return domain.ErrForbidden
+	}
+	return s.repo.RemoveWorkspaceMember(ctx, input.WorkspaceID, input.UserID)
+}
+
+func (s *FolderService) CreateFolder(ctx context.Context, input CreateFolderInput) (domain.Folder, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Folder{}, domain.ErrInvalidInput
+	}
+	if _, err := folderAccess(ctx, s.repo, input.ParentID); err != nil {
+		return domain.Folder{}, err
+	}
+
+	now := utils.NowUTC()
+	return s.repo.CreateFolder(ctx, domain.Folder{
+		ID:        uuid.New().String(),
+		ParentID:  &input.ParentID,
+		Name:      input.Name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+func (s *FolderService) GetFolder(ctx context.Context, id string) (domain.Folder, error) {
+	if err := s.validate.Var(id, "required,uuid4"); err != nil {
+		return domain.Folder{}, domain.ErrInvalidInput
+	}
+	return folderAccess(ctx, s.repo, id)
+}
+
+func (s *FolderService) ListSubfolders(ctx context.Context, id string) ([]domain.Folder, error) {
+	if err := s.validate.Var(id, "required,uuid4"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if _, err := folderAccess(ctx, s.repo, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListSubfolders(ctx, id)
+}
+
+func (s *FolderService) RenameFolder(ctx context.Context, input RenameFolderInput) (domain.Folder, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Folder{}, domain.ErrInvalidInput
+	}
+	if _, err := folderAccess(ctx, s.repo, input.ID); err != nil {
+		return domain.Folder{}, err
+	}
+	return s.repo.RenameFolder(ctx, input.ID, input.Name)
+}
+
+// MoveFolder moves a folder, with everything below it, under a new parent.
+// Root folders cannot be moved and a folder cannot move into its own subtree.
+// The caller must be able to use both workspaces involved.
+func (s *FolderService) MoveFolder(ctx context.Context, input MoveFolderInput) (domain.Folder, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Folder{}, domain.ErrInvalidInput
+	}
+	for _, id := range []string{input.ID, input.ParentID} {
+		if _, err := folderAccess(ctx, s.repo, id); err != nil {
+			return domain.Folder{}, err
+		}
+	}
+	return s.repo.MoveFolder(ctx, input.ID, input.ParentID)
+}
+
+// DeleteFolder deletes an empty folder. Folders that still hold subfolders or
+// documents are rejected with ErrConflict.
+func (s *FolderService) DeleteFolder(ctx context.Context, input DeleteFolderInput) error {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.ErrInvalidInput
+	}
+	if _, err := folderAccess(ctx, s.repo, input.ID); err != nil {
+		return err
+	}
+	return s.repo.DeleteFolder(ctx, input.ID)
+}
+
+// workspaceAccess returns the workspace if the current user can use it. The
+// default workspace is open to every signed-in user and other workspaces to
+// their members; everyone else gets ErrNotFound.
+func workspaceAccess(ctx context.Context, folders ports.FolderRepository, workspaceID string) (domain.Workspace, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	workspace, err := folders.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	if workspace.IsDefault {
+		return workspace, nil
+	}
+	member, err := folders.IsWorkspaceMember(ctx, workspaceID, user.ID)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	if !member {
+		return domain.Workspace{}, domain.ErrNotFound
+	}
+	return workspace, nil
+}
+
+// ownedWorkspace returns the workspace if the current user owns it. Members
+// get ErrForbidden and everyone else ErrNotFound.
+func ownedWorkspace(ctx context.Context, folders ports.FolderRepository, workspaceID string) (domain.Workspace, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	workspace, err := workspaceAccess(ctx, folders, workspaceID)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	if workspace.OwnerID == "" || workspace.OwnerID != user.ID {
+		return domain.Workspace{}, domain.ErrForbidden
+	}
+	return workspace, nil
+}
+
+// folderAccess returns the folder if the current user can use its workspace.
+func folderAccess(ctx context.Context, folders ports.FolderRepository, folderID string) (domain.Folder, error) {
+	folder, err := folders.GetFolder(ctx, folderID)
+	if err != nil {
+		return domain.Folder{}, err
+	}
+	if _, err := workspaceAccess(ctx, folders, folder.WorkspaceID); err != nil {
+		return domain.Folder{}, err
+	}
+	return folder, nil
+}
@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type GroupService struct {
+	groups   ports.GroupRepository
+	users    ports.UserRepository
+	acl      ports.ACLRepository
+	realtime ports.Realtime
+	validate *validator.Validate
+}
+
+type CreateGroupInput struct {
+	Name string `validate:"required,max=80"`
+}
+
+// AddGroupMemberInput names the new member by user ID or email.
+type AddGroupMemberInput struct {
+	GroupID string `validate:"required,uuid4"`
+	UserID  string `validate:"omitempty,uuid4"`
+	Email   string `validate:"omitempty,email,max=254"`
+}
+
+type RemoveGroupMemberInput struct {
+	GroupID string `validate:"required,uuid4"`
+	UserID  string `validate:"required,uuid4"`
+}
+
+// NewGroupService creates the service. realtime may be nil, in which case
+// removed members keep their open connections.
+func NewGroupService(groups ports.GroupRepository, users ports.UserRepository, acl ports.ACLRepository, realtime ports.Realtime, validate *validator.Validate) *GroupService {
+	return &GroupService{groups: groups, users: users, acl: acl, realtime: realtime, validate: validate}
+}
+
+// Create makes a group owned by the current user, who is its first member.
+func (s *GroupService) Create(ctx context.Context, input CreateGroupInput) (domain.Group, error) {
+	owner, err := currentUser(ctx)
+	if err != nil {
+		return domain.Group{}, err
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Group{}, domain.ErrInvalidInput
+	}
+	return s.groups.Create(ctx, domain.Group{
+		ID:        uuid.New().String(),
+		Name:      input.Name,
+		OwnerID:   owner.ID,
+		CreatedAt: utils.NowUTC(),
+	})
+}
+
+// List returns the groups the current user belongs to.
+func (s *GroupService) List(ctx context.Context) ([]domain.Group, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.groups.ListForUser(ctx, user.ID)
+}
+
+// Get returns a group and its members. Only members can see a group.
+func (s *GroupService) Get(ctx context.Context, id string) (domain.Group, []domain.User, error) {
+	if err := s.validate.Var(id, "required,uuid4"); err != nil {
+		return domain.Group{}, nil, domain.ErrInvalidInput
+	}
+	user, err := currentUser(ctx)
+	if err != nil {
+		return domain.Group{}, nil, err
+	}
+	group, err := s.groups.GetByID(ctx, id)
+	if err != nil {
+		return domain.Group{}, nil, err
+	}
+	members, err := s.groups.ListMembers(ctx, id)
+	if err != nil {
+		return domain.Group{}, nil, err
+	}
+	if !isMember(members, user.ID) {
+		return domain.Group{}, nil, domain.ErrNotFound
+	}
+	return group, members, nil
+}
+
+// AddMember adds a user to a group the current user owns.
+func (s *GroupService) AddMember(ctx context.Context, input AddGroupMemberInput) error {
+	input.Email = strings.TrimSpace(input.Email)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.ErrInvalidInput
+	}
+	if (input.UserID == "") == (input.Email == "") {
+		return domain.ErrInvalidInput
+	}
+	if _, err := s.ownedGroup(ctx, input.GroupID); err != nil {
+		return err
+	}
+	var (
+		user domain.User
+		err  error
+	)
+	if input.Email != "" {
+		user, err = s.users.GetByEmail(ctx, input.Email)
+	} else {
+		user, err = s.users.GetByID(ctx, input.UserID)
+	}
+	if err != nil {
+		return err
+	}
+	return s.groups.AddMember(ctx, input.GroupID, user.ID)
+}
+
+// RemoveMember takes a user out of a group. The owner can remove anyone but
+// themselves; other members can only leave. Members of identity provider
+// groups are managed by the provider. The removed user is disconnected from
+// documents they could only reach through the group.
+func (s *GroupService) RemoveMember(ctx context.Context, input RemoveGroupMemberInput) error {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.ErrInvalidInput
+	}
+	user, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	group, err := s.groups.GetByID(ctx, input.GroupID)
+	if err != nil {
+		return err
+	}
+	if group.ExternalID != "" {
+		return domain.ErrForbidden
+	}
+	if input.UserID == group.OwnerID {
+		return domain.ErrConflict
+	}
+	if user.ID != group.OwnerID && user.ID != input.UserID {
+		return domain.ErrForbidden
+	}
+	return removeGroupMember(ctx, s.groups, s.acl, s.realtime, input.GroupID, input.UserID)
+}
+
+// removeGroupMember takes a user out of a group and disconnects them from
+// documents they could only reach through it.
+func removeGroupMember(ctx context.Context, groups ports.GroupRepository, acl ports.ACLRepository, realtime ports.Realtime, groupID string, userID string) error {
+	docIDs, err := acl.ListGroupDocIDs(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	before := make(map[string]map[string]domain.Role, len(docIDs))
+	for _, docID := range docIDs {
+		roles, err := userRoles(ctx, acl, docID, []string{userID})
+		if err != nil {
+			return err
+		}
+		before[docID] = roles
+	}
+	if err := groups.RemoveMember(ctx, groupID, userID); err != nil {
+		return err
+	}
+	for docID, roles := range before {
+		disconnectDemoted(ctx, acl, realtime, docID, roles)
+	}
+	return nil
+}
+
+// ownedGroup returns the group if the current user owns it. Non-members get
+// ErrNotFound and other members ErrForbidden.
+func (s *GroupService) ownedGroup(ctx context.Context, id string) (domain.Group, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return domain.Group{}, err
+	}
+	group, err := s.groups.GetByID(ctx, id)
+	if err != nil {
+		return domain.Group{}, err
+	}
+	if group.OwnerID == user.ID {
+		return group, nil
+	}
+	members, err := s.groups.ListMembers(ctx, id)
+	if err != nil {
+		return domain.Group{}, err
+	}
+	if isMember(members, user.ID) {
+		return domain.Group{}, domain.ErrForbidden
+	}
+	return domain.Group{}, domain.ErrNotFound
+}
+
+func isMember(members []domain.User, userID string) bool {
+	for _, m := range members {
+		if m.ID == userID {
+			return true
+		}
+	}
+	return false
+}
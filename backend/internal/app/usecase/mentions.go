@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxMentions bounds how many users a single comment can notify.
+const maxMentions = 20
+
+// mentionPattern matches "@handle" or "@name@example.com" at the start of the
+// text or after a character that cannot be part of an email, so addresses
+// written out in full are not read as mentions of their domain.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@+-])@([\w.%+-]+(?:@[\w-]+(?:\.[\w-]+)+)?)`)
+
+// parseMentions returns the distinct lowercase handles mentioned in text, in
+// order of appearance.
+func parseMentions(text string) []string {
+	seen := make(map[string]bool)
+	handles := make([]string, 0)
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		handle := strings.ToLower(strings.TrimRight(m[1], ".-"))
+		if handle == "" || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+		if len(handles) == maxMentions {
+			break
+		}
+	}
+	return handles
+}
+
+// mentionExcerpt shortens comment text for a notification.
+func mentionExcerpt(text string) string {
+	const limit = 140
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= limit {
+		return string(runes)
+	}
+	return string(runes[:limit-1]) + "…"
+}
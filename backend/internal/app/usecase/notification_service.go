@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// NotificationService keeps the users' inboxes: it turns @mentions in
+// comments into notifications and pushes unread counts to the users' own
+// realtime channels.
+type NotificationService struct {
+	repo     ports.NotificationRepository
+	users    ports.UserRepository
+	acl      ports.ACLRepository
+	realtime ports.UserRealtime
+	validate *validator.Validate
+}
+
+type ListNotificationsInput struct {
+	UnreadOnly bool
+	Cursor     string `validate:"max=512"`
+	Limit      int    `validate:"min=0,max=100"`
+}
+
+// notificationCursor is the decoded form of ListNotificationsInput.Cursor.
+type notificationCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+const defaultNotificationsLimit = 50
+
+// NewNotificationService creates the service. realtime may be nil, in which
+// case unread counts are only available over REST.
+func NewNotificationService(repo ports.NotificationRepository, users ports.UserRepository, acl ports.ACLRepository, realtime ports.UserRealtime, validate *validator.Validate) *NotificationService {
+	return &NotificationService{repo: repo, users: users, acl: acl, realtime: realtime, validate: validate}
+}
+
+// List returns a page of the current user's inbox, newest first, with their
+// unread count.
+func (s *NotificationService) List(ctx context.Context, input ListNotificationsInput) (domain.NotificationPage, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return domain.NotificationPage{}, err
+	}
+	if err := s.validate.Struct(input); err != nil {
+		return domain.NotificationPage{}, domain.ErrInvalidInput
+	}
+	query := domain.NotificationQuery{UserID: user.ID, UnreadOnly: input.UnreadOnly, Limit: input.Limit}
+	if query.Limit == 0 {
+		query.Limit = defaultNotificationsLimit
+	}
+	if input.Cursor != "" {
+		var cur notificationCursor
+		if err := decodeCursor(input.Cursor, &cur); err != nil || s.validate.Var(cur.ID, "required,uuid4") != nil {
+			return domain.NotificationPage{}, domain.ErrInvalidInput
+		}
+		query.After = &domain.NotificationCursor{CreatedAt: cur.CreatedAt, ID: cur.ID}
+	}
+
+	// Fetch one extra row to learn whether another page follows.
+	limit := query.Limit
+	query.Limit++
+	notifications, err := s.repo.List(ctx, query)
+	if err != nil {
+		return domain.NotificationPage{}, err
+	}
+	unread, err := s.repo.CountUnread(ctx, user.ID)
+	if err != nil {
+		return domain.NotificationPage{}, err
+	}
+
+	page := domain.NotificationPage{Notifications: notifications, Unread: unread}
+	if len(notifications) > limit {
+		page.Notifications = notifications[:limit]
+		last := page.Notifications[limit-1]
+		page.NextCursor = encodeCursor(notificationCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return page, nil
+}
+
+// UnreadCount returns the current user's number of unread notifications.
+func (s *NotificationService) UnreadCount(ctx context.Context) (int, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.CountUnread(ctx, user.ID)
+}
+
+// MarkRead marks one of the current user's notifications read.
+func (s *NotificationService) MarkRead(ctx context.Context, id string) (domain.Notification, error) {
+	if err := s.validate.Var(id, "required,uuid4"); err != nil {
+		return domain.Notification{}, domain.ErrInvalidInput
+	}
+	user, err := currentUser(ctx)
+	if err != nil {
+		return domain.Notification{}, err
+	}
+	out, err := s.repo.MarkRead(ctx, user.ID, id)
+	if err != nil {
+		return domain.Notification{}, err
+	}
+	s.pushUnread(ctx, user.ID, nil)
+	return out, nil
+}
+
+// MarkAllRead marks the current user's whole inbox read.
+func (s *NotificationService) MarkAllRead(ctx context.Context) error {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := s.repo.MarkAllRead(ctx, user.ID); err != nil {
+		return err
+	}
+	s.pushUnread(ctx, user.ID, nil)
+	return nil
+}
+
+// notifyMentions notifies the users mentioned in a comment. Handles are a
+// full email or the part of it before the @, which must then name a single
+// user. Users who cannot view the document and the comment's author are
+// skipped, and nobody is notified twice about the same comment, so edits
+// only reach newly mentioned users.
+//
+// Mentions are best effort: the comment is already saved, so failures are
+// not reported to its author.
+func (s *NotificationService) notifyMentions(ctx context.Context, comment domain.Comment) {
+	if comment.DeletedAt != nil {
+		return
+	}
+	handles := parseMentions(comment.Text)
+	if len(handles) == 0 {
+		return
+	}
+	candidates, err := s.users.ListByMentions(ctx, handles)
+	if err != nil {
+		return
+	}
+
+	notified := make(map[string]bool)
+	for _, handle := range handles {
+		user, ok := mentionedUser(handle, candidates)
+		if !ok || notified[user.ID] {
+			continue
+		}
+		notified[user.ID] = true
+		if comment.AuthorID != nil && *comment.AuthorID == user.ID {
+			continue
+		}
+		role, err := s.acl.Role(ctx, comment.DocID, user.ID)
+		if err != nil || !role.Includes(domain.RoleViewer) {
+			continue
+		}
+
+		n, err := s.repo.Create(ctx, domain.Notification{
+			ID:        uuid.New().String(),
+			UserID:    user.ID,
+			Type:      domain.NotificationMention,
+			DocID:     comment.DocID,
+			CommentID: comment.ID,
+			ActorID:   comment.AuthorID,
+			ActorName: comment.AuthorName,
+			Excerpt:   mentionExcerpt(comment.Text),
+			CreatedAt: utils.NowUTC(),
+		})
+		if err != nil {
+			continue
+		}
+		s.pushUnread(ctx, user.ID, &n)
+	}
+}
+
+// mentionedUser picks the user a handle refers to among the candidates.
+func mentionedUser(handle string, candidates []domain.User) (domain.User, bool) {
+	var match domain.User
+	found := 0
+	for _, u := range candidates {
+		email := strings.ToLower(u.Email)
+		if strings.Contains(handle, "@") {
+			if email == handle {
+				return u, true
+			}
+			continue
+		}
+		if local, _, _ := strings.Cut(email, "@"); local == handle {
+			match = u
+			found++
+		}
+	}
+	return match, found == 1
+}
+
+// pushUnread sends the user's unread count to their channel, with the
+// notification that changed it when there is a new one.
+func (s *NotificationService) pushUnread(ctx context.Context, userID string, n *domain.Notification) {
+	if s.realtime == nil {
+		return
+	}
+	unread, err := s.repo.CountUnread(ctx, userID)
+	if err != nil {
+		return
+	}
+	s.realtime.SendToUser(userID, NewUnreadEvent(unread, n))
+}
@@ -0,0 +1,229 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// maxNameLength matches the limit on names of registered users.
+const maxNameLength = 40
+
+type OIDCService struct {
+	provider ports.IdentityProvider
+	users    ports.UserRepository
+	groups   ports.GroupRepository
+	acl      ports.ACLRepository
+	realtime ports.Realtime
+	auth     *AuthService
+}
+
+// OIDCLogin is a sign-in in progress. State, Nonce, Verifier and LinkUserID
+// must be kept by the browser until the provider redirects back.
+type OIDCLogin struct {
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string
+	// LinkUserID is set when a signed-in user started the flow, which then
+	// links the provider account to them instead of signing in.
+	LinkUserID string
+}
+
+// OIDCCallbackInput carries the provider's redirect and what was kept from
+// the OIDCLogin it answers.
+type OIDCCallbackInput struct {
+	Code  string
+	State string
+	Error string
+	Login OIDCLogin
+}
+
+// NewOIDCService creates the service. realtime may be nil, in which case
+// users dropped from a provider group keep their open connections.
+func NewOIDCService(provider ports.IdentityProvider, users ports.UserRepository, groups ports.GroupRepository, acl ports.ACLRepository, realtime ports.Realtime, auth *AuthService) *OIDCService {
+	return &OIDCService{provider: provider, users: users, groups: groups, acl: acl, realtime: realtime, auth: auth}
+}
+
+// Begin starts a sign-in and returns where to send the browser. Started by a
+// signed-in user, it links the provider account to them.
+func (s *OIDCService) Begin(ctx context.Context) (OIDCLogin, error) {
+	var (
+		login OIDCLogin
+		err   error
+	)
+	if user, ok := domain.UserFromContext(ctx); ok {
+		if _, key := domain.APIKeyFromContext(ctx); !key {
+			login.LinkUserID = user.ID
+		}
+	}
+	for _, v := range []*string{&login.State, &login.Nonce, &login.Verifier} {
+		if *v, err = newToken(); err != nil {
+			return OIDCLogin{}, err
+		}
+	}
+	sum := sha256.Sum256([]byte(login.Verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if login.URL, err = s.provider.AuthCodeURL(ctx, login.State, login.Nonce, challenge); err != nil {
+		return OIDCLogin{}, err
+	}
+	return login, nil
+}
+
+// Complete finishes a sign-in: it redeems the code, finds, creates or links
+// the local user, syncs their provider groups and opens a session. Failures
+// the user can cause wrap ErrUnauthorized; an email that belongs to another
+// account returns ErrConflict.
+func (s *OIDCService) Complete(ctx context.Context, input OIDCCallbackInput) (LoginResult, error) {
+	if input.Error != "" {
+		return LoginResult{}, fmt.Errorf("%w: provider returned %s", domain.ErrUnauthorized, input.Error)
+	}
+	if input.Code == "" || input.Login.State == "" || subtle.ConstantTimeCompare([]byte(input.State), []byte(input.Login.State)) != 1 {
+		return LoginResult{}, fmt.Errorf("%w: state mismatch", domain.ErrUnauthorized)
+	}
+	claims, err := s.provider.Exchange(ctx, input.Code, input.Login.Verifier, input.Login.Nonce)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	var user domain.User
+	if input.Login.LinkUserID != "" {
+		user, err = s.linkUser(ctx, input.Login.LinkUserID, claims)
+	} else {
+		user, err = s.resolveUser(ctx, claims)
+	}
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if err := s.syncGroups(ctx, user.ID, claims.Groups); err != nil {
+		return LoginResult{}, err
+	}
+	return s.auth.StartSession(ctx, user)
+}
+
+// resolveUser returns the user linked to the provider account, or creates a
+// user without a password on first sign-in. Local emails are never verified,
+// so a provider account is not linked to an existing user by email: whoever
+// registered the address first could keep signing in with their password.
+// That returns ErrConflict; the owner links the account while signed in.
+func (s *OIDCService) resolveUser(ctx context.Context, claims domain.IdentityClaims) (domain.User, error) {
+	name := profileName(claims)
+	user, err := s.users.GetByIdentity(ctx, claims.Issuer, claims.Subject)
+	switch {
+	case err == nil:
+		if user.Name != name {
+			return s.users.UpdateName(ctx, user.ID, name)
+		}
+		return user, nil
+	case err != domain.ErrNotFound:
+		return domain.User{}, err
+	}
+
+	if claims.Email == "" {
+		return domain.User{}, fmt.Errorf("%w: no email claim", domain.ErrUnauthorized)
+	}
+	_, err = s.users.GetByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		return domain.User{}, domain.ErrConflict
+	case err != domain.ErrNotFound:
+		return domain.User{}, err
+	}
+
+	now := utils.NowUTC()
+	return s.users.CreateWithIdentity(ctx, domain.User{
+		ID:        uuid.New().String(),
+		Email:     claims.Email,
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, claims.Issuer, claims.Subject)
+}
+
+// linkUser links the provider account to the signed-in user who started the
+// flow. Anyone else completing it is refused, and an account already linked
+// to another user returns ErrConflict.
+func (s *OIDCService) linkUser(ctx context.Context, userID string, claims domain.IdentityClaims) (domain.User, error) {
+	user, err := currentUser(ctx)
+	if err != nil || user.ID != userID {
+		return domain.User{}, fmt.Errorf("%w: link started by another user", domain.ErrUnauthorized)
+	}
+	linked, err := s.users.GetByIdentity(ctx, claims.Issuer, claims.Subject)
+	switch {
+	case err == nil && linked.ID == user.ID:
+		return linked, nil
+	case err == nil:
+		return domain.User{}, domain.ErrConflict
+	case err != domain.ErrNotFound:
+		return domain.User{}, err
+	}
+	if err := s.users.LinkIdentity(ctx, user.ID, claims.Issuer, claims.Subject); err != nil {
+		return domain.User{}, err
+	}
+	return s.users.GetByID(ctx, user.ID)
+}
+
+// syncGroups makes the user's provider group memberships match groups,
+// creating groups seen for the first time. Users dropped from a group are
+// disconnected from documents they could only reach through it.
+func (s *OIDCService) syncGroups(ctx context.Context, userID string, groups []string) error {
+	if groups == nil {
+		return nil
+	}
+	current, err := s.groups.ListForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	want := make(map[string]bool, len(groups))
+	for _, name := range groups {
+		name = strings.TrimSpace(name)
+		if name == "" || len(name) > 80 || want[name] {
+			continue
+		}
+		want[name] = true
+		group, err := s.groups.UpsertExternal(ctx, domain.Group{
+			ID:         uuid.New().String(),
+			Name:       name,
+			ExternalID: name,
+			CreatedAt:  utils.NowUTC(),
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.groups.AddMember(ctx, group.ID, userID); err != nil {
+			return err
+		}
+	}
+
+	for _, group := range current {
+		if group.ExternalID == "" || want[group.ExternalID] {
+			continue
+		}
+		if err := removeGroupMember(ctx, s.groups, s.acl, s.realtime, group.ID, userID); err != nil && err != domain.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// profileName picks a display name, falling back to the email's local part.
+func profileName(claims domain.IdentityClaims) string {
+	name := claims.Name
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if name == "" {
+		name = claims.Subject
+	}
+	if r := []rune(name); len(r) > maxNameLength {
+		name = string(r[:maxNameLength])
+	}
+	return name
+}
@@ -0,0 +1,381 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/internal/infrastructure/oidc"
+	"collabdocs/internal/infrastructure/oidc/oidctest"
+	"github.com/go-playground/validator/v10"
+)
+
+type memUsers struct {
+	byID       map[string]domain.User
+	identities map[[2]string]string
+}
+
+func newMemUsers() *memUsers {
+	return &memUsers{byID: make(map[string]domain.User), identities: make(map[[2]string]string)}
+}
+
+func (m *memUsers) Create(ctx context.Context, user domain.User) (domain.User, error) {
+	if _, err := m.GetByEmail(ctx, user.Email); err == nil {
+		return domain.User{}, domain.ErrConflict
+	}
+	m.byID[user.ID] = user
+	return user, nil
+}
+
+func (m *memUsers) GetByID(ctx context.Context, id string) (domain.User, error) {
+	user, ok := m.byID[id]
+	if !ok {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return user, nil
+}
+
+func (m *memUsers) GetByEmail(ctx context.Context, email string) (domain.User, error) {
+	for _, user := range m.byID {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return domain.User{}, domain.ErrNotFound
+}
+
+func (m *memUsers) GetByIdentity(ctx context.Context, issuer string, subject string) (domain.User, error) {
+	id, ok := m.identities[[2]string{issuer, subject}]
+	if !ok {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return m.GetByID(ctx, id)
+}
+
+func (m *memUsers) CreateWithIdentity(ctx context.Context, user domain.User, issuer string, subject string) (domain.User, error) {
+	user, err := m.Create(ctx, user)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return user, m.LinkIdentity(ctx, user.ID, issuer, subject)
+}
+
+func (m *memUsers) LinkIdentity(ctx context.Context, userID string, issuer string, subject string) error {
+	key := [2]string{issuer, subject}
+	if _, ok := m.identities[key]; ok {
+		return domain.ErrConflict
+	}
+	m.identities[key] = userID
+	return nil
+}
+
+func (m *memUsers) UpdateName(ctx context.Context, id string, name string) (domain.User, error) {
+	user, err := m.GetByID(ctx, id)
+	if err != nil {
+		return domain.User{}, err
+	}
+	user.Name = name
+	m.byID[id] = user
+	return user, nil
+}
+
+func (m *memUsers) ListByMentions(ctx context.Context, handles []string) ([]domain.User, error) {
+	return nil, nil
+}
+
+type memSessions struct {
+	users    ports.UserRepository
+	sessions map[string]domain.Session
+}
+
+func (m *memSessions) Create(ctx context.Context, session domain.Session) error {
+	m.sessions[session.TokenHash] = session
+	return nil
+}
+
+func (m *memSessions) GetUser(ctx context.Context, tokenHash string) (domain.User, error) {
+	session, ok := m.sessions[tokenHash]
+	if !ok {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return m.users.GetByID(ctx, session.UserID)
+}
+
+func (m *memSessions) Delete(ctx context.Context, tokenHash string) error {
+	delete(m.sessions, tokenHash)
+	return nil
+}
+
+func (m *memSessions) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// memGroups implements the group methods sign-in uses.
+type memGroups struct {
+	ports.GroupRepository
+	groups  map[string]domain.Group
+	members map[string]map[string]bool
+}
+
+func (m *memGroups) UpsertExternal(ctx context.Context, group domain.Group) (domain.Group, error) {
+	for _, g := range m.groups {
+		if g.ExternalID == group.ExternalID {
+			return g, nil
+		}
+	}
+	m.groups[group.ID] = group
+	return group, nil
+}
+
+func (m *memGroups) ListForUser(ctx context.Context, userID string) ([]domain.Group, error) {
+	var out []domain.Group
+	for id, members := range m.members {
+		if members[userID] {
+			out = append(out, m.groups[id])
+		}
+	}
+	return out, nil
+}
+
+func (m *memGroups) AddMember(ctx context.Context, groupID string, userID string) error {
+	if m.members[groupID] == nil {
+		m.members[groupID] = make(map[string]bool)
+	}
+	m.members[groupID][userID] = true
+	return nil
+}
+
+func (m *memGroups) RemoveMember(ctx context.Context, groupID string, userID string) error {
+	if !m.members[groupID][userID] {
+		return domain.ErrNotFound
+	}
+	delete(m.members[groupID], userID)
+	return nil
+}
+
+// groupNames lists the external groups userID belongs to.
+func (m *memGroups) groupNames(userID string) []string {
+	groups, _ := m.ListForUser(context.Background(), userID)
+	names := make([]string, 0, len(groups))
+	for _, g := range groups {
+		names = append(names, g.ExternalID)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// memACL grants nothing through groups.
+type memACL struct {
+	ports.ACLRepository
+}
+
+func (memACL) ListGroupDocIDs(ctx context.Context, groupID string) ([]string, error) {
+	return nil, nil
+}
+
+type oidcFixture struct {
+	service *OIDCService
+	idp     *oidctest.Server
+	users   *memUsers
+	groups  *memGroups
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	t.Helper()
+	idp := oidctest.NewServer(t, "collabdocs")
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:      idp.URL,
+		ClientID:    "collabdocs",
+		RedirectURL: "http://app.test/auth/oidc/callback",
+		Scopes:      []string{"openid", "email", "profile"},
+		GroupsClaim: "groups",
+		HTTPClient:  idp.Client(),
+	})
+	users := newMemUsers()
+	sessions := &memSessions{users: users, sessions: make(map[string]domain.Session)}
+	groups := &memGroups{groups: make(map[string]domain.Group), members: make(map[string]map[string]bool)}
+	auth := NewAuthService(users, sessions, validator.New(), time.Hour, nil)
+	return &oidcFixture{
+		service: NewOIDCService(provider, users, groups, memACL{}, nil, auth),
+		idp:     idp,
+		users:   users,
+		groups:  groups,
+	}
+}
+
+// signIn runs a sign-in in which the provider asserts claims. change may
+// alter the login kept by the browser before the callback.
+func (f *oidcFixture) signIn(t *testing.T, claims func(nonce string) map[string]any, change func(*OIDCCallbackInput)) (LoginResult, error) {
+	t.Helper()
+	return f.signInAs(t, context.Background(), context.Background(), claims, change)
+}
+
+// signInAs runs a sign-in started with beginCtx and completed with
+// completeCtx, which carry who is signed in at each step.
+func (f *oidcFixture) signInAs(t *testing.T, beginCtx context.Context, completeCtx context.Context, claims func(nonce string) map[string]any, change func(*OIDCCallbackInput)) (LoginResult, error) {
+	t.Helper()
+	login, err := f.service.Begin(beginCtx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code := f.idp.Authorize(login.URL, f.idp.IDToken(claims(login.Nonce)))
+	input := OIDCCallbackInput{Code: code, State: login.State, Login: login}
+	if change != nil {
+		change(&input)
+	}
+	return f.service.Complete(completeCtx, input)
+}
+
+func (f *oidcFixture) claims(subject string, email string, verified bool, groups ...string) func(string) map[string]any {
+	return func(nonce string) map[string]any {
+		c := f.idp.Claims(subject, nonce)
+		c["email"] = email
+		c["email_verified"] = verified
+		c["name"] = "Alice Example"
+		if groups != nil {
+			c["groups"] = groups
+		}
+		return c
+	}
+}
+
+func TestOIDCCompleteCreatesUser(t *testing.T) {
+	f := newOIDCFixture(t)
+	first, err := f.signIn(t, f.claims("alice-sub", "alice@example.com", false, "eng", "docs"), nil)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if first.Token == "" {
+		t.Error("no session token")
+	}
+	if first.User.Email != "alice@example.com" || first.User.Name != "Alice Example" || first.User.PasswordHash != "" {
+		t.Errorf("user = %+v", first.User)
+	}
+	if got := f.groups.groupNames(first.User.ID); len(got) != 2 || got[0] != "docs" || got[1] != "eng" {
+		t.Errorf("groups = %v, want [docs eng]", got)
+	}
+
+	// Signing in again finds the same user and follows the provider's groups.
+	second, err := f.signIn(t, f.claims("alice-sub", "alice@example.com", false, "eng"), nil)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if second.User.ID != first.User.ID {
+		t.Errorf("second sign-in created user %s, want %s", second.User.ID, first.User.ID)
+	}
+	if len(f.users.byID) != 1 {
+		t.Errorf("%d users, want 1", len(f.users.byID))
+	}
+	if got := f.groups.groupNames(first.User.ID); len(got) != 1 || got[0] != "eng" {
+		t.Errorf("groups = %v, want [eng]", got)
+	}
+}
+
+func TestOIDCCompleteRefusesExistingEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     domain.User
+		verified bool
+	}{
+		{"registered with a password", domain.User{ID: "user-1", Email: "alice@example.com", Name: "alice", PasswordHash: "hash"}, true},
+		{"unverified by the provider", domain.User{ID: "user-1", Email: "alice@example.com", Name: "alice", PasswordHash: "hash"}, false},
+		{"created by another provider", domain.User{ID: "user-1", Email: "alice@example.com", Name: "alice"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOIDCFixture(t)
+			if _, err := f.users.Create(context.Background(), tt.user); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if _, err := f.signIn(t, f.claims("alice-sub", "alice@example.com", tt.verified), nil); !errors.Is(err, domain.ErrConflict) {
+				t.Fatalf("err = %v, want ErrConflict", err)
+			}
+			if len(f.users.identities) != 0 || len(f.users.byID) != 1 {
+				t.Errorf("%d identities linked and %d users, want 0 and 1", len(f.users.identities), len(f.users.byID))
+			}
+		})
+	}
+}
+
+func TestOIDCCompleteLinksSignedInUser(t *testing.T) {
+	f := newOIDCFixture(t)
+	existing, err := f.users.Create(context.Background(), domain.User{ID: "user-1", Email: "alice@example.com", Name: "alice", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	other, err := f.users.Create(context.Background(), domain.User{ID: "user-2", Email: "bob@example.com", Name: "bob"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	alice := domain.WithUser(context.Background(), existing)
+	bob := domain.WithUser(context.Background(), other)
+	claims := f.claims("alice-sub", "alice@example.com", false)
+
+	// The link is only completed by the user who started it.
+	for name, ctx := range map[string]context.Context{"anonymous": context.Background(), "another user": bob} {
+		if _, err := f.signInAs(t, alice, ctx, claims, nil); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Errorf("completed by %s: err = %v, want ErrUnauthorized", name, err)
+		}
+	}
+	if _, err := f.signInAs(t, alice, alice, claims, func(in *OIDCCallbackInput) { in.Login.LinkUserID = other.ID }); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("forged link target: err = %v, want ErrUnauthorized", err)
+	}
+	if len(f.users.identities) != 0 {
+		t.Fatal("identity linked by a refused attempt")
+	}
+
+	result, err := f.signInAs(t, alice, alice, claims, nil)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if result.User.ID != existing.ID {
+		t.Errorf("signed in as %s, want %s", result.User.ID, existing.ID)
+	}
+	if id := f.users.identities[[2]string{f.idp.URL, "alice-sub"}]; id != existing.ID {
+		t.Errorf("identity linked to %q, want %s", id, existing.ID)
+	}
+
+	// Linking is done once; later sign-ins find the user.
+	if result, err := f.signIn(t, claims, nil); err != nil || result.User.ID != existing.ID {
+		t.Errorf("sign-in after linking = %s, %v; want %s", result.User.ID, err, existing.ID)
+	}
+	if _, err := f.signInAs(t, bob, bob, claims, nil); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("linking an account linked to another user: err = %v, want ErrConflict", err)
+	}
+}
+
+func TestOIDCCompleteRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims func(f *oidcFixture) func(string) map[string]any
+		change func(*OIDCCallbackInput)
+	}{
+		{"state mismatch", nil, func(in *OIDCCallbackInput) { in.State = "forged" }},
+		{"no state kept", nil, func(in *OIDCCallbackInput) { in.Login.State = ""; in.State = "" }},
+		{"provider error", nil, func(in *OIDCCallbackInput) { in.Error = "access_denied" }},
+		{"PKCE verifier mismatch", nil, func(in *OIDCCallbackInput) { in.Login.Verifier = "forged" }},
+		{"nonce mismatch", nil, func(in *OIDCCallbackInput) { in.Login.Nonce = "forged" }},
+		{"no email", func(f *oidcFixture) func(string) map[string]any {
+			return f.claims("alice-sub", "", true)
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOIDCFixture(t)
+			claims := f.claims("alice-sub", "alice@example.com", true)
+			if tt.claims != nil {
+				claims = tt.claims(f)
+			}
+			if _, err := f.signIn(t, claims, tt.change); !errors.Is(err, domain.ErrUnauthorized) {
+				t.Fatalf("err = %v, want ErrUnauthorized", err)
+			}
+			if len(f.users.byID) != 0 {
+				t.Errorf("%d users created, want 0", len(f.users.byID))
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// propertyKeyPattern restricts keys to identifiers that are safe in query
+// parameters such as prop.<key>.gte.
+var propertyKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// propertyValueRules are the validator tags applied to property values after
+// their JSON type has been checked.
+var propertyValueRules = map[domain.PropertyType]string{
+	domain.PropertyString: "max=1000",
+	domain.PropertyDate:   "datetime=2006-01-02",
+	domain.PropertyEnum:   "required",
+	domain.PropertyUser:   "required,uuid4",
+}
+
+type PropertyService struct {
+	repo     ports.PropertyRepository
+	folders  ports.FolderRepository
+	validate *validator.Validate
+}
+
+type CreatePropertyInput struct {
+	WorkspaceID string   `validate:"required,uuid4"`
+	Key         string   `validate:"required"`
+	Name        string   `validate:"required,max=80"`
+	Type        string   `validate:"required,oneof=string number date enum user"`
+	Options     []string `validate:"max=50,dive,required,max=80"`
+}
+
+type UpdatePropertyInput struct {
+	WorkspaceID string   `validate:"required,uuid4"`
+	Key         string   `validate:"required"`
+	Name        string   `validate:"required,max=80"`
+	Options     []string `validate:"max=50,dive,required,max=80"`
+}
+
+type DeletePropertyInput struct {
+	WorkspaceID string `validate:"required,uuid4"`
+	Key         string `validate:"required"`
+}
+
+// PropertyFilterInput is a listing filter as given in a query string:
+// prop.<key>=<value> or prop.<key>.<op>=<value>.
+type PropertyFilterInput struct {
+	Key   string
+	Op    string `validate:"omitempty,oneof=eq gt gte lt lte"`
+	Value string `validate:"max=1000"`
+}
+
+func NewPropertyService(repo ports.PropertyRepository, folders ports.FolderRepository, validate *validator.Validate) *PropertyService {
+	return &PropertyService{repo: repo, folders: folders, validate: validate}
+}
+
+// Create adds a property to the schema of a workspace the current user
+// manages.
+func (s *PropertyService) Create(ctx context.Context, input CreatePropertyInput) (domain.PropertyDefinition, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if err := s.validate.Struct(input); err != nil || !propertyKeyPattern.MatchString(input.Key) {
+		return domain.PropertyDefinition{}, domain.ErrInvalidInput
+	}
+	if _, err := schemaWorkspace(ctx, s.folders, input.WorkspaceID); err != nil {
+		return domain.PropertyDefinition{}, err
+	}
+	options, ok := propertyOptions(domain.PropertyType(input.Type), input.Options)
+	if !ok {
+		return domain.PropertyDefinition{}, domain.ErrInvalidInput
+	}
+
+	now := utils.NowUTC()
+	return s.repo.CreateDefinition(ctx, domain.PropertyDefinition{
+		ID:          uuid.New().String(),
+		WorkspaceID: input.WorkspaceID,
+		Key:         input.Key,
+		Name:        input.Name,
+		Type:        domain.PropertyType(input.Type),
+		Options:     options,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+}
+
+func (s *PropertyService) List(ctx context.Context, workspaceID string) ([]domain.PropertyDefinition, error) {
+	if err := s.validate.Var(workspaceID, "required,uuid4"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if _, err := workspaceAccess(ctx, s.folders, workspaceID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDefinitions(ctx, workspaceID)
+}
+
+// Update renames a property or replaces its enum options. The type of a
+// property cannot change; existing values outside new options are kept.
+func (s *PropertyService) Update(ctx context.Context, input UpdatePropertyInput) (domain.PropertyDefinition, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.PropertyDefinition{}, domain.ErrInvalidInput
+	}
+	if _, err := schemaWorkspace(ctx, s.folders, input.WorkspaceID); err != nil {
+		return domain.PropertyDefinition{}, err
+	}
+	defs, err := s.repo.ListDefinitions(ctx, input.WorkspaceID)
+	if err != nil {
+		return domain.PropertyDefinition{}, err
+	}
+	i := slices.IndexFunc(defs, func(d domain.PropertyDefinition) bool { return d.Key == input.Key })
+	if i < 0 {
+		return domain.PropertyDefinition{}, domain.ErrNotFound
+	}
+	options, ok := propertyOptions(defs[i].Type, input.Options)
+	if !ok {
+		return domain.PropertyDefinition{}, domain.ErrInvalidInput
+	}
+	return s.repo.UpdateDefinition(ctx, input.WorkspaceID, input.Key, input.Name, options)
+}
+
+// Delete removes a property from the workspace along with its values.
+func (s *PropertyService) Delete(ctx context.Context, input DeletePropertyInput) error {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.ErrInvalidInput
+	}
+	if _, err := schemaWorkspace(ctx, s.folders, input.WorkspaceID); err != nil {
+		return err
+	}
+	return s.repo.DeleteDefinition(ctx, input.WorkspaceID, input.Key)
+}
+
+// schemaWorkspace returns the workspace if the current user may change its
+// property schema: its owner, or an administrator for the default workspace,
+// which has no owner. Other members get ErrForbidden.
+func schemaWorkspace(ctx context.Context, folders ports.FolderRepository, workspaceID string) (domain.Workspace, error) {
+	workspace, err := workspaceAccess(ctx, folders, workspaceID)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	if workspace.IsDefault {
+		if _, err := currentAdmin(ctx); err != nil {
+			return domain.Workspace{}, err
+		}
+		return workspace, nil
+	}
+	return ownedWorkspace(ctx, folders, workspaceID)
+}
+
+// propertyOptions trims and de-duplicates enum options. Only enums take
+// options and they need at least one.
+func propertyOptions(typ domain.PropertyType, options []string) ([]string, bool) {
+	if typ != domain.PropertyEnum {
+		return []string{}, len(options) == 0
+	}
+	out := make([]string, 0, len(options))
+	for _, o := range options {
+		o = strings.TrimSpace(o)
+		if o != "" && !slices.Contains(out, o) {
+			out = append(out, o)
+		}
+	}
+	return out, len(out) > 0
+}
+
+// validateProperties checks values against the workspace schema and splits
+// them into values to store and keys to clear (null values). User values must
+// name existing users.
+func validateProperties(ctx context.Context, validate *validator.Validate, users ports.UserRepository, defs []domain.PropertyDefinition, values map[string]any) (map[string]any, []string, error) {
+	set := make(map[string]any, len(values))
+	var unset []string
+	for key, value := range values {
+		i := slices.IndexFunc(defs, func(d domain.PropertyDefinition) bool { return d.Key == key })
+		if i < 0 {
+			return nil, nil, domain.ErrInvalidInput
+		}
+		if value == nil {
+			unset = append(unset, key)
+			continue
+		}
+		def := defs[i]
+		if def.Type == domain.PropertyNumber {
+			n, ok := value.(float64)
+			if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+				return nil, nil, domain.ErrInvalidInput
+			}
+			set[key] = n
+			continue
+		}
+		str, ok := value.(string)
+		if !ok {
+			return nil, nil, domain.ErrInvalidInput
+		}
+		str = strings.TrimSpace(str)
+		if err := validate.Var(str, propertyValueRules[def.Type]); err != nil {
+			return nil, nil, domain.ErrInvalidInput
+		}
+		if def.Type == domain.PropertyEnum && !slices.Contains(def.Options, str) {
+			return nil, nil, domain.ErrInvalidInput
+		}
+		if def.Type == domain.PropertyUser {
+			_, err := users.GetByID(ctx, str)
+			if err == domain.ErrNotFound {
+				return nil, nil, domain.ErrInvalidInput
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		set[key] = str
+	}
+	return set, unset, nil
+}
+
+// propertyFilters converts query-string filters. Values that parse as numbers
+// also match numeric properties.
+func propertyFilters(validate *validator.Validate, inputs []PropertyFilterInput) ([]domain.PropertyFilter, error) {
+	filters := make([]domain.PropertyFilter, 0, len(inputs))
+	for _, in := range inputs {
+		if err := validate.Struct(in); err != nil || !propertyKeyPattern.MatchString(in.Key) {
+			return nil, domain.ErrInvalidInput
+		}
+		f := domain.PropertyFilter{Key: in.Key, Op: domain.PropertyOp(in.Op), Text: in.Value}
+		if f.Op == "" {
+			f.Op = domain.PropertyEq
+		}
+		if n, err := strconv.ParseFloat(in.Value, 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+			f.Number = &n
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
@@ -0,0 +1,37 @@
+package usecase
+
+import "unicode/utf8"
+
+// maxEmojiRunes allows the longest common sequences: families joined with
+// zero width joiners and flags written with tag characters.
+const maxEmojiRunes = 16
+
+// validEmoji reports whether s is a single emoji sequence: pictographs
+// optionally combined with skin tones, zero width joiners, variation
+// selectors and tags, or a keycap.
+func validEmoji(s string) bool {
+	if s == "" || !utf8.ValidString(s) || utf8.RuneCountInString(s) > maxEmojiRunes {
+		return false
+	}
+	pictographs := 0
+	keycap := false
+	for _, r := range s {
+		switch {
+		case r == 0x200D, r == 0xFE0F, r >= 0xE0020 && r <= 0xE007F:
+			// Joiners, variation selector 16 and tags combine pictographs.
+		case r == 0x20E3:
+			keycap = true
+		case r >= 0x1F000 && r <= 0x1FAFF, r >= 0x2100 && r <= 0x2BFF, r == 0x00A9, r == 0x00AE, r == 0x3030, r == 0x303D:
+			pictographs++
+		case r >= '0' && r <= '9', r == '#', r == '*':
+			// Keycap bases, checked below.
+		default:
+			return false
+		}
+	}
+	if keycap {
+		first, _ := utf8.DecodeRuneInString(s)
+		return pictographs == 0 && (first >= '0' && first <= '9' || first == '#' || first == '*')
+	}
+	return pictographs > 0
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"github.com/go-playground/validator/v10"
+)
+
+const defaultSearchLimit = 20
+
+type SearchService struct {
+	repo     ports.SearchRepository
+	validate *validator.Validate
+}
+
+type SearchDocumentsInput struct {
+	Query         string `validate:"required,max=200"`
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Tags          []string              `validate:"max=20,dive,required,max=40"`
+	TagMode       string                `validate:"omitempty,oneof=any all"`
+	Properties    []PropertyFilterInput `validate:"max=20"`
+	Limit         int                   `validate:"min=0,max=100"`
+	Offset        int                   `validate:"min=0"`
+}
+
+func NewSearchService(repo ports.SearchRepository, validate *validator.Validate) *SearchService {
+	return &SearchService{repo: repo, validate: validate}
+}
+
+// Search only matches documents the current user can view.
+func (s *SearchService) Search(ctx context.Context, input SearchDocumentsInput) (domain.SearchPage, error) {
+	viewer, err := currentUser(ctx)
+	if err != nil {
+		return domain.SearchPage{}, err
+	}
+	ids, err := keyDocIDs(ctx, domain.ScopeDocsRead)
+	if err != nil {
+		return domain.SearchPage{}, err
+	}
+	input.Query = strings.TrimSpace(input.Query)
+	for i, tag := range input.Tags {
+		input.Tags[i] = normalizeTag(tag)
+	}
+	if err := s.validate.Struct(input); err != nil {
+		return domain.SearchPage{}, domain.ErrInvalidInput
+	}
+	if input.UpdatedAfter != nil && input.UpdatedBefore != nil && !input.UpdatedAfter.Before(*input.UpdatedBefore) {
+		return domain.SearchPage{}, domain.ErrInvalidInput
+	}
+	properties, err := propertyFilters(s.validate, input.Properties)
+	if err != nil {
+		return domain.SearchPage{}, err
+	}
+	if input.Limit == 0 {
+		input.Limit = defaultSearchLimit
+	}
+
+	results, total, err := s.repo.Search(ctx, domain.SearchQuery{
+		ViewerID:      viewer.ID,
+		IDs:           ids,
+		Query:         input.Query,
+		UpdatedAfter:  input.UpdatedAfter,
+		UpdatedBefore: input.UpdatedBefore,
+		Tags:          input.Tags,
+		AllTags:       input.TagMode == "all",
+		Properties:    properties,
+		Limit:         input.Limit,
+		Offset:        input.Offset,
+	})
+	if err != nil {
+		return domain.SearchPage{}, err
+	}
+	return domain.SearchPage{Results: results, Total: total, Limit: input.Limit, Offset: input.Offset}, nil
+}
@@ -0,0 +1,351 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const defaultShareAccessLimit = 100
+
+// ShareLinkService manages share links. Opening a link checks its password,
+// expiry and usage limit once and issues a short-lived signed share session
+// that is sent with later requests; every open and every request made with a
+// session is written to the link's access log.
+type ShareLinkService struct {
+	links      ports.ShareLinkRepository
+	acl        ports.ACLRepository
+	realtime   ports.Realtime
+	validate   *validator.Validate
+	secret     []byte
+	sessionTTL time.Duration
+}
+
+type CreateShareLinkInput struct {
+	DocID     string `validate:"required,uuid4"`
+	Role      string `validate:"required,oneof=editor commenter viewer"`
+	Password  string `validate:"max=72"`
+	ExpiresAt *time.Time
+	MaxUses   *int `validate:"omitempty,min=1"`
+}
+
+type RevokeShareLinkInput struct {
+	DocID  string `validate:"required,uuid4"`
+	LinkID string `validate:"required,uuid4"`
+}
+
+type ListShareLinkAccessInput struct {
+	DocID  string `validate:"required,uuid4"`
+	LinkID string `validate:"required,uuid4"`
+	Limit  int    `validate:"min=0,max=500"`
+}
+
+// OpenShareLinkInput redeems a link token. IP and UserAgent are recorded in
+// the access log.
+type OpenShareLinkInput struct {
+	Token     string `validate:"required,max=256"`
+	Password  string `validate:"max=72"`
+	IP        string
+	UserAgent string
+}
+
+// ShareRequest describes a request made with a share session, for the
+// access log.
+type ShareRequest struct {
+	Action    string
+	IP        string
+	UserAgent string
+}
+
+// ShareSession is issued when a link is opened. Its token grants the link's
+// role on the document until ExpiresAt or until the link is revoked.
+type ShareSession struct {
+	DocID     string      `json:"docId"`
+	Role      domain.Role `json:"role"`
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expiresAt"`
+}
+
+// shareClaims is the signed payload of a share session token.
+type shareClaims struct {
+	LinkID  string      `json:"l"`
+	DocID   string      `json:"d"`
+	Role    domain.Role `json:"r"`
+	Expires int64       `json:"e"`
+}
+
+// NewShareLinkService creates the service. secret signs link and session
+// tokens. realtime may be nil, in which case revoking a link does not close
+// the connections made through it.
+func NewShareLinkService(links ports.ShareLinkRepository, acl ports.ACLRepository, realtime ports.Realtime, validate *validator.Validate, secret string, sessionTTL time.Duration) *ShareLinkService {
+	return &ShareLinkService{links: links, acl: acl, realtime: realtime, validate: validate, secret: []byte(secret), sessionTTL: sessionTTL}
+}
+
+// Create adds a share link to a document. Only owners can share by link.
+func (s *ShareLinkService) Create(ctx context.Context, input CreateShareLinkInput) (domain.ShareLink, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.ShareLink{}, domain.ErrInvalidInput
+	}
+	now := utils.NowUTC()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return domain.ShareLink{}, domain.ErrInvalidInput
+	}
+	owner, err := currentUser(ctx)
+	if err != nil {
+		return domain.ShareLink{}, err
+	}
+	if _, err := authorize(ctx, s.acl, input.DocID, domain.RoleOwner, domain.ScopeDocsManage); err != nil {
+		return domain.ShareLink{}, err
+	}
+
+	link := domain.ShareLink{
+		ID:        uuid.New().String(),
+		DocID:     input.DocID,
+		Role:      domain.Role(input.Role),
+		ExpiresAt: input.ExpiresAt,
+		MaxUses:   input.MaxUses,
+		CreatedBy: &owner.ID,
+		CreatedAt: now,
+	}
+	if input.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return domain.ShareLink{}, err
+		}
+		link.PasswordHash = string(hash)
+	}
+	out, err := s.links.Create(ctx, link)
+	if err != nil {
+		return domain.ShareLink{}, err
+	}
+	out.Token = s.linkToken(out.ID)
+	return out, nil
+}
+
+func (s *ShareLinkService) List(ctx context.Context, docID string) ([]domain.ShareLink, error) {
+	if err := s.validate.Var(docID, "required,uuid4"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := s.authorizeOwner(ctx, docID); err != nil {
+		return nil, err
+	}
+	links, err := s.links.ListByDoc(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range links {
+		links[i].Token = s.linkToken(links[i].ID)
+	}
+	return links, nil
+}
+
+// Revoke disables a link and closes the connections opened through it.
+// Sessions already issued stop working on their next request.
+func (s *ShareLinkService) Revoke(ctx context.Context, input RevokeShareLinkInput) (domain.ShareLink, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.ShareLink{}, domain.ErrInvalidInput
+	}
+	if err := s.authorizeOwner(ctx, input.DocID); err != nil {
+		return domain.ShareLink{}, err
+	}
+	link, err := s.links.Revoke(ctx, input.DocID, input.LinkID)
+	if err != nil {
+		return domain.ShareLink{}, err
+	}
+	if s.realtime != nil {
+		s.realtime.Disconnect(link.DocID, domain.ShareAccess{LinkID: link.ID}.Principal())
+	}
+	link.Token = s.linkToken(link.ID)
+	return link, nil
+}
+
+func (s *ShareLinkService) ListAccess(ctx context.Context, input ListShareLinkAccessInput) ([]domain.ShareLinkAccess, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := s.authorizeOwner(ctx, input.DocID); err != nil {
+		return nil, err
+	}
+	if input.Limit == 0 {
+		input.Limit = defaultShareAccessLimit
+	}
+	return s.links.ListAccess(ctx, input.DocID, input.LinkID, input.Limit)
+}
+
+// authorizeOwner requires a signed-in owner; share link holders never
+// manage links, whatever their role.
+func (s *ShareLinkService) authorizeOwner(ctx context.Context, docID string) error {
+	if _, err := currentUser(ctx); err != nil {
+		return err
+	}
+	_, err := authorize(ctx, s.acl, docID, domain.RoleOwner, domain.ScopeDocsManage)
+	return err
+}
+
+// Open redeems a link token, counting one use. Unknown tokens return
+// ErrNotFound, a missing or wrong password ErrUnauthorized, and revoked,
+// expired or used up links ErrForbidden.
+func (s *ShareLinkService) Open(ctx context.Context, input OpenShareLinkInput) (ShareSession, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return ShareSession{}, domain.ErrInvalidInput
+	}
+	linkID, ok := s.verifyLinkToken(input.Token)
+	if !ok {
+		return ShareSession{}, domain.ErrNotFound
+	}
+	link, err := s.links.GetByID(ctx, linkID)
+	if err != nil {
+		return ShareSession{}, err
+	}
+	req := ShareRequest{Action: "open", IP: input.IP, UserAgent: input.UserAgent}
+
+	if outcome := linkUnusable(link, utils.NowUTC()); outcome != "" {
+		return ShareSession{}, s.deny(ctx, link, req, outcome, domain.ErrForbidden)
+	}
+	if link.HasPassword && bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(input.Password)) != nil {
+		return ShareSession{}, s.deny(ctx, link, req, domain.ShareOutcomeBadPassword, domain.ErrUnauthorized)
+	}
+	// The use is only counted once the password matched, and atomically,
+	// so concurrent opens cannot exceed the limit.
+	consumed, err := s.links.Consume(ctx, link.ID)
+	if err == domain.ErrConflict {
+		return ShareSession{}, s.deny(ctx, link, req, domain.ShareOutcomeExhausted, domain.ErrForbidden)
+	}
+	if err != nil {
+		return ShareSession{}, err
+	}
+	link = consumed
+	if err := s.logAccess(ctx, link.ID, req, domain.ShareOutcomeGranted); err != nil {
+		return ShareSession{}, err
+	}
+
+	expires := utils.NowUTC().Add(s.sessionTTL)
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(expires) {
+		expires = *link.ExpiresAt
+	}
+	token, err := s.sessionToken(shareClaims{LinkID: link.ID, DocID: link.DocID, Role: link.Role, Expires: expires.Unix()})
+	if err != nil {
+		return ShareSession{}, err
+	}
+	return ShareSession{DocID: link.DocID, Role: link.Role, Token: token, ExpiresAt: expires}, nil
+}
+
+// Resolve checks a share session token and logs the request made with it.
+// Invalid or expired sessions and sessions of revoked or expired links
+// return ErrUnauthorized.
+func (s *ShareLinkService) Resolve(ctx context.Context, token string, req ShareRequest) (domain.ShareAccess, error) {
+	claims, ok := s.verifySessionToken(token)
+	if !ok || time.Unix(claims.Expires, 0).Before(utils.NowUTC()) {
+		return domain.ShareAccess{}, domain.ErrUnauthorized
+	}
+	link, err := s.links.GetByID(ctx, claims.LinkID)
+	if err == domain.ErrNotFound {
+		return domain.ShareAccess{}, domain.ErrUnauthorized
+	}
+	if err != nil {
+		return domain.ShareAccess{}, err
+	}
+	if link.RevokedAt != nil || (link.ExpiresAt != nil && !link.ExpiresAt.After(utils.NowUTC())) {
+		return domain.ShareAccess{}, s.deny(ctx, link, req, linkUnusable(link, utils.NowUTC()), domain.ErrUnauthorized)
+	}
+	if err := s.logAccess(ctx, link.ID, req, domain.ShareOutcomeGranted); err != nil {
+		return domain.ShareAccess{}, err
+	}
+	return domain.ShareAccess{LinkID: link.ID, DocID: link.DocID, Role: link.Role}, nil
+}
+
+// linkUnusable returns the outcome explaining why a link cannot be opened,
+// or "" if it can.
+func linkUnusable(link domain.ShareLink, now time.Time) string {
+	switch {
+	case link.RevokedAt != nil:
+		return domain.ShareOutcomeRevoked
+	case link.ExpiresAt != nil && !link.ExpiresAt.After(now):
+		return domain.ShareOutcomeExpired
+	case link.MaxUses != nil && link.UseCount >= *link.MaxUses:
+		return domain.ShareOutcomeExhausted
+	}
+	return ""
+}
+
+// deny logs a refused access and returns err, or the logging error.
+func (s *ShareLinkService) deny(ctx context.Context, link domain.ShareLink, req ShareRequest, outcome string, err error) error {
+	if logErr := s.logAccess(ctx, link.ID, req, outcome); logErr != nil {
+		return logErr
+	}
+	return err
+}
+
+func (s *ShareLinkService) logAccess(ctx context.Context, linkID string, req ShareRequest, outcome string) error {
+	entry := domain.ShareLinkAccess{
+		ID:        uuid.New().String(),
+		LinkID:    linkID,
+		Action:    req.Action,
+		Outcome:   outcome,
+		IP:        req.IP,
+		UserAgent: truncateRunes(req.UserAgent, 500),
+		CreatedAt: utils.NowUTC(),
+	}
+	if user, ok := domain.UserFromContext(ctx); ok {
+		entry.UserID = &user.ID
+	}
+	return s.links.LogAccess(ctx, entry)
+}
+
+// linkToken is the link ID with a signature, so tokens cannot be guessed
+// from IDs.
+func (s *ShareLinkService) linkToken(id string) string {
+	return id + "." + s.sign("share-link", id)
+}
+
+func (s *ShareLinkService) verifyLinkToken(token string) (string, bool) {
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || s.validate.Var(id, "uuid4") != nil || !hmac.Equal([]byte(sig), []byte(s.sign("share-link", id))) {
+		return "", false
+	}
+	return id, true
+}
+
+func (s *ShareLinkService) sessionToken(claims shareClaims) (string, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + s.sign("share-session", payload), nil
+}
+
+func (s *ShareLinkService) verifySessionToken(token string) (shareClaims, bool) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign("share-session", payload))) {
+		return shareClaims{}, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return shareClaims{}, false
+	}
+	var claims shareClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return shareClaims{}, false
+	}
+	return claims, true
+}
+
+// sign returns an HMAC-SHA256 of data. The purpose keeps a signature made
+// for one kind of token from validating another.
+func (s *ShareLinkService) sign(purpose string, data string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose + ":" + data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -5,23 +5,35 @@ import (
 
 	"collabdocs/internal/app/ports"
 	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"collabdocs/pkg/yjs"
 	"github.com/go-playground/validator/v10"
 )
 
 type SnapshotService struct {
 	snapshots ports.SnapshotRepository
 	updates   ports.UpdateRepository
+	search    ports.SearchRepository
+	comments  ports.CommentRepository
+	acl       ports.ACLRepository
+	webhooks  *WebhookService
 	validate  *validator.Validate
 }
 
-func NewSnapshotService(snapshots ports.SnapshotRepository, updates ports.UpdateRepository, validate *validator.Validate) *SnapshotService {
-	return &SnapshotService{snapshots: snapshots, updates: updates, validate: validate}
+// NewSnapshotService creates the service. search and comments may be nil, in
+// which case saved snapshots are not indexed or comments not re-anchored, and
+// webhooks may be nil, in which case no webhook events are raised.
+func NewSnapshotService(snapshots ports.SnapshotRepository, updates ports.UpdateRepository, search ports.SearchRepository, comments ports.CommentRepository, acl ports.ACLRepository, webhooks *WebhookService, validate *validator.Validate) *SnapshotService {
+	return &SnapshotService{snapshots: snapshots, updates: updates, search: search, comments: comments, acl: acl, webhooks: webhooks, validate: validate}
 }
 
 func (s *SnapshotService) GetSnapshot(ctx context.Context, docID string) ([]byte, error) {
 	if err := s.validate.Var(docID, "required,uuid4"); err != nil {
 		return nil, domain.ErrInvalidInput
 	}
+	if _, err := authorize(ctx, s.acl, docID, domain.RoleViewer, domain.ScopeSnapshotsRead); err != nil {
+		return nil, err
+	}
 	return s.snapshots.GetSnapshot(ctx, docID)
 }
 
@@ -32,7 +44,63 @@ func (s *SnapshotService) UpsertSnapshot(ctx context.Context, docID string, snap
 	if len(snapshot) == 0 {
 		return domain.ErrInvalidInput
 	}
-	return s.snapshots.UpsertSnapshot(ctx, docID, snapshot)
+	if _, err := authorize(ctx, s.acl, docID, domain.RoleEditor, domain.ScopeDocsWrite); err != nil {
+		return err
+	}
+	return s.saveSnapshot(ctx, docID, snapshot)
+}
+
+// saveSnapshot stores a snapshot and refreshes what is derived from it.
+func (s *SnapshotService) saveSnapshot(ctx context.Context, docID string, snapshot []byte) error {
+	if err := s.snapshots.UpsertSnapshot(ctx, docID, snapshot); err != nil {
+		return err
+	}
+	return s.snapshotSaved(ctx, docID, snapshot)
+}
+
+// snapshotSaved raises the webhook event for a stored snapshot and refreshes
+// the search index and comment anchors from it.
+func (s *SnapshotService) snapshotSaved(ctx context.Context, docID string, snapshot []byte) error {
+	s.webhooks.publish(ctx, docID, domain.WebhookSnapshotUpdated, SnapshotData{Size: len(snapshot), UpdatedAt: utils.NowUTC()})
+	// Snapshots the decoder cannot read keep the previously indexed text
+	// and comment ranges.
+	doc, err := yjs.Decode(snapshot)
+	if err != nil {
+		return nil
+	}
+	if err := s.indexSnapshot(ctx, docID, doc); err != nil {
+		return err
+	}
+	return s.reanchorComments(ctx, docID, doc)
+}
+
+// indexSnapshot refreshes the search index with the snapshot text.
+func (s *SnapshotService) indexSnapshot(ctx context.Context, docID string, doc *yjs.Doc) error {
+	if s.search == nil {
+		return nil
+	}
+	return s.search.IndexContent(ctx, docID, doc.Text())
+}
+
+// reanchorComments resolves the anchors of the document's comments against
+// the snapshot and stores the ranges that moved or became orphaned.
+func (s *SnapshotService) reanchorComments(ctx context.Context, docID string, doc *yjs.Doc) error {
+	if s.comments == nil {
+		return nil
+	}
+	anchors, err := s.comments.ListAnchors(ctx, docID)
+	if err != nil {
+		return err
+	}
+	changed := make([]domain.CommentAnchor, 0)
+	for _, a := range anchors {
+		resolved, ok := resolveAnchor(doc, a)
+		if !ok || (resolved.FromPos == a.FromPos && resolved.ToPos == a.ToPos && resolved.Orphaned == a.Orphaned) {
+			continue
+		}
+		changed = append(changed, resolved)
+	}
+	return s.comments.UpdateAnchors(ctx, docID, changed)
 }
 
 func (s *SnapshotService) AppendUpdate(ctx context.Context, docID string, update []byte) error {
@@ -45,5 +113,8 @@ func (s *SnapshotService) AppendUpdate(ctx context.Context, docID string, update
 	if len(update) == 0 {
 		return domain.ErrInvalidInput
 	}
+	if _, err := authorize(ctx, s.acl, docID, domain.RoleEditor, domain.ScopeDocsWrite); err != nil {
+		return err
+	}
 	return s.updates.AppendUpdate(ctx, docID, update)
 }
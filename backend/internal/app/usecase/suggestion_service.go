@@ -0,0 +1,185 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"collabdocs/pkg/yjs"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type SuggestionService struct {
+	repo      ports.SuggestionRepository
+	snapshots *SnapshotService
+	acl       ports.ACLRepository
+	realtime  ports.Realtime
+	validate  *validator.Validate
+}
+
+// CreateSuggestionInput proposes the change made by Update, an encoded Yjs
+// update, to the range FromPos..ToPos.
+type CreateSuggestionInput struct {
+	DocID       string `validate:"required,uuid4"`
+	FromPos     int    `validate:"min=0"`
+	ToPos       int    `validate:"min=0"`
+	Update      []byte `validate:"required,max=262144"`
+	Description string `validate:"max=2000"`
+}
+
+type DecideSuggestionInput struct {
+	DocID        string `validate:"required,uuid4"`
+	SuggestionID string `validate:"required,uuid4"`
+}
+
+// NewSuggestionService creates the service. realtime may be nil, in which
+// case accepted changes and suggestion events are not pushed to connected
+// clients.
+func NewSuggestionService(repo ports.SuggestionRepository, snapshots *SnapshotService, acl ports.ACLRepository, realtime ports.Realtime, validate *validator.Validate) *SuggestionService {
+	return &SuggestionService{repo: repo, snapshots: snapshots, acl: acl, realtime: realtime, validate: validate}
+}
+
+// List returns the document's suggestions, oldest first. A non-empty status
+// lists only the open, accepted or rejected ones.
+func (s *SuggestionService) List(ctx context.Context, docID string, status string) ([]domain.Suggestion, error) {
+	if err := s.validate.Var(docID, "required,uuid4"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := s.validate.Var(status, "omitempty,oneof=open accepted rejected"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, docID, domain.RoleViewer, domain.ScopeCommentsRead); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByDoc(ctx, docID, status)
+}
+
+func (s *SuggestionService) Get(ctx context.Context, docID string, suggestionID string) (domain.Suggestion, error) {
+	if err := s.validate.Var(docID, "required,uuid4"); err != nil {
+		return domain.Suggestion{}, domain.ErrInvalidInput
+	}
+	if err := s.validate.Var(suggestionID, "required,uuid4"); err != nil {
+		return domain.Suggestion{}, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, docID, domain.RoleViewer, domain.ScopeCommentsRead); err != nil {
+		return domain.Suggestion{}, err
+	}
+	return s.repo.GetByID(ctx, docID, suggestionID)
+}
+
+// Create stores a suggestion by the authenticated user, who needs at least
+// the commenter role. Anonymous share link holders suggest as guests.
+func (s *SuggestionService) Create(ctx context.Context, input CreateSuggestionInput) (domain.Suggestion, error) {
+	input.Description = strings.TrimSpace(input.Description)
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Suggestion{}, domain.ErrInvalidInput
+	}
+	if input.FromPos > input.ToPos {
+		return domain.Suggestion{}, domain.ErrInvalidInput
+	}
+	if _, err := yjs.Decode(input.Update); err != nil {
+		return domain.Suggestion{}, domain.ErrInvalidInput
+	}
+	author, err := authorize(ctx, s.acl, input.DocID, domain.RoleCommenter, domain.ScopeCommentsWrite)
+	if err != nil {
+		return domain.Suggestion{}, err
+	}
+
+	suggestion := domain.Suggestion{
+		ID:          uuid.New().String(),
+		DocID:       input.DocID,
+		AuthorName:  author.Name,
+		Description: input.Description,
+		FromPos:     input.FromPos,
+		ToPos:       input.ToPos,
+		Update:      input.Update,
+		CreatedAt:   utils.NowUTC(),
+	}
+	if author.ID != "" {
+		suggestion.AuthorID = &author.ID
+	} else {
+		suggestion.AuthorName = domain.GuestName
+	}
+	out, err := s.repo.Create(ctx, suggestion)
+	if err != nil {
+		return domain.Suggestion{}, err
+	}
+	s.broadcast(eventSuggestionAdd, out)
+	return out, nil
+}
+
+// Accept applies an open suggestion to the document, which needs the editor
+// role, and records who accepted it. The suggestion is decided and merged
+// into the snapshot together, so of concurrent accepts only one applies it;
+// the change is then sent to the document's room like an edit.
+func (s *SuggestionService) Accept(ctx context.Context, input DecideSuggestionInput) (domain.Suggestion, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Suggestion{}, domain.ErrInvalidInput
+	}
+	user, err := authorize(ctx, s.acl, input.DocID, domain.RoleEditor, domain.ScopeDocsWrite)
+	if err != nil {
+		return domain.Suggestion{}, err
+	}
+	out, snapshot, err := s.repo.Accept(ctx, input.DocID, input.SuggestionID, decision(user, domain.SuggestionAccepted))
+	if err != nil {
+		return domain.Suggestion{}, err
+	}
+	if s.realtime != nil {
+		s.realtime.BroadcastUpdate(input.DocID, out.Update)
+	}
+	if err := s.snapshots.snapshotSaved(ctx, input.DocID, snapshot); err != nil {
+		return domain.Suggestion{}, err
+	}
+	s.broadcast(eventSuggestionAccept, out)
+	return out, nil
+}
+
+// Reject discards an open suggestion and records who rejected it. Editors
+// can reject any suggestion; authors can withdraw their own.
+func (s *SuggestionService) Reject(ctx context.Context, input DecideSuggestionInput) (domain.Suggestion, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return domain.Suggestion{}, domain.ErrInvalidInput
+	}
+	user, err := authorize(ctx, s.acl, input.DocID, domain.RoleCommenter, domain.ScopeCommentsWrite)
+	if err != nil {
+		return domain.Suggestion{}, err
+	}
+	suggestion, err := s.repo.GetByID(ctx, input.DocID, input.SuggestionID)
+	if err != nil {
+		return domain.Suggestion{}, err
+	}
+	own := suggestion.AuthorID != nil && user.ID != "" && *suggestion.AuthorID == user.ID
+	if !own {
+		if _, err := authorize(ctx, s.acl, input.DocID, domain.RoleEditor, domain.ScopeDocsWrite); err != nil {
+			return domain.Suggestion{}, err
+		}
+	}
+
+	out, err := s.repo.Decide(ctx, input.DocID, input.SuggestionID, decision(user, domain.SuggestionRejected))
+	if err != nil {
+		return domain.Suggestion{}, err
+	}
+	s.broadcast(eventSuggestionReject, out)
+	return out, nil
+}
+
+// decision records status as decided now by user, who is the zero User for
+// anonymous share link holders.
+func decision(user domain.User, status string) domain.SuggestionDecision {
+	d := domain.SuggestionDecision{Status: status, Actor: domain.GuestName, DecidedAt: utils.NowUTC()}
+	if user.ID != "" {
+		d.ActorID = &user.ID
+		d.Actor = user.Name
+	}
+	return d
+}
+
+func (s *SuggestionService) broadcast(eventType string, suggestion domain.Suggestion) {
+	if s.realtime == nil {
+		return
+	}
+	s.realtime.BroadcastJSON(suggestion.DocID, SuggestionEvent{Type: eventType, DocID: suggestion.DocID, Suggestion: suggestion})
+}
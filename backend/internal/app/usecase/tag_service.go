@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"github.com/go-playground/validator/v10"
+)
+
+type TagService struct {
+	repo     ports.TagRepository
+	acl      ports.ACLRepository
+	realtime ports.Realtime
+	validate *validator.Validate
+}
+
+type AddTagsInput struct {
+	DocID string   `validate:"required,uuid4"`
+	Tags  []string `validate:"required,min=1,max=20,dive,required,max=40"`
+}
+
+type RemoveTagInput struct {
+	DocID string `validate:"required,uuid4"`
+	Tag   string `validate:"required,max=40"`
+}
+
+// NewTagService creates the service. realtime may be nil, in which case tag
+// changes are not pushed to open editors.
+func NewTagService(repo ports.TagRepository, acl ports.ACLRepository, realtime ports.Realtime, validate *validator.Validate) *TagService {
+	return &TagService{repo: repo, acl: acl, realtime: realtime, validate: validate}
+}
+
+// normalizeTag trims a tag and collapses inner whitespace.
+func normalizeTag(tag string) string {
+	return strings.Join(strings.Fields(tag), " ")
+}
+
+// ListTags counts tag usage over the documents the current user can view.
+func (s *TagService) ListTags(ctx context.Context) ([]domain.Tag, error) {
+	viewer, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListTags(ctx, viewer.ID)
+}
+
+// AddTags attaches tags to a document and returns its full tag list. Tags
+// match case-insensitively; new ones keep the spelling given here.
+func (s *TagService) AddTags(ctx context.Context, input AddTagsInput) ([]string, error) {
+	for i, tag := range input.Tags {
+		input.Tags[i] = normalizeTag(tag)
+	}
+	if err := s.validate.Struct(input); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, input.DocID, domain.RoleEditor, domain.ScopeDocsWrite); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.repo.AddTags(ctx, input.DocID, input.Tags)
+	if err != nil {
+		return nil, err
+	}
+	s.broadcast(input.DocID, tags)
+	return tags, nil
+}
+
+func (s *TagService) RemoveTag(ctx context.Context, input RemoveTagInput) ([]string, error) {
+	input.Tag = normalizeTag(input.Tag)
+	if err := s.validate.Struct(input); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if _, err := authorize(ctx, s.acl, input.DocID, domain.RoleEditor, domain.ScopeDocsWrite); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.repo.RemoveTag(ctx, input.DocID, input.Tag)
+	if err != nil {
+		return nil, err
+	}
+	s.broadcast(input.DocID, tags)
+	return tags, nil
+}
+
+func (s *TagService) broadcast(docID string, tags []string) {
+	if s.realtime == nil {
+		return
+	}
+	s.realtime.BroadcastJSON(docID, TagsEvent{Type: eventTagsUpdate, DocID: docID, Tags: tags})
+}
@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"regexp"
+	"time"
+)
+
+// templateVariablePattern matches placeholders such as {{date}} or {{ customer }}.
+var templateVariablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// templateVariables returns the values available to a template: the built-in
+// date and author, overridden by any variables passed by the caller.
+func templateVariables(author string, custom map[string]string, now time.Time) map[string]string {
+	vars := map[string]string{"date": now.Format("2006-01-02")}
+	if author != "" {
+		vars["author"] = author
+	}
+	for k, v := range custom {
+		vars[k] = v
+	}
+	return vars
+}
+
+// renderTemplate substitutes known placeholders in text and leaves unknown
+// ones untouched.
+func renderTemplate(text string, vars map[string]string) string {
+	return templateVariablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := templateVariablePattern.FindStringSubmatch(match)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return match
+	})
+}
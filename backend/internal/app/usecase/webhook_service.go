@@ -0,0 +1,344 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// WebhookSecretPrefix starts every webhook signing secret.
+const WebhookSecretPrefix = "whsec_"
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256 of
+// the timestamp, a dot and the body, keyed with the webhook's secret.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	// webhookMaxAttempts is how many times a delivery is sent before it is
+	// marked failed.
+	webhookMaxAttempts = 8
+	// Retries back off exponentially from webhookRetryBase, doubling after
+	// each attempt up to webhookRetryMax.
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = 6 * time.Hour
+	// webhookLease is how long a claimed delivery is hidden from other
+	// dispatchers while it is being sent.
+	webhookLease     = 10 * time.Minute
+	webhookBatchSize = 50
+
+	defaultDeliveriesLimit = 50
+)
+
+// Data of the webhook events, sent as the payload's data field.
+
+// DocumentData is the data of doc.created and doc.deleted.
+type DocumentData struct {
+	Document domain.Document `json:"document"`
+}
+
+type DocumentRenamedData struct {
+	Document      domain.Document `json:"document"`
+	PreviousTitle string          `json:"previousTitle"`
+}
+
+// CommentData is the data of comment.created and comment.resolved.
+type CommentData struct {
+	Comment domain.Comment `json:"comment"`
+}
+
+// SnapshotData is the data of snapshot.updated. The content itself is
+// fetched from the snapshot endpoint.
+type SnapshotData struct {
+	Size      int       `json:"size"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type WebhookService struct {
+	repo     ports.WebhookRepository
+	sender   ports.WebhookSender
+	acl      ports.ACLRepository
+	validate *validator.Validate
+}
+
+type CreateWebhookInput struct {
+	URL    string   `validate:"required,max=2048,url"`
+	Events []string `validate:"required,min=1,dive,oneof=doc.created doc.renamed doc.deleted comment.created comment.resolved snapshot.updated"`
+	// DocID limits the webhook to one document; empty means every document
+	// the user can view.
+	DocID string `validate:"omitempty,uuid4"`
+}
+
+// WebhookResult carries the signing secret of a new webhook, which is only
+// ever returned here.
+type WebhookResult struct {
+	Webhook domain.Webhook
+	Secret  string
+}
+
+func NewWebhookService(repo ports.WebhookRepository, sender ports.WebhookSender, acl ports.ACLRepository, validate *validator.Validate) *WebhookService {
+	return &WebhookService{repo: repo, sender: sender, acl: acl, validate: validate}
+}
+
+// Create subscribes a URL to events of the documents the current user can
+// view. Webhooks cannot be managed with an API key.
+func (s *WebhookService) Create(ctx context.Context, input CreateWebhookInput) (WebhookResult, error) {
+	user, err := s.owner(ctx)
+	if err != nil {
+		return WebhookResult{}, err
+	}
+	input.URL = strings.TrimSpace(input.URL)
+	if err := s.validate.Struct(input); err != nil {
+		return WebhookResult{}, domain.ErrInvalidInput
+	}
+	if u, err := url.Parse(input.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return WebhookResult{}, domain.ErrInvalidInput
+	}
+	if err := s.sender.CheckURL(ctx, input.URL); err != nil {
+		return WebhookResult{}, domain.ErrInvalidInput
+	}
+	var docID *string
+	if input.DocID != "" {
+		if _, err := authorize(ctx, s.acl, input.DocID, domain.RoleViewer, domain.ScopeDocsRead); err != nil {
+			return WebhookResult{}, err
+		}
+		docID = &input.DocID
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return WebhookResult{}, err
+	}
+	secret := WebhookSecretPrefix + token
+	webhook, err := s.repo.Create(ctx, domain.Webhook{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		DocID:     docID,
+		URL:       input.URL,
+		Events:    dedupe(input.Events),
+		Secret:    secret,
+		CreatedAt: utils.NowUTC(),
+	})
+	if err != nil {
+		return WebhookResult{}, err
+	}
+	return WebhookResult{Webhook: webhook, Secret: secret}, nil
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]domain.Webhook, error) {
+	user, err := s.owner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListByUser(ctx, user.ID)
+}
+
+func (s *WebhookService) Get(ctx context.Context, id string) (domain.Webhook, error) {
+	if err := s.validate.Var(id, "required,uuid4"); err != nil {
+		return domain.Webhook{}, domain.ErrInvalidInput
+	}
+	user, err := s.owner(ctx)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	return s.repo.GetByID(ctx, user.ID, id)
+}
+
+// Delete removes a webhook along with its delivery log.
+func (s *WebhookService) Delete(ctx context.Context, id string) error {
+	if err := s.validate.Var(id, "required,uuid4"); err != nil {
+		return domain.ErrInvalidInput
+	}
+	user, err := s.owner(ctx)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, user.ID, id)
+}
+
+// Deliveries returns the latest entries of a webhook's delivery log, newest
+// first. A zero limit returns the default page size.
+func (s *WebhookService) Deliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	if err := s.validate.Var(webhookID, "required,uuid4"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := s.validate.Var(limit, "min=0,max=100"); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	user, err := s.owner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetByID(ctx, user.ID, webhookID); err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = defaultDeliveriesLimit
+	}
+	return s.repo.ListDeliveries(ctx, user.ID, webhookID, limit)
+}
+
+// Redeliver queues the payload of a logged delivery again, as a new delivery
+// sent as soon as possible. The original entry is left as it was.
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID string, deliveryID string) (domain.WebhookDelivery, error) {
+	if err := s.validate.Var(webhookID, "required,uuid4"); err != nil {
+		return domain.WebhookDelivery{}, domain.ErrInvalidInput
+	}
+	if err := s.validate.Var(deliveryID, "required,uuid4"); err != nil {
+		return domain.WebhookDelivery{}, domain.ErrInvalidInput
+	}
+	user, err := s.owner(ctx)
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	original, err := s.repo.GetDelivery(ctx, user.ID, webhookID, deliveryID)
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+
+	now := utils.NowUTC()
+	delivery := domain.WebhookDelivery{
+		ID:            uuid.New().String(),
+		WebhookID:     webhookID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        domain.DeliveryPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &original.ID,
+		CreatedAt:     now,
+	}
+	if err := s.repo.CreateDeliveries(ctx, []domain.WebhookDelivery{delivery}); err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+// DeliverDue sends the deliveries whose next attempt is due and returns how
+// many were attempted. Failed attempts are retried with exponential backoff
+// until webhookMaxAttempts is reached.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	due, err := s.repo.ClaimDue(ctx, utils.NowUTC(), webhookLease, webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, d := range due {
+		if err := s.deliver(ctx, d); err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+func (s *WebhookService) deliver(ctx context.Context, due domain.DueDelivery) error {
+	d := due.Delivery
+	timestamp := strconv.FormatInt(utils.NowUTC().Unix(), 10)
+	header := http.Header{}
+	header.Set(WebhookEventHeader, d.Event)
+	header.Set(WebhookDeliveryHeader, d.ID)
+	header.Set(WebhookTimestampHeader, timestamp)
+	header.Set(WebhookSignatureHeader, "sha256="+signWebhook(due.Secret, timestamp, d.Payload))
+
+	code, err := s.sender.Send(ctx, due.URL, header, d.Payload)
+	attempt := domain.WebhookAttempt{At: utils.NowUTC()}
+	switch {
+	case err != nil:
+		attempt.Error = err.Error()
+	case code < 200 || code > 299:
+		attempt.StatusCode = &code
+		attempt.Error = http.StatusText(code)
+	default:
+		attempt.StatusCode = &code
+		attempt.Status = domain.DeliveryDelivered
+		return s.repo.RecordAttempt(ctx, d.ID, attempt)
+	}
+
+	attempts := d.Attempts + 1
+	if attempts >= webhookMaxAttempts {
+		attempt.Status = domain.DeliveryFailed
+	} else {
+		next := attempt.At.Add(retryDelay(attempts))
+		attempt.Status = domain.DeliveryPending
+		attempt.NextAttemptAt = &next
+	}
+	return s.repo.RecordAttempt(ctx, d.ID, attempt)
+}
+
+// retryDelay is the wait before the attempt following the given number of
+// failed ones.
+func retryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
+
+func signWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// publish queues an event of a document for every webhook subscribed to it.
+// Webhooks are a side effect of the change that raised the event, so errors
+// are dropped rather than failing it.
+func (s *WebhookService) publish(ctx context.Context, docID string, eventType string, data any) {
+	if s == nil {
+		return
+	}
+	// The change is already made; queue its event even if the request that
+	// made it is cancelled.
+	ctx = context.WithoutCancel(ctx)
+	webhooks, err := s.repo.ListSubscribers(ctx, docID, eventType)
+	if err != nil || len(webhooks) == 0 {
+		return
+	}
+	now := utils.NowUTC()
+	event := domain.WebhookEvent{ID: uuid.New().String(), Type: eventType, DocID: docID, CreatedAt: now, Data: data}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	deliveries := make([]domain.WebhookDelivery, 0, len(webhooks))
+	for _, w := range webhooks {
+		deliveries = append(deliveries, domain.WebhookDelivery{
+			ID:            uuid.New().String(),
+			WebhookID:     w.ID,
+			EventID:       event.ID,
+			Event:         eventType,
+			Payload:       payload,
+			Status:        domain.DeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		})
+	}
+	_ = s.repo.CreateDeliveries(ctx, deliveries)
+}
+
+// owner returns the signed-in user, refusing requests made with a key.
+func (s *WebhookService) owner(ctx context.Context) (domain.User, error) {
+	if _, ok := domain.APIKeyFromContext(ctx); ok {
+		return domain.User{}, domain.ErrForbidden
+	}
+	return currentUser(ctx)
+}
@@ -0,0 +1,308 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"collabdocs/internal/app/ports"
+	"collabdocs/internal/domain"
+	"collabdocs/internal/infrastructure/webhook"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// memWebhooks implements the webhook repository methods the service uses.
+type memWebhooks struct {
+	ports.WebhookRepository
+	created    []domain.Webhook
+	deliveries map[string]domain.WebhookDelivery
+	queued     []domain.WebhookDelivery
+	due        []domain.DueDelivery
+	attempts   map[string]domain.WebhookAttempt
+}
+
+func newMemWebhooks() *memWebhooks {
+	return &memWebhooks{deliveries: make(map[string]domain.WebhookDelivery), attempts: make(map[string]domain.WebhookAttempt)}
+}
+
+func (m *memWebhooks) Create(ctx context.Context, w domain.Webhook) (domain.Webhook, error) {
+	m.created = append(m.created, w)
+	return w, nil
+}
+
+func (m *memWebhooks) GetDelivery(ctx context.Context, userID string, webhookID string, id string) (domain.WebhookDelivery, error) {
+	d, ok := m.deliveries[id]
+	if !ok || d.WebhookID != webhookID {
+		return domain.WebhookDelivery{}, domain.ErrNotFound
+	}
+	return d, nil
+}
+
+func (m *memWebhooks) CreateDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	m.queued = append(m.queued, deliveries...)
+	return nil
+}
+
+func (m *memWebhooks) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.DueDelivery, error) {
+	due := m.due
+	m.due = nil
+	return due, nil
+}
+
+func (m *memWebhooks) RecordAttempt(ctx context.Context, id string, attempt domain.WebhookAttempt) error {
+	m.attempts[id] = attempt
+	return nil
+}
+
+func withTestUser(ctx context.Context) context.Context {
+	return domain.WithUser(ctx, domain.User{ID: uuid.New().String(), Name: "Maria"})
+}
+
+// receiver is a webhook endpoint answering with status and recording the
+// requests it gets.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func TestSignWebhook(t *testing.T) {
+	got := signWebhook("whsec_test", "1700000000", []byte(`{"id":"evt"}`))
+	if want := "a94cea056df1fbb92eadafcf2c5cd541dbe0c6ef736e4748202dd53f86694a3e"; got != want {
+		t.Errorf("signWebhook = %s, want %s", got, want)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{7, 32 * time.Minute},
+		{9, 128 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDeliverDue(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		down     bool
+		want     string
+		code     int
+		retryIn  time.Duration
+	}{
+		{"delivered", http.StatusNoContent, 0, false, domain.DeliveryDelivered, http.StatusNoContent, 0},
+		{"first failure", http.StatusInternalServerError, 0, false, domain.DeliveryPending, http.StatusInternalServerError, 30 * time.Second},
+		{"backoff", http.StatusServiceUnavailable, 3, false, domain.DeliveryPending, http.StatusServiceUnavailable, 4 * time.Minute},
+		{"redirect is a failure", http.StatusFound, 0, false, domain.DeliveryPending, http.StatusFound, 30 * time.Second},
+		{"no response", 0, 1, true, domain.DeliveryPending, 0, time.Minute},
+		{"last attempt", http.StatusInternalServerError, webhookMaxAttempts - 1, false, domain.DeliveryFailed, http.StatusInternalServerError, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recv := newReceiver(t)
+			recv.status = tt.status
+			url := recv.URL + "/hook"
+			if tt.down {
+				url = closed.URL
+			}
+			repo := newMemWebhooks()
+			delivery := domain.WebhookDelivery{
+				ID:       uuid.New().String(),
+				Event:    domain.WebhookDocCreated,
+				Payload:  []byte(`{"id":"evt"}`),
+				Status:   domain.DeliveryPending,
+				Attempts: tt.attempts,
+			}
+			repo.due = []domain.DueDelivery{{Delivery: delivery, URL: url, Secret: "whsec_test"}}
+			s := NewWebhookService(repo, webhook.NewSender(5*time.Second, true), nil, validator.New())
+
+			sent, err := s.DeliverDue(context.Background())
+			if err != nil || sent != 1 {
+				t.Fatalf("DeliverDue = %d, %v; want 1", sent, err)
+			}
+			attempt, ok := repo.attempts[delivery.ID]
+			if !ok {
+				t.Fatal("no attempt recorded")
+			}
+			if attempt.Status != tt.want {
+				t.Errorf("status = %s, want %s", attempt.Status, tt.want)
+			}
+			switch {
+			case tt.code == 0 && attempt.StatusCode != nil:
+				t.Errorf("status code = %d, want none", *attempt.StatusCode)
+			case tt.code != 0 && (attempt.StatusCode == nil || *attempt.StatusCode != tt.code):
+				t.Errorf("status code = %v, want %d", attempt.StatusCode, tt.code)
+			}
+			if (attempt.Error == "") != (tt.want == domain.DeliveryDelivered) {
+				t.Errorf("error = %q", attempt.Error)
+			}
+			switch {
+			case tt.retryIn == 0 && attempt.NextAttemptAt != nil:
+				t.Errorf("next attempt at %s, want none", attempt.NextAttemptAt)
+			case tt.retryIn != 0 && (attempt.NextAttemptAt == nil || attempt.NextAttemptAt.Sub(attempt.At) != tt.retryIn):
+				t.Errorf("next attempt at %v, want %s after %s", attempt.NextAttemptAt, tt.retryIn, attempt.At)
+			}
+		})
+	}
+}
+
+func TestDeliverDueSignsRequests(t *testing.T) {
+	recv := newReceiver(t)
+	repo := newMemWebhooks()
+	delivery := domain.WebhookDelivery{ID: uuid.New().String(), Event: domain.WebhookDocRenamed, Payload: []byte(`{"id":"evt","type":"doc.renamed"}`)}
+	repo.due = []domain.DueDelivery{{Delivery: delivery, URL: recv.URL, Secret: "whsec_test"}}
+	s := NewWebhookService(repo, webhook.NewSender(5*time.Second, true), nil, validator.New())
+
+	before := time.Now().Unix()
+	if _, err := s.DeliverDue(context.Background()); err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	if len(recv.requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(recv.requests))
+	}
+	req, body := recv.requests[0], recv.bodies[0]
+	if got := req.Header.Get(WebhookEventHeader); got != domain.WebhookDocRenamed {
+		t.Errorf("%s = %q", WebhookEventHeader, got)
+	}
+	if got := req.Header.Get(WebhookDeliveryHeader); got != delivery.ID {
+		t.Errorf("%s = %q, want %s", WebhookDeliveryHeader, got, delivery.ID)
+	}
+	timestamp := req.Header.Get(WebhookTimestampHeader)
+	if ts, err := strconv.ParseInt(timestamp, 10, 64); err != nil || ts < before || ts > time.Now().Unix() {
+		t.Errorf("%s = %q, want the Unix time of sending", WebhookTimestampHeader, timestamp)
+	}
+
+	// Verify the way the README tells receivers to.
+	sig, ok := strings.CutPrefix(req.Header.Get(WebhookSignatureHeader), "sha256=")
+	if !ok {
+		t.Fatalf("%s = %q, want a sha256= prefix", WebhookSignatureHeader, req.Header.Get(WebhookSignatureHeader))
+	}
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, mac.Sum(nil)) {
+		t.Errorf("signature %s does not verify", sig)
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	repo := newMemWebhooks()
+	s := NewWebhookService(repo, webhook.NewSender(5*time.Second, true), nil, validator.New())
+	code := http.StatusInternalServerError
+	original := domain.WebhookDelivery{
+		ID:             uuid.New().String(),
+		WebhookID:      uuid.New().String(),
+		EventID:        uuid.New().String(),
+		Event:          domain.WebhookCommentCreated,
+		Payload:        []byte(`{"id":"evt"}`),
+		Status:         domain.DeliveryFailed,
+		Attempts:       webhookMaxAttempts,
+		LastStatusCode: &code,
+	}
+	repo.deliveries[original.ID] = original
+	ctx := withTestUser(context.Background())
+
+	out, err := s.Redeliver(ctx, original.WebhookID, original.ID)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if out.ID == original.ID || out.RedeliveryOf == nil || *out.RedeliveryOf != original.ID {
+		t.Errorf("redelivery %s of %v, want a new delivery of %s", out.ID, out.RedeliveryOf, original.ID)
+	}
+	if out.Status != domain.DeliveryPending || out.Attempts != 0 || out.NextAttemptAt == nil {
+		t.Errorf("redelivery = %+v, want pending and due now", out)
+	}
+	if out.EventID != original.EventID || out.Event != original.Event || string(out.Payload) != string(original.Payload) {
+		t.Errorf("redelivery carries %s %s %s, want the original event", out.EventID, out.Event, out.Payload)
+	}
+	if len(repo.queued) != 1 || repo.queued[0].ID != out.ID {
+		t.Errorf("queued %v, want the redelivery", repo.queued)
+	}
+	if repo.deliveries[original.ID].Status != domain.DeliveryFailed {
+		t.Error("original delivery changed")
+	}
+
+	if _, err := s.Redeliver(ctx, original.WebhookID, uuid.New().String()); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("unknown delivery: err = %v, want ErrNotFound", err)
+	}
+	keyCtx := domain.WithAPIKey(ctx, domain.APIKey{ID: uuid.New().String()})
+	if _, err := s.Redeliver(keyCtx, original.WebhookID, original.ID); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("with an API key: err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestCreateWebhookRejectsInternalTargets(t *testing.T) {
+	repo := newMemWebhooks()
+	s := NewWebhookService(repo, webhook.NewSender(5*time.Second, false), nil, validator.New())
+	ctx := withTestUser(context.Background())
+	events := []string{domain.WebhookDocCreated}
+
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.1.2.3/hook",
+		"http://[::1]/hook",
+		"ftp://93.184.216.34/hook",
+	} {
+		if _, err := s.Create(ctx, CreateWebhookInput{URL: url, Events: events}); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("Create(%s): err = %v, want ErrInvalidInput", url, err)
+		}
+	}
+	if len(repo.created) != 0 {
+		t.Fatalf("%d webhooks stored, want 0", len(repo.created))
+	}
+
+	out, err := s.Create(ctx, CreateWebhookInput{URL: "https://93.184.216.34/hook", Events: events})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !strings.HasPrefix(out.Secret, WebhookSecretPrefix) || out.Webhook.Secret != out.Secret {
+		t.Errorf("secret = %q", out.Secret)
+	}
+}
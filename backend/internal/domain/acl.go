@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Role is a level of access to a document. Each role includes the
+// permissions of the roles below it.
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"
+	RoleCommenter Role = "commenter"
+	RoleEditor    Role = "editor"
+	// RoleOwner can also delete the document, change its state and manage
+	// access. It can only be granted to users.
+	RoleOwner Role = "owner"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer:    1,
+	RoleCommenter: 2,
+	RoleEditor:    3,
+	RoleOwner:     4,
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes reports whether r grants at least the permissions of other. The
+// empty role, meaning no access, includes nothing.
+func (r Role) Includes(other Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[other]
+}
+
+// Grant gives a user or a group a role on a document. Exactly one of UserID
+// and GroupID is set.
+type Grant struct {
+	ID      string  `json:"id"`
+	DocID   string  `json:"docId"`
+	UserID  *string `json:"userId,omitempty"`
+	GroupID *string `json:"groupId,omitempty"`
+	// Name is the display name of the user or group.
+	Name string `json:"name"`
+	// Email is set for user grants.
+	Email     string    `json:"email,omitempty"`
+	Role      Role      `json:"role"`
+	GrantedBy *string   `json:"grantedBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Group is a named set of users that can be granted access as a whole. Only
+// its owner manages the members. Groups with an ExternalID mirror a group of
+// the identity provider; they have no owner and their members are synced at
+// sign-in.
+type Group struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	OwnerID    string    `json:"ownerId,omitempty"`
+	ExternalID string    `json:"externalId,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// DocAccess is a role already resolved for a document, such as the one a
+// WebSocket connection was opened with. The connection is closed when the
+// role is revoked or lowered, so it holds for the connection's lifetime.
+type DocAccess struct {
+	DocID string
+	Role  Role
+}
+
+type docAccessKey struct{}
+
+// WithDocAccess returns a context carrying a resolved document role.
+func WithDocAccess(ctx context.Context, access DocAccess) context.Context {
+	return context.WithValue(ctx, docAccessKey{}, access)
+}
+
+// DocAccessFromContext returns the resolved document role, if any.
+func DocAccessFromContext(ctx context.Context) (DocAccess, bool) {
+	access, ok := ctx.Value(docAccessKey{}).(DocAccess)
+	return access, ok
+}
@@ -2,14 +2,146 @@ package domain
 
 import "time"
 
-// Comment is an inline comment attached to a document.
+// DeletedCommentText replaces the text of a deleted comment kept as a
+// tombstone because its thread still has replies.
+const DeletedCommentText = "comment deleted"
+
+// Comment is an inline comment attached to a document. Root comments anchor
+// a thread to a range; replies have a ParentID and share their root's range
+// and resolved state.
+//
+// FromAnchor and ToAnchor are encoded Yjs relative positions. When set, the
+// range follows the text it was made on: FromPos and ToPos are recomputed
+// from the anchors whenever a snapshot is saved, and Orphaned flags comments
+// whose anchored text has been deleted.
 type Comment struct {
-	ID         string    `json:"id"`
-	DocID      string    `json:"docId"`
-	AuthorName string    `json:"authorName"`
-	FromPos    int       `json:"fromPos"`
-	ToPos      int       `json:"toPos"`
-	Text       string    `json:"text"`
-	Resolved   bool      `json:"resolved"`
-	CreatedAt  time.Time `json:"createdAt"`
+	ID         string  `json:"id"`
+	DocID      string  `json:"docId"`
+	ParentID   *string `json:"parentId,omitempty"`
+	AuthorID   *string `json:"authorId"`
+	AuthorName string  `json:"authorName"`
+	FromPos    int     `json:"fromPos"`
+	ToPos      int     `json:"toPos"`
+	FromAnchor []byte  `json:"fromAnchor,omitempty"`
+	ToAnchor   []byte  `json:"toAnchor,omitempty"`
+	Orphaned   bool    `json:"orphaned"`
+	Text       string  `json:"text"`
+	// HTML is Text rendered from Markdown and sanitized, ready to display.
+	HTML     string `json:"html"`
+	Resolved bool   `json:"resolved"`
+	// ResolvedBy and ReopenedBy are the users who last resolved and last
+	// reopened the thread; they are nil for guests, whose names are kept.
+	ResolvedBy     *string    `json:"resolvedBy"`
+	ResolvedByName string     `json:"resolvedByName,omitempty"`
+	ResolvedAt     *time.Time `json:"resolvedAt"`
+	ReopenedBy     *string    `json:"reopenedBy"`
+	ReopenedByName string     `json:"reopenedByName,omitempty"`
+	ReopenedAt     *time.Time `json:"reopenedAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	Version        int64      `json:"version"`
+	// ReplyCount counts the live replies of a root comment.
+	ReplyCount int `json:"replyCount"`
+	// DeletedAt is set on tombstones; their author and text are hidden.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// Reactions are ordered by the first time each emoji was used.
+	Reactions []Reaction `json:"reactions"`
+}
+
+// Reaction aggregates the users who reacted to a comment with one emoji.
+type Reaction struct {
+	Emoji string    `json:"emoji"`
+	Count int       `json:"count"`
+	Users []Reactor `json:"users"`
+}
+
+// Reactor is a user who reacted, in the order they did.
+type Reactor struct {
+	UserID string `json:"userId"`
+	Name   string `json:"name"`
+}
+
+// IsReply reports whether the comment belongs to another comment's thread.
+func (c Comment) IsReply() bool {
+	return c.ParentID != nil
+}
+
+// CommentUpdate is a partial update of a comment by an actor. Nil fields are
+// left unchanged.
+type CommentUpdate struct {
+	Resolved *bool
+	Text     *string
+	// IfVersion, when set, makes the update fail with a VersionConflictError
+	// unless the comment is still at that version.
+	IfVersion *int64
+	// ActorID is nil for guests.
+	ActorID *string
+	Actor   string
+}
+
+// CommentRevision records one edit of a comment's text.
+type CommentRevision struct {
+	ID           string    `json:"id"`
+	CommentID    string    `json:"commentId"`
+	PreviousText string    `json:"previousText"`
+	Text         string    `json:"text"`
+	EditedBy     *string   `json:"editedBy"`
+	EditedByName string    `json:"editedByName"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// CommentHistory is a comment with its text edits, oldest first.
+type CommentHistory struct {
+	Comment   Comment           `json:"comment"`
+	Revisions []CommentRevision `json:"revisions"`
+}
+
+// CommentAnchor is the anchored range of a root comment as resolved against
+// a snapshot.
+type CommentAnchor struct {
+	CommentID  string
+	FromAnchor []byte
+	ToAnchor   []byte
+	FromPos    int
+	ToPos      int
+	Orphaned   bool
+}
+
+// CommentCursor is the position after which a comment listing continues:
+// the creation time of the last returned comment and its ID.
+type CommentCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// CommentListQuery filters the root comments of a document, newest first.
+type CommentListQuery struct {
+	DocID    string
+	Resolved *bool
+	AuthorID string
+	// CreatedAfter matches comments created at or after the time.
+	CreatedAfter *time.Time
+	// FromPos and ToPos match comments whose range overlaps them.
+	FromPos *int
+	ToPos   *int
+	// Query is a full-text search over the text of the comment and its
+	// replies.
+	Query string
+	After *CommentCursor
+	Limit int
+}
+
+// CommentCounts counts the comments matching a listing's filters other than
+// the resolved state.
+type CommentCounts struct {
+	Open     int `json:"open"`
+	Resolved int `json:"resolved"`
+	Total    int `json:"total"`
+}
+
+// CommentPage is one page of a comment listing.
+type CommentPage struct {
+	Comments   []Comment     `json:"comments"`
+	Counts     CommentCounts `json:"counts"`
+	NextCursor string        `json:"nextCursor,omitempty"`
 }
@@ -4,8 +4,114 @@ import "time"
 
 // Document represents document metadata.
 type Document struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	CreatedAt time.Time `json:"-"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	FolderID  string     `json:"folderId"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// ForkedFrom is the ID of the document this one was duplicated from.
+	ForkedFrom *string  `json:"forkedFrom,omitempty"`
+	IsTemplate bool     `json:"isTemplate"`
+	Tags       []string `json:"tags"`
+	// Properties holds custom property values keyed by definition key.
+	Properties map[string]any `json:"properties"`
+	State      DocumentState  `json:"state"`
+	// AllowComments keeps comments open while the document is locked.
+	AllowComments bool `json:"allowComments"`
+	// Version increases with every metadata change. Content edits made
+	// through the editor do not change it.
+	Version int64 `json:"version"`
+}
+
+// DocumentState controls whether a document can be edited.
+type DocumentState string
+
+const (
+	DocumentActive DocumentState = "active"
+	// DocumentLocked documents are read-only; comments stay open when
+	// AllowComments is set.
+	DocumentLocked DocumentState = "locked"
+	// DocumentArchived documents are read-only, comments included.
+	DocumentArchived DocumentState = "archived"
+)
+
+// ReadOnly reports whether the document's content and metadata are frozen.
+func (d Document) ReadOnly() bool {
+	return d.State != DocumentActive
+}
+
+// CommentsAllowed reports whether comments can be added or changed.
+func (d Document) CommentsAllowed() bool {
+	return d.State == DocumentActive || (d.State == DocumentLocked && d.AllowComments)
+}
+
+// DocumentStateEvent is an audit record of a document state change.
+type DocumentStateEvent struct {
+	ID            string        `json:"id"`
+	DocID         string        `json:"docId"`
+	FromState     DocumentState `json:"fromState"`
+	ToState       DocumentState `json:"toState"`
+	AllowComments bool          `json:"allowComments"`
+	ActorID       *string       `json:"actorId"`
+	Actor         string        `json:"actor"`
+	Reason        string        `json:"reason"`
+	CreatedAt     time.Time     `json:"createdAt"`
+}
+
+// DocumentUpdate is a partial update of a document. Nil fields are left
+// unchanged.
+type DocumentUpdate struct {
+	Title           *string
+	SetProperties   map[string]any
+	UnsetProperties []string
+	// IfVersion, when set, makes the update fail with a VersionConflictError
+	// unless the document is still at that version.
+	IfVersion *int64
+}
+
+// DocumentSort selects the column a document listing is ordered by.
+type DocumentSort string
+
+const (
+	DocumentSortTitle   DocumentSort = "title"
+	DocumentSortCreated DocumentSort = "created"
+	DocumentSortUpdated DocumentSort = "updated"
+)
+
+// DocumentCursor is the position after which a listing continues: the sort
+// value of the last returned document and its ID as a tie-breaker.
+type DocumentCursor struct {
+	Value string
+	ID    string
+}
+
+// DocumentListQuery filters and orders a document listing.
+type DocumentListQuery struct {
+	// ViewerID limits the listing to documents the user can view.
+	ViewerID string
+	// IDs, when not nil, limits the listing to these documents.
+	IDs           []string
+	Sort          DocumentSort
+	Desc          bool
+	Trashed       bool
+	IsTemplate    *bool
+	FolderID      string
+	Tags          []string
+	AllTags       bool // documents must carry every tag rather than any
+	Properties    []PropertyFilter
+	TitlePrefix   string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	After         *DocumentCursor
+	Limit         int
+}
+
+// DocumentPage is one page of a document listing.
+type DocumentPage struct {
+	Documents  []Document `json:"documents"`
+	Total      int        `json:"total"`
+	NextCursor string     `json:"nextCursor,omitempty"`
 }
@@ -3,8 +3,25 @@ package domain
 import "errors"
 
 var (
-	ErrNotFound      = errors.New("not found")
-	ErrInvalidInput  = errors.New("invalid input")
-	ErrConflict      = errors.New("conflict")
-	ErrInternal      = errors.New("internal error")
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrConflict     = errors.New("conflict")
+	ErrInternal     = errors.New("internal error")
+	// ErrUnauthorized means the request carries no valid credentials.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden means the user's role does not allow the operation.
+	ErrForbidden = errors.New("forbidden")
+	// ErrReadOnly rejects changes to a locked or archived document.
+	ErrReadOnly = errors.New("document is read-only")
 )
+
+// VersionConflictError reports a write rejected because the resource changed
+// after the version the caller based it on. It matches ErrConflict.
+type VersionConflictError struct {
+	Version int64
+	Current any
+}
+
+func (e *VersionConflictError) Error() string { return "version conflict" }
+
+func (e *VersionConflictError) Unwrap() error { return ErrConflict }
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// Workspace is the top level of the document hierarchy. The default
+// workspace is shared by every user and has no owner; other workspaces are
+// only open to their members.
+type Workspace struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	IsDefault    bool      `json:"isDefault"`
+	OwnerID      string    `json:"ownerId,omitempty"`
+	RootFolderID string    `json:"rootFolderId"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// Folder is a node in a workspace's folder tree. The root folder of a
+// workspace has no parent.
+type Folder struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspaceId"`
+	ParentID    *string   `json:"parentId"`
+	Name        string    `json:"name"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,14 @@
+package domain
+
+// IdentityClaims is what an external identity provider asserts about the
+// user signing in.
+type IdentityClaims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// Groups are the provider's group names. nil means the provider does not
+	// send groups, so memberships are left alone.
+	Groups []string
+}
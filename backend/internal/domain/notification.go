@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// NotificationMention is sent to users mentioned in a comment.
+const NotificationMention = "mention"
+
+// Notification is an entry in a user's inbox pointing at the comment that
+// caused it.
+type Notification struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"userId"`
+	Type      string     `json:"type"`
+	DocID     string     `json:"docId"`
+	CommentID string     `json:"commentId"`
+	ActorID   *string    `json:"actorId"`
+	ActorName string     `json:"actorName"`
+	Excerpt   string     `json:"excerpt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ReadAt    *time.Time `json:"readAt"`
+}
+
+// NotificationCursor is the position after which an inbox listing
+// continues: the creation time of the last returned notification and its ID.
+type NotificationCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// NotificationQuery lists a user's inbox, newest first.
+type NotificationQuery struct {
+	UserID     string
+	UnreadOnly bool
+	After      *NotificationCursor
+	Limit      int
+}
+
+// NotificationPage is one page of an inbox with the user's unread count.
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	Unread        int            `json:"unread"`
+	NextCursor    string         `json:"nextCursor,omitempty"`
+}
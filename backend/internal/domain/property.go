@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// PropertyType is the value type of a custom document property.
+type PropertyType string
+
+const (
+	PropertyString PropertyType = "string"
+	PropertyNumber PropertyType = "number"
+	// PropertyDate values are calendar dates formatted as YYYY-MM-DD.
+	PropertyDate PropertyType = "date"
+	// PropertyEnum values must be one of the definition's options.
+	PropertyEnum PropertyType = "enum"
+	// PropertyUser values are the IDs of existing users.
+	PropertyUser PropertyType = "user"
+)
+
+// PropertyDefinition declares a custom property available to every document
+// in a workspace. Documents store values under the definition's key.
+type PropertyDefinition struct {
+	ID          string       `json:"id"`
+	WorkspaceID string       `json:"workspaceId"`
+	Key         string       `json:"key"`
+	Name        string       `json:"name"`
+	Type        PropertyType `json:"type"`
+	Options     []string     `json:"options"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+}
+
+// PropertyOp is the comparison a property filter applies.
+type PropertyOp string
+
+const (
+	PropertyEq  PropertyOp = "eq"
+	PropertyGt  PropertyOp = "gt"
+	PropertyGte PropertyOp = "gte"
+	PropertyLt  PropertyOp = "lt"
+	PropertyLte PropertyOp = "lte"
+)
+
+// PropertyFilter restricts a listing by a property value. Number values
+// compare numerically when Number is set; everything else compares as text,
+// which orders dates correctly.
+type PropertyFilter struct {
+	Key    string
+	Op     PropertyOp
+	Text   string
+	Number *float64
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// SearchQuery describes a full-text search over documents.
+type SearchQuery struct {
+	// ViewerID limits results to documents the user can view.
+	ViewerID string
+	// IDs, when not nil, limits results to these documents.
+	IDs           []string
+	Query         string
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Tags          []string
+	AllTags       bool
+	Properties    []PropertyFilter
+	Limit         int
+	Offset        int
+}
+
+// SearchResult is a document matched by a search, with highlighted snippets.
+// Highlights are HTML-escaped text where matches are wrapped in <mark>.
+type SearchResult struct {
+	Document
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"titleHighlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+// SearchPage is one page of search results.
+type SearchPage struct {
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ShareLink lets anyone holding its token open a document with a fixed
+// role, without being granted access by name.
+type ShareLink struct {
+	ID    string `json:"id"`
+	DocID string `json:"docId"`
+	Role  Role   `json:"role"`
+	// Token is the signed link token. It is derived from the ID and only
+	// shown to the document's owners.
+	Token        string     `json:"token,omitempty"`
+	PasswordHash string     `json:"-"`
+	HasPassword  bool       `json:"hasPassword"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	// MaxUses caps how many times the link can be opened; nil is unlimited.
+	MaxUses   *int       `json:"maxUses,omitempty"`
+	UseCount  int        `json:"useCount"`
+	CreatedBy *string    `json:"createdBy,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// ShareLinkAccess is a log entry for an attempt to use a share link.
+type ShareLinkAccess struct {
+	ID     string `json:"id"`
+	LinkID string `json:"linkId"`
+	// Action is "open" for redeeming the link, or the request made with the
+	// share session it issued.
+	Action    string    `json:"action"`
+	Outcome   string    `json:"outcome"`
+	UserID    *string   `json:"userId,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Share link access outcomes.
+const (
+	ShareOutcomeGranted     = "granted"
+	ShareOutcomeBadPassword = "bad_password"
+	ShareOutcomeRevoked     = "revoked"
+	ShareOutcomeExpired     = "expired"
+	ShareOutcomeExhausted   = "exhausted"
+)
+
+// GuestName is shown as the author of comments and presence of share link
+// holders who are not signed in.
+const GuestName = "Guest"
+
+// ShareAccess is the access a request holds through an opened share link.
+type ShareAccess struct {
+	LinkID string
+	DocID  string
+	Role   Role
+}
+
+// Principal identifies connections made through the link, so they can be
+// closed when it is revoked.
+func (a ShareAccess) Principal() string {
+	return "share:" + a.LinkID
+}
+
+type shareAccessKey struct{}
+
+// WithShareAccess returns a context carrying share link access.
+func WithShareAccess(ctx context.Context, access ShareAccess) context.Context {
+	return context.WithValue(ctx, shareAccessKey{}, access)
+}
+
+// ShareAccessFromContext returns the request's share link access, if any.
+func ShareAccessFromContext(ctx context.Context) (ShareAccess, bool) {
+	access, ok := ctx.Value(shareAccessKey{}).(ShareAccess)
+	return access, ok
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// Suggestion states. Open suggestions await a decision; accepted and
+// rejected ones are kept as a record of it.
+const (
+	SuggestionOpen     = "open"
+	SuggestionAccepted = "accepted"
+	SuggestionRejected = "rejected"
+)
+
+// Suggestion is a change proposed to a document instead of made to it, like
+// a tracked change. Update is the Yjs update that makes the change and
+// FromPos and ToPos the range it applies to.
+type Suggestion struct {
+	ID          string  `json:"id"`
+	DocID       string  `json:"docId"`
+	AuthorID    *string `json:"authorId"`
+	AuthorName  string  `json:"authorName"`
+	Description string  `json:"description"`
+	FromPos     int     `json:"fromPos"`
+	ToPos       int     `json:"toPos"`
+	Update      []byte  `json:"update"`
+	Status      string  `json:"status"`
+	// DecidedBy is who accepted or rejected the suggestion; it is nil for
+	// guests, whose names are kept.
+	DecidedBy     *string    `json:"decidedBy"`
+	DecidedByName string     `json:"decidedByName,omitempty"`
+	DecidedAt     *time.Time `json:"decidedAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// SuggestionDecision accepts or rejects an open suggestion.
+type SuggestionDecision struct {
+	Status string
+	// ActorID is nil for guests.
+	ActorID   *string
+	Actor     string
+	DecidedAt time.Time
+}
@@ -0,0 +1,7 @@
+package domain
+
+// Tag is a free-form label with the number of live documents carrying it.
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types.
+const (
+	WebhookDocCreated      = "doc.created"
+	WebhookDocRenamed      = "doc.renamed"
+	WebhookDocDeleted      = "doc.deleted"
+	WebhookCommentCreated  = "comment.created"
+	WebhookCommentResolved = "comment.resolved"
+	WebhookSnapshotUpdated = "snapshot.updated"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{WebhookDocCreated, WebhookDocRenamed, WebhookDocDeleted, WebhookCommentCreated, WebhookCommentResolved, WebhookSnapshotUpdated}
+
+// Delivery states. Pending deliveries are retried until they succeed or run
+// out of attempts.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook subscribes a URL to events of the documents its user can view,
+// or of a single document when DocID is set. Payloads are signed with the
+// secret, which is only shown when the webhook is created.
+type Webhook struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	DocID     *string   `json:"docId"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookEvent is the payload posted to subscribers.
+type WebhookEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	DocID     string    `json:"docId"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// WebhookDelivery is an entry in a webhook's delivery log: one event sent to
+// it, with the outcome of the latest attempt.
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhookId"`
+	EventID        string          `json:"eventId"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode *int            `json:"lastStatusCode"`
+	LastError      string          `json:"lastError,omitempty"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt"`
+	// RedeliveryOf is the delivery this one repeats.
+	RedeliveryOf *string   `json:"redeliveryOf,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// DueDelivery is a pending delivery with where to send it.
+type DueDelivery struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// WebhookAttempt is the outcome of sending a delivery. StatusCode is nil
+// when no response was received.
+type WebhookAttempt struct {
+	StatusCode *int
+	Error      string
+	Status     string
+	// NextAttemptAt is when a pending delivery is retried.
+	NextAttemptAt *time.Time
+	At            time.Time
+}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS docs_updated_at_idx;
+DROP INDEX IF EXISTS docs_search_vector_idx;
+ALTER TABLE docs DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE docs DROP COLUMN IF EXISTS content_text;
@@ -0,0 +1,9 @@
+ALTER TABLE docs ADD COLUMN IF NOT EXISTS content_text TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE docs ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('simple', title), 'A') ||
+  setweight(to_tsvector('simple', content_text), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS docs_search_vector_idx ON docs USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS docs_updated_at_idx ON docs (updated_at);
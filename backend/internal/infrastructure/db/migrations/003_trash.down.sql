@@ -0,0 +1,3 @@
+DELETE FROM docs WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS docs_deleted_at_idx;
+ALTER TABLE docs DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE docs ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS docs_deleted_at_idx ON docs (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS docs_forked_from_idx;
+ALTER TABLE docs DROP COLUMN IF EXISTS forked_from;
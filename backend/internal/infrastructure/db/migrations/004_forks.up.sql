@@ -0,0 +1,3 @@
+ALTER TABLE docs ADD COLUMN IF NOT EXISTS forked_from UUID REFERENCES docs(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS docs_forked_from_idx ON docs (forked_from) WHERE forked_from IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS docs_is_template_idx;
+ALTER TABLE docs DROP COLUMN IF EXISTS is_template;
@@ -0,0 +1,3 @@
+ALTER TABLE docs ADD COLUMN IF NOT EXISTS is_template BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS docs_is_template_idx ON docs (is_template) WHERE is_template;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS docs_folder_id_idx;
+ALTER TABLE docs DROP COLUMN IF EXISTS folder_id;
+DROP TABLE IF EXISTS folders;
+DROP TABLE IF EXISTS workspaces;
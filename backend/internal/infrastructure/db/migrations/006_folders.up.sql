@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS workspaces (
+  id UUID PRIMARY KEY,
+  name TEXT NOT NULL,
+  is_default BOOLEAN NOT NULL DEFAULT false,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS workspaces_default_idx ON workspaces (is_default) WHERE is_default;
+
+-- Every workspace has exactly one root folder (parent_id IS NULL).
+CREATE TABLE IF NOT EXISTS folders (
+  id UUID PRIMARY KEY,
+  workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  parent_id UUID REFERENCES folders(id) ON DELETE RESTRICT,
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS folders_root_idx ON folders (workspace_id) WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS folders_parent_id_idx ON folders (parent_id);
+
+INSERT INTO workspaces (id, name, is_default, created_at, updated_at)
+SELECT uuid_generate_v4(), 'Default', true, NOW(), NOW()
+WHERE NOT EXISTS (SELECT 1 FROM workspaces WHERE is_default);
+
+INSERT INTO folders (id, workspace_id, parent_id, name, created_at, updated_at)
+SELECT uuid_generate_v4(), w.id, NULL, w.name, NOW(), NOW()
+FROM workspaces w
+WHERE w.is_default AND NOT EXISTS (SELECT 1 FROM folders f WHERE f.workspace_id = w.id AND f.parent_id IS NULL);
+
+ALTER TABLE docs ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES folders(id) ON DELETE RESTRICT;
+
+UPDATE docs SET folder_id = (
+  SELECT f.id FROM folders f JOIN workspaces w ON w.id = f.workspace_id
+  WHERE w.is_default AND f.parent_id IS NULL
+)
+WHERE folder_id IS NULL;
+
+ALTER TABLE docs ALTER COLUMN folder_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS docs_folder_id_idx ON docs (folder_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS tags (
+  id UUID PRIMARY KEY,
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tags_name_idx ON tags (lower(name));
+
+CREATE TABLE IF NOT EXISTS doc_tags (
+  doc_id UUID NOT NULL REFERENCES docs(id) ON DELETE CASCADE,
+  tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (doc_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS doc_tags_tag_id_idx ON doc_tags (tag_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS docs_properties_idx;
+ALTER TABLE docs DROP COLUMN IF EXISTS properties;
+DROP TABLE IF EXISTS property_definitions;
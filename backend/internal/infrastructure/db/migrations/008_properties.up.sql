@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS property_definitions (
+  id UUID PRIMARY KEY,
+  workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  key TEXT NOT NULL,
+  name TEXT NOT NULL,
+  type TEXT NOT NULL CHECK (type IN ('string', 'number', 'date', 'enum', 'user')),
+  options TEXT[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+  UNIQUE (workspace_id, key)
+);
+
+ALTER TABLE docs ADD COLUMN IF NOT EXISTS properties JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS docs_properties_idx ON docs USING GIN (properties);
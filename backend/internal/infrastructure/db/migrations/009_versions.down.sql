@@ -0,0 +1,2 @@
+ALTER TABLE doc_comments DROP COLUMN IF EXISTS version;
+ALTER TABLE docs DROP COLUMN IF EXISTS version;
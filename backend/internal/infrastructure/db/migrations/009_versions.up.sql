@@ -0,0 +1,2 @@
+ALTER TABLE docs ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE doc_comments ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
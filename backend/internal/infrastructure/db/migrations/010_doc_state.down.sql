@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS doc_state_events;
+ALTER TABLE docs DROP COLUMN IF EXISTS allow_comments;
+ALTER TABLE docs DROP COLUMN IF EXISTS state;
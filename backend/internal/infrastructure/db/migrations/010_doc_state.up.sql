@@ -0,0 +1,17 @@
+ALTER TABLE docs
+  ADD COLUMN IF NOT EXISTS state TEXT NOT NULL DEFAULT 'active'
+    CHECK (state IN ('active', 'locked', 'archived')),
+  ADD COLUMN IF NOT EXISTS allow_comments BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE TABLE IF NOT EXISTS doc_state_events (
+  id UUID PRIMARY KEY,
+  doc_id UUID NOT NULL REFERENCES docs(id) ON DELETE CASCADE,
+  from_state TEXT NOT NULL,
+  to_state TEXT NOT NULL,
+  allow_comments BOOLEAN NOT NULL,
+  actor TEXT NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS doc_state_events_doc_id_idx ON doc_state_events (doc_id, created_at DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE doc_state_events DROP COLUMN IF EXISTS actor_id;
+ALTER TABLE doc_comments DROP COLUMN IF EXISTS author_id;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS groups (
+  id UUID PRIMARY KEY,
+  name TEXT NOT NULL,
+  owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+  group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS group_members_user_id_idx ON group_members (user_id);
+
+CREATE TABLE IF NOT EXISTS doc_acl (
+  id UUID PRIMARY KEY,
+  doc_id UUID NOT NULL REFERENCES docs(id) ON DELETE CASCADE,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  group_id UUID REFERENCES groups(id) ON DELETE CASCADE,
+  role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'commenter', 'viewer')),
+  granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  CHECK ((user_id IS NULL) <> (group_id IS NULL)),
+  CHECK (role <> 'owner' OR user_id IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS doc_acl_user_idx ON doc_acl (doc_id, user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS doc_acl_group_idx ON doc_acl (doc_id, group_id) WHERE group_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS doc_acl_user_id_idx ON doc_acl (user_id);
+CREATE INDEX IF NOT EXISTS doc_acl_group_id_idx ON doc_acl (group_id);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS share_links (
+  id UUID PRIMARY KEY,
+  doc_id UUID NOT NULL REFERENCES docs(id) ON DELETE CASCADE,
+  role TEXT NOT NULL CHECK (role IN ('editor', 'commenter', 'viewer')),
+  password_hash TEXT,
+  expires_at TIMESTAMPTZ,
+  max_uses INT CHECK (max_uses > 0),
+  use_count INT NOT NULL DEFAULT 0,
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS share_links_doc_id_idx ON share_links (doc_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS share_link_access (
+  id UUID PRIMARY KEY,
+  link_id UUID NOT NULL REFERENCES share_links(id) ON DELETE CASCADE,
+  action TEXT NOT NULL,
+  outcome TEXT NOT NULL,
+  user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  ip TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS share_link_access_link_id_idx ON share_link_access (link_id, created_at DESC);
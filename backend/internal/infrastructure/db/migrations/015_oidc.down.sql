@@ -0,0 +1,6 @@
+DELETE FROM groups WHERE owner_id IS NULL;
+DROP INDEX IF EXISTS groups_external_id_idx;
+ALTER TABLE groups DROP COLUMN IF EXISTS external_id;
+ALTER TABLE groups ALTER COLUMN owner_id SET NOT NULL;
+
+DROP TABLE IF EXISTS user_identities;
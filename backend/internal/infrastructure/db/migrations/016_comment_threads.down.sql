@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS doc_comments_parent_id_idx;
+DELETE FROM doc_comments WHERE parent_id IS NOT NULL;
+ALTER TABLE doc_comments DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE doc_comments DROP COLUMN IF EXISTS parent_id;
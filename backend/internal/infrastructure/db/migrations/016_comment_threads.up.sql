@@ -0,0 +1,6 @@
+ALTER TABLE doc_comments ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES doc_comments(id) ON DELETE CASCADE;
+ALTER TABLE doc_comments ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE doc_comments SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE doc_comments ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS doc_comments_parent_id_idx ON doc_comments (parent_id, created_at);
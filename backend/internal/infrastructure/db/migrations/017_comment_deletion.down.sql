@@ -0,0 +1,3 @@
+DELETE FROM doc_comments WHERE deleted_at IS NOT NULL;
+ALTER TABLE doc_comments DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE doc_comments DROP COLUMN IF EXISTS deleted_at;
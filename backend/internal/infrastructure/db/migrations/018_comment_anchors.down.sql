@@ -0,0 +1,3 @@
+ALTER TABLE doc_comments DROP COLUMN IF EXISTS orphaned;
+ALTER TABLE doc_comments DROP COLUMN IF EXISTS to_anchor;
+ALTER TABLE doc_comments DROP COLUMN IF EXISTS from_anchor;
@@ -0,0 +1,3 @@
+ALTER TABLE doc_comments ADD COLUMN IF NOT EXISTS from_anchor BYTEA;
+ALTER TABLE doc_comments ADD COLUMN IF NOT EXISTS to_anchor BYTEA;
+ALTER TABLE doc_comments ADD COLUMN IF NOT EXISTS orphaned BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS notifications (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  type TEXT NOT NULL,
+  doc_id UUID NOT NULL REFERENCES docs(id) ON DELETE CASCADE,
+  comment_id UUID NOT NULL REFERENCES doc_comments(id) ON DELETE CASCADE,
+  actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  actor_name TEXT NOT NULL,
+  excerpt TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  read_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_once_idx ON notifications (user_id, comment_id, type);
+CREATE INDEX IF NOT EXISTS notifications_user_idx ON notifications (user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS comment_reactions (
+  comment_id UUID NOT NULL REFERENCES doc_comments(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  emoji TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (comment_id, user_id, emoji)
+);
+
+CREATE INDEX IF NOT EXISTS comment_reactions_comment_idx ON comment_reactions (comment_id, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS doc_comments_roots_idx;
+DROP INDEX IF EXISTS doc_comments_search_vector_idx;
+ALTER TABLE doc_comments DROP COLUMN IF EXISTS search_vector;
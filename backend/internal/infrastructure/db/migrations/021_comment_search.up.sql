@@ -0,0 +1,6 @@
+ALTER TABLE doc_comments ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+  to_tsvector('simple', text)
+) STORED;
+
+CREATE INDEX IF NOT EXISTS doc_comments_search_vector_idx ON doc_comments USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS doc_comments_roots_idx ON doc_comments (doc_id, created_at DESC, id DESC) WHERE parent_id IS NULL;
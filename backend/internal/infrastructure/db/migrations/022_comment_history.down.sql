@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS comment_revisions;
+ALTER TABLE doc_comments
+  DROP COLUMN IF EXISTS reopened_at,
+  DROP COLUMN IF EXISTS reopened_by_name,
+  DROP COLUMN IF EXISTS reopened_by,
+  DROP COLUMN IF EXISTS resolved_at,
+  DROP COLUMN IF EXISTS resolved_by_name,
+  DROP COLUMN IF EXISTS resolved_by;
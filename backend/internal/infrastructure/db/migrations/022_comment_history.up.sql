@@ -0,0 +1,19 @@
+ALTER TABLE doc_comments
+  ADD COLUMN IF NOT EXISTS resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS resolved_by_name TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS reopened_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS reopened_by_name TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS reopened_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS comment_revisions (
+  id UUID PRIMARY KEY,
+  comment_id UUID NOT NULL REFERENCES doc_comments(id) ON DELETE CASCADE,
+  previous_text TEXT NOT NULL,
+  text TEXT NOT NULL,
+  edited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  edited_by_name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS comment_revisions_comment_idx ON comment_revisions (comment_id, created_at);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS doc_suggestions (
+  id UUID PRIMARY KEY,
+  doc_id UUID NOT NULL REFERENCES docs(id) ON DELETE CASCADE,
+  author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  author_name TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  from_pos INT NOT NULL,
+  to_pos INT NOT NULL,
+  update BYTEA NOT NULL,
+  status TEXT NOT NULL DEFAULT 'open',
+  decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  decided_by_name TEXT NOT NULL DEFAULT '',
+  decided_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS doc_suggestions_doc_idx ON doc_suggestions (doc_id, status, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS workspace_members;
+ALTER TABLE workspaces DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,34 @@
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS owner_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- The default workspace is open to every signed-in user; other workspaces
+-- only to their members.
+CREATE TABLE IF NOT EXISTS workspace_members (
+  workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS workspace_members_user_id_idx ON workspace_members (user_id);
+
+-- Existing workspaces are handed to the owners of the documents filed in
+-- them, the earliest grant becoming the workspace owner.
+INSERT INTO workspace_members (workspace_id, user_id, created_at)
+SELECT DISTINCT f.workspace_id, a.user_id, NOW()
+FROM doc_acl a
+JOIN docs d ON d.id = a.doc_id
+JOIN folders f ON f.id = d.folder_id
+JOIN workspaces w ON w.id = f.workspace_id
+WHERE a.role = 'owner' AND NOT w.is_default
+ON CONFLICT DO NOTHING;
+
+UPDATE workspaces w SET owner_id = (
+  SELECT a.user_id
+  FROM doc_acl a
+  JOIN docs d ON d.id = a.doc_id
+  JOIN folders f ON f.id = d.folder_id
+  WHERE f.workspace_id = w.id AND a.role = 'owner'
+  ORDER BY a.created_at, a.id
+  LIMIT 1
+)
+WHERE owner_id IS NULL AND NOT is_default;
@@ -7,19 +7,25 @@ import (
 )
 
 type WSClient struct {
-	id   string
-	conn *websocket.Conn
-	mu   sync.Mutex
+	id        string
+	principal string
+	conn      *websocket.Conn
+	mu        sync.Mutex
 }
 
-func NewWSClient(id string, conn *websocket.Conn) *WSClient {
-	return &WSClient{id: id, conn: conn}
+func NewWSClient(id string, principal string, conn *websocket.Conn) *WSClient {
+	return &WSClient{id: id, principal: principal, conn: conn}
 }
 
 func (c *WSClient) ID() string {
 	return c.id
 }
 
+// Principal is the user, or share link, the connection was authorized for.
+func (c *WSClient) Principal() string {
+	return c.principal
+}
+
 func (c *WSClient) Send(messageType int, payload []byte) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -1,20 +1,25 @@
 package hub
 
 import (
+	"encoding/json"
 	"sync"
 
 	"collabdocs/internal/app/ports"
+	"github.com/gorilla/websocket"
 )
 
 type Hub struct {
 	mu    sync.RWMutex
 	rooms map[string]*Room
+	// users holds the per-user notification rooms, keyed by user ID.
+	users map[string]*Room
 	quit  chan struct{}
 }
 
 func NewHub() *Hub {
 	return &Hub{
 		rooms: make(map[string]*Room),
+		users: make(map[string]*Room),
 		quit:  make(chan struct{}),
 	}
 }
@@ -28,6 +33,9 @@ func (h *Hub) Shutdown() {
 	for _, room := range h.rooms {
 		room.Close()
 	}
+	for _, room := range h.users {
+		room.Close()
+	}
 }
 
 func (h *Hub) GetRoom(docID string) ports.Room {
@@ -42,4 +50,75 @@ func (h *Hub) GetRoom(docID string) ports.Room {
 	return room
 }
 
+func (h *Hub) UserRoom(userID string) ports.Room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	room, ok := h.users[userID]
+	if !ok {
+		room = NewRoom(userID)
+		h.users[userID] = room
+		go room.Run()
+	}
+	return room
+}
+
+// SendToUser sends a server event to every connection on the user's
+// channel. Users who are not connected are skipped.
+func (h *Hub) SendToUser(userID string, payload any) {
+	h.mu.RLock()
+	room, ok := h.users[userID]
+	h.mu.RUnlock()
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	room.Broadcast("", websocket.TextMessage, data)
+}
+
+// BroadcastJSON sends a server event to every client in the document's
+// room. Documents nobody has open are skipped.
+func (h *Hub) BroadcastJSON(docID string, payload any) {
+	h.mu.RLock()
+	room, ok := h.rooms[docID]
+	h.mu.RUnlock()
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	room.Broadcast("", websocket.TextMessage, data)
+}
+
+// BroadcastUpdate sends a Yjs update to every client in the document's
+// room. Documents nobody has open are skipped; they load the update with the
+// next snapshot.
+func (h *Hub) BroadcastUpdate(docID string, update []byte) {
+	h.mu.RLock()
+	room, ok := h.rooms[docID]
+	h.mu.RUnlock()
+	if !ok {
+		return
+	}
+	room.Broadcast("", websocket.BinaryMessage, update)
+}
+
+// Disconnect closes the principal's connections to the document's room, if
+// it is open.
+func (h *Hub) Disconnect(docID string, principal string) {
+	h.mu.RLock()
+	room, ok := h.rooms[docID]
+	h.mu.RUnlock()
+	if !ok {
+		return
+	}
+	room.Disconnect(principal)
+}
+
 var _ ports.Hub = (*Hub)(nil)
+var _ ports.Realtime = (*Hub)(nil)
+var _ ports.UserRealtime = (*Hub)(nil)
@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"collabdocs/internal/app/ports"
+	"github.com/gorilla/websocket"
 )
 
 type broadcastMessage struct {
@@ -17,6 +18,7 @@ type Room struct {
 	clients   map[string]ports.Client
 	register  chan ports.Client
 	unregister chan string
+	disconnect chan string
 	broadcast chan broadcastMessage
 	closed    chan struct{}
 	mu        sync.RWMutex
@@ -28,6 +30,7 @@ func NewRoom(id string) *Room {
 		clients:   make(map[string]ports.Client),
 		register:  make(chan ports.Client),
 		unregister: make(chan string),
+		disconnect: make(chan string),
 		broadcast: make(chan broadcastMessage, 256),
 		closed:    make(chan struct{}),
 	}
@@ -47,6 +50,18 @@ func (r *Room) Run() {
 				delete(r.clients, clientID)
 			}
 			r.mu.Unlock()
+		case principal := <-r.disconnect:
+			// Closing the connection ends the client's read loop, which
+			// then unregisters it.
+			r.mu.RLock()
+			for _, client := range r.clients {
+				if client.Principal() != principal {
+					continue
+				}
+				_ = client.Send(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "access revoked"))
+				_ = client.Close()
+			}
+			r.mu.RUnlock()
 		case msg := <-r.broadcast:
 			r.mu.RLock()
 			for id, client := range r.clients {
@@ -78,6 +93,14 @@ func (r *Room) Unregister(clientID string) {
 	r.unregister <- clientID
 }
 
+// Disconnect closes every connection of the principal in this room.
+func (r *Room) Disconnect(principal string) {
+	select {
+	case r.disconnect <- principal:
+	case <-r.closed:
+	}
+}
+
 func (r *Room) Close() {
 	close(r.closed)
 }
@@ -0,0 +1,264 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"collabdocs/internal/domain"
+)
+
+// clockSkew is tolerated between our clock and the provider's.
+const clockSkew = time.Minute
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// audience accepts the aud claim as a single string or a list.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(v string) bool {
+	for _, s := range a {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// flexBool accepts booleans sent as strings, which some providers do for
+// email_verified.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	var v bool
+	if err := json.Unmarshal(data, &v); err == nil {
+		*b = flexBool(v)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*b = flexBool(s == "true")
+	return nil
+}
+
+type tokenClaims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	Expiry            *int64   `json:"exp"`
+	IssuedAt          *int64   `json:"iat"`
+	NotBefore         *int64   `json:"nbf"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+func invalidToken(format string, args ...any) error {
+	return fmt.Errorf("%w: id token: %s", domain.ErrUnauthorized, fmt.Sprintf(format, args...))
+}
+
+// verify checks the ID token's signature and claims and maps them to
+// identity claims.
+func (p *Provider) verify(ctx context.Context, meta *metadata, raw string, nonce string) (domain.IdentityClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return domain.IdentityClaims{}, invalidToken("malformed")
+	}
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return domain.IdentityClaims{}, invalidToken("header: %v", err)
+	}
+	hash, ok := algHash(header.Alg)
+	if !ok {
+		return domain.IdentityClaims{}, invalidToken("unsupported alg %q", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return domain.IdentityClaims{}, invalidToken("signature encoding")
+	}
+	key, err := p.keys.key(ctx, header.Kid, header.Alg)
+	if errors.Is(err, errUnknownKey) {
+		return domain.IdentityClaims{}, invalidToken("%v", err)
+	}
+	if err != nil {
+		return domain.IdentityClaims{}, err
+	}
+	if !verifySignature(key, header.Alg, hash, parts[0]+"."+parts[1], sig) {
+		return domain.IdentityClaims{}, invalidToken("bad signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return domain.IdentityClaims{}, invalidToken("payload encoding")
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return domain.IdentityClaims{}, invalidToken("claims: %v", err)
+	}
+	if err := p.checkClaims(meta, claims, nonce); err != nil {
+		return domain.IdentityClaims{}, err
+	}
+
+	out := domain.IdentityClaims{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         strings.TrimSpace(claims.Email),
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          strings.TrimSpace(claims.Name),
+	}
+	if out.Name == "" {
+		out.Name = strings.TrimSpace(claims.PreferredUsername)
+	}
+	if p.cfg.GroupsClaim != "" {
+		groups, err := groupsClaim(payload, p.cfg.GroupsClaim)
+		if err != nil {
+			return domain.IdentityClaims{}, invalidToken("%s claim: %v", p.cfg.GroupsClaim, err)
+		}
+		out.Groups = groups
+	}
+	return out, nil
+}
+
+func (p *Provider) checkClaims(meta *metadata, claims tokenClaims, nonce string) error {
+	now := p.now()
+	switch {
+	case claims.Issuer != meta.Issuer:
+		return invalidToken("issuer %q", claims.Issuer)
+	case claims.Subject == "":
+		return invalidToken("no subject")
+	case !claims.Audience.contains(p.cfg.ClientID):
+		return invalidToken("audience does not include client")
+	case len(claims.Audience) > 1 && claims.AuthorizedParty == "":
+		return invalidToken("no azp for multiple audiences")
+	case claims.AuthorizedParty != "" && claims.AuthorizedParty != p.cfg.ClientID:
+		return invalidToken("azp %q", claims.AuthorizedParty)
+	case claims.Expiry == nil || now.After(time.Unix(*claims.Expiry, 0).Add(clockSkew)):
+		return invalidToken("expired")
+	case claims.IssuedAt == nil || time.Unix(*claims.IssuedAt, 0).After(now.Add(clockSkew)):
+		return invalidToken("issued in the future")
+	case claims.NotBefore != nil && time.Unix(*claims.NotBefore, 0).After(now.Add(clockSkew)):
+		return invalidToken("not yet valid")
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return invalidToken("nonce mismatch")
+	}
+	return nil
+}
+
+// groupsClaim reads a list of group names. A missing claim means no groups;
+// a single string is one group.
+func groupsClaim(payload []byte, name string) ([]string, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &all); err != nil {
+		return nil, err
+	}
+	raw, ok := all[name]
+	if !ok || string(raw) == "null" {
+		return []string{}, nil
+	}
+	var groups []string
+	if err := json.Unmarshal(raw, &groups); err == nil {
+		return groups, nil
+	}
+	var one string
+	if err := json.Unmarshal(raw, &one); err != nil {
+		return nil, errors.New("not a list of strings")
+	}
+	return []string{one}, nil
+}
+
+func decodeSegment(seg string, out any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func algHash(alg string) (crypto.Hash, bool) {
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		return crypto.SHA256, true
+	case "RS384", "PS384", "ES384":
+		return crypto.SHA384, true
+	case "RS512", "PS512", "ES512":
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// keyFitsAlg reports whether the key type matches the algorithm family.
+func keyFitsAlg(key crypto.PublicKey, alg string) bool {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		switch alg {
+		case "ES256":
+			return key.Curve == elliptic.P256()
+		case "ES384":
+			return key.Curve == elliptic.P384()
+		case "ES512":
+			return key.Curve == elliptic.P521()
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func verifySignature(key crypto.PublicKey, alg string, hash crypto.Hash, signed string, sig []byte) bool {
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "PS") {
+			return rsa.VerifyPSS(pub, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, digest, sig) == nil
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(pub, digest, r, s)
+	default:
+		return false
+	}
+}
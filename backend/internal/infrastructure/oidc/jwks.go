@@ -0,0 +1,206 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval limits how often an unknown key ID makes the key set
+// be fetched again, so forged tokens cannot hammer the provider.
+const minRefreshInterval = 30 * time.Second
+
+var errUnknownKey = errors.New("no matching signing key")
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type publicKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// keySet caches the provider's signing keys. Keys are fetched again once the
+// TTL has passed, or earlier when a token names a key ID not in the cache,
+// which is how providers roll their keys.
+type keySet struct {
+	client *http.Client
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	uri       string
+	keys      []publicKey
+	fetchedAt time.Time
+}
+
+func newKeySet(client *http.Client, ttl time.Duration, now func() time.Time) *keySet {
+	return &keySet{client: client, ttl: ttl, now: now}
+}
+
+func (s *keySet) setURI(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if uri != s.uri {
+		s.uri = uri
+		s.keys = nil
+		s.fetchedAt = time.Time{}
+	}
+}
+
+// key returns the key that signed a token with the given header.
+func (s *keySet) key(ctx context.Context, kid string, alg string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	age := s.now().Sub(s.fetchedAt)
+	if s.keys == nil || age >= s.ttl {
+		if err := s.fetch(ctx); err != nil {
+			return nil, err
+		}
+	} else if _, ok := s.find(kid, alg); !ok && age >= minRefreshInterval {
+		if err := s.fetch(ctx); err != nil {
+			return nil, err
+		}
+	}
+	key, ok := s.find(kid, alg)
+	if !ok {
+		return nil, fmt.Errorf("%w: kid %q, alg %s", errUnknownKey, kid, alg)
+	}
+	return key, nil
+}
+
+// find matches by key ID, or takes the only usable key when the token does
+// not name one.
+func (s *keySet) find(kid string, alg string) (crypto.PublicKey, bool) {
+	var match *publicKey
+	for i, k := range s.keys {
+		if k.alg != "" && k.alg != alg {
+			continue
+		}
+		if !keyFitsAlg(k.key, alg) {
+			continue
+		}
+		if kid != "" {
+			if k.kid == kid {
+				return k.key, true
+			}
+			continue
+		}
+		if match != nil {
+			return nil, false
+		}
+		match = &s.keys[i]
+	}
+	if match == nil {
+		return nil, false
+	}
+	return match.key, true
+}
+
+func (s *keySet) fetch(ctx context.Context) error {
+	if s.uri == "" {
+		return errors.New("oidc: jwks_uri unknown")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.uri, nil)
+	if err != nil {
+		return err
+	}
+	var body struct {
+		Keys []jwk `json:"keys"`
+	}
+	status, err := doJSON(s.client, req, &body)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("oidc: jwks returned %d", status)
+	}
+
+	keys := make([]publicKey, 0, len(body.Keys))
+	for _, k := range body.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			// Keys of unsupported types are skipped, not fatal.
+			continue
+		}
+		keys = append(keys, publicKey{kid: k.Kid, alg: k.Alg, key: pub})
+	}
+	s.keys = keys
+	s.fetchedAt = s.now()
+	return nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 || e.Int64() < 3 {
+			return nil, errors.New("rsa exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("ec point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
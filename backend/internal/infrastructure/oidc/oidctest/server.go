@@ -0,0 +1,309 @@
+// Package oidctest runs a minimal OpenID provider for tests. It serves
+// discovery, a JWKS and a token endpoint that enforces PKCE, and signs ID
+// tokens with keys that can be rotated.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Key is a signing key of the provider.
+type Key struct {
+	ID      string
+	Alg     string
+	Private crypto.Signer
+}
+
+// NewRSAKey generates an RS256 key.
+func NewRSAKey(t testing.TB, id string) Key {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating rsa key: %v", err)
+	}
+	return Key{ID: id, Alg: "RS256", Private: private}
+}
+
+// NewECKey generates an ES256 key.
+func NewECKey(t testing.TB, id string) Key {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating ec key: %v", err)
+	}
+	return Key{ID: id, Alg: "ES256", Private: private}
+}
+
+type grant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	idToken     string
+}
+
+// Server is the provider. Its URL is the issuer.
+type Server struct {
+	*httptest.Server
+	ClientID string
+
+	t            testing.TB
+	mu           sync.Mutex
+	keys         []Key
+	codes        map[string]grant
+	jwksRequests int
+	nextCode     int
+}
+
+// NewServer starts a provider for clientID that signs with one RSA key. It is
+// closed when the test ends.
+func NewServer(t testing.TB, clientID string) *Server {
+	t.Helper()
+	s := &Server{
+		ClientID: clientID,
+		t:        t,
+		keys:     []Key{NewRSAKey(t, "key-1")},
+		codes:    make(map[string]grant),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	mux.HandleFunc("POST /token", s.token)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SigningKey returns the key ID tokens are signed with.
+func (s *Server) SigningKey() Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[0]
+}
+
+// SetKeys replaces the published keys. The first one signs new tokens.
+func (s *Server) SetKeys(keys ...Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+// RotateKey replaces the published keys with a new RSA key and returns it.
+func (s *Server) RotateKey(id string) Key {
+	key := NewRSAKey(s.t, id)
+	s.SetKeys(key)
+	return key
+}
+
+// JWKSRequests counts how often the key set was fetched.
+func (s *Server) JWKSRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jwksRequests
+}
+
+// Claims returns valid ID token claims for subject, issued now.
+func (s *Server) Claims(subject string, nonce string) map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":   s.URL,
+		"sub":   subject,
+		"aud":   s.ClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": nonce,
+	}
+}
+
+// IDToken signs claims with the current signing key.
+func (s *Server) IDToken(claims map[string]any) string {
+	key := s.SigningKey()
+	return Sign(s.t, key.Private, key.Alg, key.ID, claims)
+}
+
+// Authorize plays the browser's visit to authURL: it checks the request the
+// relying party built and returns a code that redeems idToken.
+func (s *Server) Authorize(authURL string, idToken string) string {
+	s.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		s.t.Fatalf("auth url: %v", err)
+	}
+	q := u.Query()
+	switch {
+	case u.Scheme+"://"+u.Host != s.URL || u.Path != "/authorize":
+		s.t.Fatalf("auth url %s is not this provider's", authURL)
+	case q.Get("response_type") != "code":
+		s.t.Fatalf("response_type %q", q.Get("response_type"))
+	case q.Get("client_id") != s.ClientID:
+		s.t.Fatalf("client_id %q", q.Get("client_id"))
+	case q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "":
+		s.t.Fatalf("no S256 code challenge in %s", authURL)
+	case q.Get("state") == "" || q.Get("nonce") == "":
+		s.t.Fatalf("no state or nonce in %s", authURL)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextCode++
+	code := fmt.Sprintf("code-%d", s.nextCode)
+	s.codes[code] = grant{
+		clientID:    q.Get("client_id"),
+		redirectURI: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		idToken:     idToken,
+	}
+	return code
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.jwksRequests++
+	keys := make([]map[string]string, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, publicJWK(k))
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+// token redeems a code once. The verifier must match the challenge and the
+// client and redirect URI must match the authorization request.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	clientID := r.PostForm.Get("client_id")
+	if id, _, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(id)
+	}
+
+	s.mu.Lock()
+	g, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code":
+		tokenError(w, "unsupported_grant_type")
+	case !ok, clientID != g.clientID, r.PostForm.Get("redirect_uri") != g.redirectURI,
+		base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge:
+		tokenError(w, "invalid_grant")
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     g.idToken,
+		})
+	}
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Sign builds a compact JWS over claims. key may be an RSA key (RS* or PS*)
+// or an ECDSA key (ES*); the header carries alg and, if set, kid.
+func Sign(t testing.TB, key crypto.Signer, alg string, kid string, claims map[string]any) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+
+	hash := crypto.SHA256
+	switch alg[2:] {
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	var (
+		sig []byte
+		err error
+	)
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if alg[:2] == "PS" {
+			sig, err = rsa.SignPSS(rand.Reader, k, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+		}
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest)
+		if err == nil {
+			size := (k.Curve.Params().BitSize + 7) / 8
+			sig = make([]byte, 2*size)
+			r.FillBytes(sig[:size])
+			s.FillBytes(sig[size:])
+		}
+	default:
+		t.Fatalf("unsupported key %T", key)
+	}
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func encodeSegment(t testing.TB, v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("encoding token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func publicJWK(k Key) map[string]string {
+	jwk := map[string]string{"kid": k.ID, "alg": k.Alg, "use": "sig"}
+	switch pub := k.Private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk["kty"] = "EC"
+		jwk["crv"] = pub.Curve.Params().Name
+		jwk["x"] = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk["y"] = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	}
+	return jwk
+}
@@ -0,0 +1,201 @@
+// Package oidc is an OpenID Connect relying party for the authorization code
+// flow with PKCE. It discovers the provider, caches its signing keys and
+// validates ID tokens.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"collabdocs/internal/domain"
+)
+
+// maxResponseBytes bounds what is read from the provider.
+const maxResponseBytes = 1 << 20
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// GroupsClaim names the ID token claim listing the user's groups; empty
+	// disables group mapping.
+	GroupsClaim string
+	// CacheTTL is how long discovery and signing keys are cached.
+	CacheTTL time.Duration
+	// HTTPClient defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one OpenID provider. It is safe for concurrent use.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	meta      *metadata
+	fetchedAt time.Time
+	keys      *keySet
+}
+
+func NewProvider(cfg Config) *Provider {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = time.Hour
+	}
+	p := &Provider{cfg: cfg, client: client, now: time.Now}
+	p.keys = newKeySet(client, cfg.CacheTTL, p.now)
+	return p
+}
+
+// AuthCodeURL returns the provider URL the browser is sent to for signing in.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc: authorization endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange redeems an authorization code and returns the claims of the
+// validated ID token. Rejected codes and invalid tokens wrap
+// domain.ErrUnauthorized.
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (domain.IdentityClaims, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return domain.IdentityClaims{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return domain.IdentityClaims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var token tokenResponse
+	status, err := p.do(req, &token)
+	if err != nil {
+		return domain.IdentityClaims{}, err
+	}
+	if status != http.StatusOK {
+		if status == http.StatusBadRequest && token.Error == "invalid_grant" {
+			return domain.IdentityClaims{}, fmt.Errorf("%w: code rejected: %s", domain.ErrUnauthorized, token.ErrorDescription)
+		}
+		return domain.IdentityClaims{}, fmt.Errorf("oidc: token endpoint returned %d: %s %s", status, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return domain.IdentityClaims{}, fmt.Errorf("%w: no id_token in token response", domain.ErrUnauthorized)
+	}
+	return p.verify(ctx, meta, token.IDToken, nonce)
+}
+
+// discover returns the provider metadata, fetching it again once CacheTTL
+// has passed or after a failed fetch.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil && p.now().Sub(p.fetchedAt) < p.cfg.CacheTTL {
+		return p.meta, nil
+	}
+
+	endpoint := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	var meta metadata
+	status, err := p.do(req, &meta)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc: discovery returned %d", status)
+	}
+	if meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc: discovery issuer %q does not match %q", meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document is missing endpoints")
+	}
+	p.meta = &meta
+	p.fetchedAt = p.now()
+	p.keys.setURI(meta.JWKSURI)
+	return p.meta, nil
+}
+
+// do sends req and decodes a JSON body into out, whatever the status.
+func (p *Provider) do(req *http.Request, out any) (int, error) {
+	return doJSON(p.client, req, out)
+}
+
+func doJSON(client *http.Client, req *http.Request, out any) (int, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("oidc: %s %s: %w", req.Method, req.URL.Redacted(), err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseBytes))
+	if err != nil {
+		return 0, fmt.Errorf("oidc: reading %s: %w", req.URL.Redacted(), err)
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, out); err != nil && res.StatusCode == http.StatusOK {
+			return 0, fmt.Errorf("oidc: decoding %s: %w", req.URL.Redacted(), err)
+		}
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,261 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"collabdocs/internal/domain"
+	"collabdocs/internal/infrastructure/oidc/oidctest"
+)
+
+const (
+	testClientID = "collabdocs"
+	testVerifier = "verifier-0123456789-0123456789-0123456789"
+	testNonce    = "nonce-1"
+)
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Server) {
+	t.Helper()
+	idp := oidctest.NewServer(t, testClientID)
+	p := NewProvider(Config{
+		Issuer:      idp.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://app.test/auth/oidc/callback",
+		Scopes:      []string{"openid", "email", "profile"},
+		GroupsClaim: "groups",
+		HTTPClient:  idp.Client(),
+	})
+	return p, idp
+}
+
+// setClock makes the provider and its key set read the time from now.
+func setClock(p *Provider, now *time.Time) {
+	clock := func() time.Time { return *now }
+	p.now = clock
+	p.keys.now = clock
+}
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// authorize starts a sign-in and returns the code the provider issues for
+// idToken.
+func authorize(t *testing.T, p *Provider, idp *oidctest.Server, idToken string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", testNonce, challenge(testVerifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	return idp.Authorize(authURL, idToken)
+}
+
+func exchange(t *testing.T, p *Provider, idp *oidctest.Server, idToken string) (domain.IdentityClaims, error) {
+	t.Helper()
+	code := authorize(t, p, idp, idToken)
+	return p.Exchange(context.Background(), code, testVerifier, testNonce)
+}
+
+func encode(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("encoding: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	p, idp := newTestProvider(t)
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", testNonce, challenge(testVerifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", authURL, err)
+	}
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          "http://app.test/auth/oidc/callback",
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"nonce":                 testNonce,
+		"code_challenge":        challenge(testVerifier),
+		"code_challenge_method": "S256",
+	}
+	for k, v := range want {
+		if got := u.Query().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != idp.URL+"/authorize" {
+		t.Errorf("endpoint = %s, want %s/authorize", got, idp.URL)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	p, idp := newTestProvider(t)
+	claims := idp.Claims("alice-sub", testNonce)
+	claims["email"] = " alice@example.com "
+	claims["email_verified"] = "true"
+	claims["preferred_username"] = "alice"
+	claims["groups"] = []string{"eng", "docs"}
+
+	got, err := exchange(t, p, idp, idp.IDToken(claims))
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if got.Issuer != idp.URL || got.Subject != "alice-sub" {
+		t.Errorf("identity = %s %s, want %s alice-sub", got.Issuer, got.Subject, idp.URL)
+	}
+	if got.Email != "alice@example.com" || !got.EmailVerified {
+		t.Errorf("email = %q verified %v", got.Email, got.EmailVerified)
+	}
+	if got.Name != "alice" {
+		t.Errorf("name = %q, want preferred_username", got.Name)
+	}
+	if len(got.Groups) != 2 || got.Groups[0] != "eng" || got.Groups[1] != "docs" {
+		t.Errorf("groups = %v", got.Groups)
+	}
+}
+
+func TestExchangeES256(t *testing.T) {
+	p, idp := newTestProvider(t)
+	idp.SetKeys(oidctest.NewECKey(t, "ec-1"))
+	if _, err := exchange(t, p, idp, idp.IDToken(idp.Claims("alice-sub", testNonce))); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+}
+
+func TestExchangeRejectsCode(t *testing.T) {
+	p, idp := newTestProvider(t)
+	token := idp.IDToken(idp.Claims("alice-sub", testNonce))
+	ctx := context.Background()
+
+	code := authorize(t, p, idp, token)
+	if _, err := p.Exchange(ctx, code, "another-verifier", testNonce); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("wrong PKCE verifier: err = %v, want ErrUnauthorized", err)
+	}
+
+	code = authorize(t, p, idp, token)
+	if _, err := p.Exchange(ctx, code, testVerifier, testNonce); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := p.Exchange(ctx, code, testVerifier, testNonce); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("reused code: err = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestExchangeRejectsToken(t *testing.T) {
+	p, idp := newTestProvider(t)
+	signing := idp.SigningKey()
+	ecKey := oidctest.NewECKey(t, "ec-1")
+	idp.SetKeys(signing, ecKey)
+	now := time.Now()
+
+	claims := func(change func(map[string]any)) map[string]any {
+		c := idp.Claims("alice-sub", testNonce)
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+	signed := func(change func(map[string]any)) string {
+		return idp.IDToken(claims(change))
+	}
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"malformed", "not-a-token"},
+		{"alg none", encode(t, map[string]string{"alg": "none"}) + "." + encode(t, claims(nil)) + "."},
+		{"alg HS256", encode(t, map[string]string{"alg": "HS256", "kid": signing.ID}) + "." + encode(t, claims(nil)) + ".c2ln"},
+		{"alg of another key type", oidctest.Sign(t, ecKey.Private, "RS256", ecKey.ID, claims(nil))},
+		{"unknown kid", oidctest.Sign(t, signing.Private, "RS256", "key-unknown", claims(nil))},
+		{"signed by unpublished key", oidctest.Sign(t, oidctest.NewRSAKey(t, signing.ID).Private, "RS256", signing.ID, claims(nil))},
+		{"wrong issuer", signed(func(c map[string]any) { c["iss"] = "https://evil.example.com" })},
+		{"no subject", signed(func(c map[string]any) { delete(c, "sub") })},
+		{"wrong audience", signed(func(c map[string]any) { c["aud"] = "another-client" })},
+		{"multiple audiences without azp", signed(func(c map[string]any) { c["aud"] = []string{testClientID, "another-client"} })},
+		{"azp of another client", signed(func(c map[string]any) {
+			c["aud"] = []string{testClientID, "another-client"}
+			c["azp"] = "another-client"
+		})},
+		{"expired", signed(func(c map[string]any) { c["exp"] = now.Add(-2 * clockSkew).Unix() })},
+		{"no expiry", signed(func(c map[string]any) { delete(c, "exp") })},
+		{"issued in the future", signed(func(c map[string]any) { c["iat"] = now.Add(2 * clockSkew).Unix() })},
+		{"not yet valid", signed(func(c map[string]any) { c["nbf"] = now.Add(2 * clockSkew).Unix() })},
+		{"nonce mismatch", signed(func(c map[string]any) { c["nonce"] = "nonce-2" })},
+		{"no nonce", signed(func(c map[string]any) { delete(c, "nonce") })},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := exchange(t, p, idp, tt.token)
+			if !errors.Is(err, domain.ErrUnauthorized) {
+				t.Fatalf("err = %v, want ErrUnauthorized", err)
+			}
+		})
+	}
+
+	if _, err := exchange(t, p, idp, signed(func(c map[string]any) {
+		c["aud"] = []string{testClientID, "another-client"}
+		c["azp"] = testClientID
+	})); err != nil {
+		t.Errorf("multiple audiences with azp: %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	p, idp := newTestProvider(t)
+	now := time.Now()
+	setClock(p, &now)
+
+	if _, err := exchange(t, p, idp, idp.IDToken(idp.Claims("alice-sub", testNonce))); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if n := idp.JWKSRequests(); n != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", n)
+	}
+
+	idp.RotateKey("key-2")
+	rotated := idp.IDToken(idp.Claims("alice-sub", testNonce))
+
+	// Right after a fetch an unknown kid does not fetch the keys again.
+	if _, err := exchange(t, p, idp, rotated); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Fatalf("err = %v, want ErrUnauthorized", err)
+	}
+	if n := idp.JWKSRequests(); n != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", n)
+	}
+
+	now = now.Add(minRefreshInterval)
+	if _, err := exchange(t, p, idp, rotated); err != nil {
+		t.Fatalf("Exchange after rotation: %v", err)
+	}
+	if n := idp.JWKSRequests(); n != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", n)
+	}
+	if _, err := exchange(t, p, idp, rotated); err != nil {
+		t.Fatalf("Exchange with cached key: %v", err)
+	}
+	if n := idp.JWKSRequests(); n != 2 {
+		t.Errorf("jwks fetched %d times, want 2", n)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	idp := oidctest.NewServer(t, testClientID)
+	p := NewProvider(Config{Issuer: idp.URL + "/", ClientID: testClientID, HTTPClient: idp.Client()})
+	if _, err := p.AuthCodeURL(context.Background(), "state-1", testNonce, challenge(testVerifier)); err == nil {
+		t.Fatal("AuthCodeURL succeeded with a mismatched issuer")
+	}
+}
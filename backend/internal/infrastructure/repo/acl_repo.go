@@ -0,0 +1,230 @@
+package repo
+
+import (
+	"context"
+
+	"collabdocs/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// grantColumns selects a grant joined with the name of its user or group.
+const grantColumns = `
+SELECT a.id, a.doc_id, a.user_id, a.group_id, COALESCE(u.name, g.name, ''), COALESCE(u.email, ''), a.role, a.granted_by, a.created_at
+FROM doc_acl a
+LEFT JOIN users u ON u.id = a.user_id
+LEFT JOIN groups g ON g.id = a.group_id`
+
+// roleRank orders roles from viewer (1) to owner (4) in SQL.
+const roleRank = `CASE role WHEN 'owner' THEN 4 WHEN 'editor' THEN 3 WHEN 'commenter' THEN 2 ELSE 1 END`
+
+type ACLRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewACLRepo(pool *pgxpool.Pool) *ACLRepo {
+	return &ACLRepo{pool: pool}
+}
+
+// userGrants matches the doc_acl rows that apply to the user in the given
+// placeholder, directly or through a group.
+func userGrants(param string) string {
+	return `(user_id = ` + param + ` OR group_id IN (SELECT group_id FROM group_members WHERE user_id = ` + param + `))`
+}
+
+// accessCondition matches documents the user holds any role on.
+func accessCondition(f *sqlFilter, userID string) string {
+	return `docs.id IN (SELECT doc_id FROM doc_acl WHERE ` + userGrants(f.arg(userID)+`::uuid`) + `)`
+}
+
+func scanGrant(row pgx.Row) (domain.Grant, error) {
+	var out domain.Grant
+	if err := row.Scan(&out.ID, &out.DocID, &out.UserID, &out.GroupID, &out.Name, &out.Email, &out.Role, &out.GrantedBy, &out.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Grant{}, domain.ErrNotFound
+		}
+		return domain.Grant{}, err
+	}
+	return out, nil
+}
+
+// grantOwner makes the user owner of a document created in tx.
+func grantOwner(ctx context.Context, tx pgx.Tx, docID string, userID string) error {
+	const q = `
+INSERT INTO doc_acl (id, doc_id, user_id, role, granted_by, created_at)
+VALUES (uuid_generate_v4(), $1, $2, 'owner', $2, NOW())`
+	_, err := tx.Exec(ctx, q, docID, userID)
+	return err
+}
+
+// Role returns the highest role the user holds on the document, directly or
+// through groups, or the empty role when they have no access.
+func (r *ACLRepo) Role(ctx context.Context, docID string, userID string) (domain.Role, error) {
+	q := `
+SELECT role FROM doc_acl
+WHERE doc_id = $1 AND ` + userGrants("$2") + `
+ORDER BY ` + roleRank + ` DESC
+LIMIT 1`
+
+	var role domain.Role
+	err := r.pool.QueryRow(ctx, q, docID, userID).Scan(&role)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+func (r *ACLRepo) ListGrants(ctx context.Context, docID string) ([]domain.Grant, error) {
+	const q = grantColumns + `
+WHERE a.doc_id = $1
+ORDER BY ` + roleRank + ` DESC, a.created_at ASC`
+
+	rows, err := r.pool.Query(ctx, q, docID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := make([]domain.Grant, 0)
+	for rows.Next() {
+		g, err := scanGrant(rows)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+func (r *ACLRepo) GetGrant(ctx context.Context, docID string, grantID string) (domain.Grant, error) {
+	return scanGrant(r.pool.QueryRow(ctx, grantColumns+` WHERE a.doc_id = $1 AND a.id = $2`, docID, grantID))
+}
+
+// Upsert grants a role to the grant's user or group, replacing the role they
+// already held on the document. Demoting the last owner returns ErrConflict.
+func (r *ACLRepo) Upsert(ctx context.Context, grant domain.Grant) (domain.Grant, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Grant{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockACL(ctx, tx, grant.DocID); err != nil {
+		return domain.Grant{}, err
+	}
+
+	target := `(doc_id, user_id) WHERE user_id IS NOT NULL`
+	if grant.GroupID != nil {
+		target = `(doc_id, group_id) WHERE group_id IS NOT NULL`
+	}
+	q := `
+INSERT INTO doc_acl (id, doc_id, user_id, group_id, role, granted_by, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT ` + target + ` DO UPDATE SET role = EXCLUDED.role, granted_by = EXCLUDED.granted_by
+RETURNING id`
+
+	var id string
+	err = tx.QueryRow(ctx, q, grant.ID, grant.DocID, grant.UserID, grant.GroupID, grant.Role, grant.GrantedBy, grant.CreatedAt).Scan(&id)
+	if err != nil {
+		return domain.Grant{}, err
+	}
+	if err := ensureOwner(ctx, tx, grant.DocID); err != nil {
+		return domain.Grant{}, err
+	}
+	out, err := scanGrant(tx.QueryRow(ctx, grantColumns+` WHERE a.id = $1`, id))
+	if err != nil {
+		return domain.Grant{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Grant{}, err
+	}
+	return out, nil
+}
+
+// Delete revokes a grant and returns it. Removing the last owner returns
+// ErrConflict.
+func (r *ACLRepo) Delete(ctx context.Context, docID string, grantID string) (domain.Grant, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Grant{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockACL(ctx, tx, docID); err != nil {
+		return domain.Grant{}, err
+	}
+	out, err := scanGrant(tx.QueryRow(ctx, grantColumns+` WHERE a.doc_id = $1 AND a.id = $2`, docID, grantID))
+	if err != nil {
+		return domain.Grant{}, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM doc_acl WHERE id = $1`, grantID); err != nil {
+		return domain.Grant{}, err
+	}
+	if err := ensureOwner(ctx, tx, docID); err != nil {
+		return domain.Grant{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Grant{}, err
+	}
+	return out, nil
+}
+
+// ListGroupDocIDs returns the documents the group has been granted access to.
+func (r *ACLRepo) ListGroupDocIDs(ctx context.Context, groupID string) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `SELECT doc_id FROM doc_acl WHERE group_id = $1`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// lockACL serializes access changes on a live document so the last-owner
+// check cannot race.
+func lockACL(ctx context.Context, tx pgx.Tx, docID string) error {
+	var id string
+	err := tx.QueryRow(ctx, `SELECT id FROM docs WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, docID).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return domain.ErrNotFound
+	}
+	return err
+}
+
+// ensureOwner fails with ErrConflict when a change left the document without
+// an owner.
+func ensureOwner(ctx context.Context, tx pgx.Tx, docID string) error {
+	var owned bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM doc_acl WHERE doc_id = $1 AND role = 'owner')`, docID).Scan(&owned); err != nil {
+		return err
+	}
+	if !owned {
+		return domain.ErrConflict
+	}
+	return nil
+}
+
+// ClaimOwnerless makes the user owner of every document nobody owns, such as
+// documents created before access control existed. A lower role the user
+// already held on such a document is raised.
+func (r *ACLRepo) ClaimOwnerless(ctx context.Context, userID string) (int64, error) {
+	const q = `
+INSERT INTO doc_acl (id, doc_id, user_id, role, granted_by, created_at)
+SELECT uuid_generate_v4(), d.id, $1, 'owner', $1, NOW()
+FROM docs d
+WHERE NOT EXISTS (SELECT 1 FROM doc_acl a WHERE a.doc_id = d.id AND a.role = 'owner')
+ON CONFLICT (doc_id, user_id) WHERE user_id IS NOT NULL DO UPDATE SET role = 'owner'`
+	res, err := r.pool.Exec(ctx, q, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
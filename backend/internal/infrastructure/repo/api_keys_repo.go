@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+
+	"collabdocs/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const apiKeyColumns = `id, user_id, name, prefix, secret_hash, scopes, doc_ids::text[], created_at, expires_at, last_used_at, revoked_at`
+
+type APIKeyRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepo(pool *pgxpool.Pool) *APIKeyRepo {
+	return &APIKeyRepo{pool: pool}
+}
+
+func apiKeyDest(k *domain.APIKey) []any {
+	return []any{&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.SecretHash, &k.Scopes, &k.DocIDs, &k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt}
+}
+
+func scanAPIKey(row pgx.Row) (domain.APIKey, error) {
+	var out domain.APIKey
+	if err := row.Scan(apiKeyDest(&out)...); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.APIKey{}, domain.ErrNotFound
+		}
+		return domain.APIKey{}, err
+	}
+	return out, nil
+}
+
+func (r *APIKeyRepo) Create(ctx context.Context, key domain.APIKey) (domain.APIKey, error) {
+	const q = `
+INSERT INTO api_keys (id, user_id, name, prefix, secret_hash, scopes, doc_ids, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7::uuid[], $8, $9)
+RETURNING ` + apiKeyColumns
+
+	return scanAPIKey(r.pool.QueryRow(ctx, q, key.ID, key.UserID, key.Name, key.Prefix, key.SecretHash, key.Scopes, key.DocIDs, key.CreatedAt, key.ExpiresAt))
+}
+
+// ListByUser returns a user's keys, revoked ones included, newest first.
+func (r *APIKeyRepo) ListByUser(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	const q = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC, id`
+
+	rows, err := r.pool.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]domain.APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke disables a user's key. Revoking it again keeps the first revocation
+// time.
+func (r *APIKeyRepo) Revoke(ctx context.Context, userID string, id string) (domain.APIKey, error) {
+	const q = `
+UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+WHERE id = $1 AND user_id = $2
+RETURNING ` + apiKeyColumns
+
+	return scanAPIKey(r.pool.QueryRow(ctx, q, id, userID))
+}
+
+// Authenticate returns a live key by secret hash together with its user, and
+// records the use.
+func (r *APIKeyRepo) Authenticate(ctx context.Context, secretHash string) (domain.APIKey, domain.User, error) {
+	const q = `
+WITH k AS (
+  UPDATE api_keys SET last_used_at = NOW()
+  WHERE secret_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+  RETURNING ` + apiKeyColumns + `
+)
+SELECT k.*, u.id, u.email, u.name, u.password_hash, u.created_at, u.updated_at
+FROM k JOIN users u ON u.id = k.user_id`
+
+	var (
+		key  domain.APIKey
+		user domain.User
+	)
+	dest := append(apiKeyDest(&key), &user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	if err := r.pool.QueryRow(ctx, q, secretHash).Scan(dest...); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.APIKey{}, domain.User{}, domain.ErrNotFound
+		}
+		return domain.APIKey{}, domain.User{}, err
+	}
+	return key, user, nil
+}
@@ -2,12 +2,38 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
 
 	"collabdocs/internal/domain"
+	"collabdocs/pkg/markdown"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// commentColumns reads doc_comments, which queries must not alias, so the
+// reply count and reactions can refer to it.
+const commentColumns = `id, doc_id, parent_id, author_id, author_name, from_pos, to_pos, from_anchor, to_anchor, orphaned, text, resolved,
+  resolved_by, resolved_by_name, resolved_at, reopened_by, reopened_by_name, reopened_at, created_at, updated_at, version,
+  (SELECT count(*) FROM doc_comments replies WHERE replies.parent_id = doc_comments.id AND replies.deleted_at IS NULL), deleted_at,
+  ` + commentReactions
+
+// commentReactions aggregates a comment's reactions into a JSON array of
+// domain.Reaction.
+const commentReactions = `(SELECT COALESCE(json_agg(json_build_object('emoji', r.emoji, 'count', r.n, 'users', r.users) ORDER BY r.first, r.emoji), '[]')
+  FROM (
+    SELECT cr.emoji, count(*) AS n, min(cr.created_at) AS first,
+      json_agg(json_build_object('userId', u.id, 'name', u.name) ORDER BY cr.created_at, u.id) AS users
+    FROM comment_reactions cr JOIN users u ON u.id = cr.user_id
+    WHERE cr.comment_id = doc_comments.id
+    GROUP BY cr.emoji
+  ) r)`
+
+// visibleComment matches live comments, and deleted roots kept as tombstones
+// because they still have live replies.
+const visibleComment = `(doc_comments.deleted_at IS NULL OR (doc_comments.parent_id IS NULL AND EXISTS (
+  SELECT 1 FROM doc_comments replies WHERE replies.parent_id = doc_comments.id AND replies.deleted_at IS NULL
+)))`
+
 type CommentRepo struct {
 	pool *pgxpool.Pool
 }
@@ -16,14 +42,111 @@ func NewCommentRepo(pool *pgxpool.Pool) *CommentRepo {
 	return &CommentRepo{pool: pool}
 }
 
-func (r *CommentRepo) ListByDocID(ctx context.Context, docID string) ([]domain.Comment, error) {
+func scanComment(row pgx.Row) (domain.Comment, error) {
+	var out domain.Comment
+	var reactions []byte
+	if err := row.Scan(&out.ID, &out.DocID, &out.ParentID, &out.AuthorID, &out.AuthorName, &out.FromPos, &out.ToPos, &out.FromAnchor, &out.ToAnchor, &out.Orphaned, &out.Text, &out.Resolved,
+		&out.ResolvedBy, &out.ResolvedByName, &out.ResolvedAt, &out.ReopenedBy, &out.ReopenedByName, &out.ReopenedAt, &out.CreatedAt, &out.UpdatedAt, &out.Version, &out.ReplyCount, &out.DeletedAt, &reactions); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Comment{}, domain.ErrNotFound
+		}
+		return domain.Comment{}, err
+	}
+	if err := json.Unmarshal(reactions, &out.Reactions); err != nil {
+		return domain.Comment{}, err
+	}
+	if out.DeletedAt != nil {
+		out.AuthorID = nil
+		out.AuthorName = ""
+		out.Text = domain.DeletedCommentText
+		out.Reactions = []domain.Reaction{}
+	}
+	out.HTML = markdown.ToHTML(out.Text)
+	return out, nil
+}
+
+// ListByDocID returns a page of the root comments of a document, newest
+// first.
+func (r *CommentRepo) ListByDocID(ctx context.Context, query domain.CommentListQuery) ([]domain.Comment, error) {
+	f := commentFilter(query)
+	if query.Resolved != nil {
+		f.where("doc_comments.resolved = " + f.arg(*query.Resolved))
+	}
+	if query.After != nil {
+		f.where("(doc_comments.created_at, doc_comments.id) < (" + f.arg(query.After.CreatedAt) + ", " + f.arg(query.After.ID) + "::uuid)")
+	}
+	q := `
+SELECT ` + commentColumns + `
+FROM doc_comments
+` + f.clause() + `
+ORDER BY created_at DESC, id DESC
+LIMIT ` + f.arg(query.Limit)
+
+	return r.list(ctx, q, f.args...)
+}
+
+// CountByStatus counts the open and resolved root comments matching the
+// query, ignoring its resolved state and cursor.
+func (r *CommentRepo) CountByStatus(ctx context.Context, query domain.CommentListQuery) (domain.CommentCounts, error) {
+	f := commentFilter(query)
+	q := `
+SELECT count(*) FILTER (WHERE NOT resolved), count(*) FILTER (WHERE resolved)
+FROM doc_comments
+` + f.clause()
+
+	var out domain.CommentCounts
+	if err := r.pool.QueryRow(ctx, q, f.args...).Scan(&out.Open, &out.Resolved); err != nil {
+		return domain.CommentCounts{}, err
+	}
+	out.Total = out.Open + out.Resolved
+	return out, nil
+}
+
+// commentFilter holds the conditions shared by listing and counting root
+// comments. Deleted comments never match on their author or text.
+func commentFilter(query domain.CommentListQuery) *sqlFilter {
+	f := &sqlFilter{}
+	docID := f.arg(query.DocID)
+	f.where("doc_comments.doc_id = " + docID)
+	f.where("doc_comments.parent_id IS NULL")
+	f.where(visibleComment)
+	f.where("EXISTS (SELECT 1 FROM docs WHERE id = " + docID + " AND deleted_at IS NULL)")
+	if query.AuthorID != "" {
+		f.where("doc_comments.author_id = " + f.arg(query.AuthorID) + " AND doc_comments.deleted_at IS NULL")
+	}
+	if query.CreatedAfter != nil {
+		f.where("doc_comments.created_at >= " + f.arg(*query.CreatedAfter))
+	}
+	if query.FromPos != nil {
+		f.where("doc_comments.to_pos >= " + f.arg(*query.FromPos))
+	}
+	if query.ToPos != nil {
+		f.where("doc_comments.from_pos <= " + f.arg(*query.ToPos))
+	}
+	if query.Query != "" {
+		tsquery := "websearch_to_tsquery('simple', " + f.arg(query.Query) + ")"
+		f.where(`((doc_comments.deleted_at IS NULL AND doc_comments.search_vector @@ ` + tsquery + `) OR EXISTS (
+  SELECT 1 FROM doc_comments replies
+  WHERE replies.parent_id = doc_comments.id AND replies.deleted_at IS NULL AND replies.search_vector @@ ` + tsquery + `
+))`)
+	}
+	return f
+}
+
+// ListReplies returns the replies to a root comment, oldest first.
+func (r *CommentRepo) ListReplies(ctx context.Context, docID string, parentID string) ([]domain.Comment, error) {
 	const q = `
-SELECT id, doc_id, author_name, from_pos, to_pos, text, resolved, created_at
+SELECT ` + commentColumns + `
 FROM doc_comments
-WHERE doc_id = $1
-ORDER BY created_at DESC`
+WHERE doc_id = $1 AND parent_id = $2 AND deleted_at IS NULL
+  AND EXISTS (SELECT 1 FROM docs WHERE id = $1 AND deleted_at IS NULL)
+ORDER BY created_at, id`
 
-	rows, err := r.pool.Query(ctx, q, docID)
+	return r.list(ctx, q, docID, parentID)
+}
+
+func (r *CommentRepo) list(ctx context.Context, q string, args ...any) ([]domain.Comment, error) {
+	rows, err := r.pool.Query(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -31,45 +154,285 @@ ORDER BY created_at DESC`
 
 	comments := make([]domain.Comment, 0)
 	for rows.Next() {
-		var c domain.Comment
-		if err := rows.Scan(&c.ID, &c.DocID, &c.AuthorName, &c.FromPos, &c.ToPos, &c.Text, &c.Resolved, &c.CreatedAt); err != nil {
+		c, err := scanComment(rows)
+		if err != nil {
 			return nil, err
 		}
 		comments = append(comments, c)
 	}
-	return comments, nil
+	return comments, rows.Err()
 }
 
-func (r *CommentRepo) Create(ctx context.Context, comment domain.Comment) (domain.Comment, error) {
+func (r *CommentRepo) GetByID(ctx context.Context, docID string, commentID string) (domain.Comment, error) {
 	const q = `
-INSERT INTO doc_comments (id, doc_id, author_name, from_pos, to_pos, text, resolved, created_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, doc_id, author_name, from_pos, to_pos, text, resolved, created_at`
+SELECT ` + commentColumns + `
+FROM doc_comments
+WHERE id = $1 AND doc_id = $2 AND ` + visibleComment + `
+  AND EXISTS (SELECT 1 FROM docs WHERE id = $2 AND deleted_at IS NULL)`
 
-	row := r.pool.QueryRow(ctx, q, comment.ID, comment.DocID, comment.AuthorName, comment.FromPos, comment.ToPos, comment.Text, comment.Resolved, comment.CreatedAt)
-	var out domain.Comment
-	if err := row.Scan(&out.ID, &out.DocID, &out.AuthorName, &out.FromPos, &out.ToPos, &out.Text, &out.Resolved, &out.CreatedAt); err != nil {
-		return domain.Comment{}, err
+	return scanComment(r.pool.QueryRow(ctx, q, commentID, docID))
+}
+
+// Create inserts a comment. A reply takes its range, anchors and resolved
+// state from its parent, which must be a root comment of the same document.
+func (r *CommentRepo) Create(ctx context.Context, comment domain.Comment) (domain.Comment, error) {
+	q := `
+INSERT INTO doc_comments (id, doc_id, author_name, from_pos, to_pos, from_anchor, to_anchor, orphaned, text, resolved, created_at, updated_at, author_id, parent_id)
+SELECT $1, $2, $3, COALESCE(p.from_pos, $4), COALESCE(p.to_pos, $5),
+  CASE WHEN p.id IS NULL THEN $11 ELSE p.from_anchor END,
+  CASE WHEN p.id IS NULL THEN $12 ELSE p.to_anchor END,
+  COALESCE(p.orphaned, false), $6, COALESCE(p.resolved, $7), $8, $8, $9, $10
+FROM (SELECT 1) one
+LEFT JOIN doc_comments p ON p.id = $10 AND p.doc_id = $2 AND p.parent_id IS NULL AND p.deleted_at IS NULL
+WHERE ` + commentableDoc("$2") + `
+  AND ($10::uuid IS NULL OR p.id IS NOT NULL)
+RETURNING ` + commentColumns
+
+	out, err := scanComment(r.pool.QueryRow(ctx, q, comment.ID, comment.DocID, comment.AuthorName, comment.FromPos, comment.ToPos, comment.Text, comment.Resolved, comment.CreatedAt, comment.AuthorID, comment.ParentID, comment.FromAnchor, comment.ToAnchor))
+	if err == domain.ErrNotFound {
+		return domain.Comment{}, r.rejectedWrite(ctx, comment.DocID, comment.ID, nil)
 	}
-	return out, nil
+	return out, err
 }
 
-func (r *CommentRepo) Update(ctx context.Context, docID string, commentID string, resolved *bool, text *string) (domain.Comment, error) {
-	const q = `
+// Update changes a comment as update.Actor. Resolving or reopening a root
+// comment does the same to its replies and records who did it; text edits
+// are kept as revisions. Comments of read-only documents are rejected with
+// ErrReadOnly. A non-nil IfVersion makes the update conditional on the
+// comment's version; a mismatch returns a VersionConflictError.
+func (r *CommentRepo) Update(ctx context.Context, docID string, commentID string, update domain.CommentUpdate) (domain.Comment, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var previous string
+	err = tx.QueryRow(ctx, `SELECT text FROM doc_comments WHERE id = $1 AND doc_id = $2 AND deleted_at IS NULL FOR UPDATE`, commentID, docID).Scan(&previous)
+	if err == pgx.ErrNoRows {
+		return domain.Comment{}, r.rejectedWrite(ctx, docID, commentID, update.IfVersion)
+	}
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	q := `
 UPDATE doc_comments
 SET
   resolved = COALESCE($3, resolved),
-  text = COALESCE($4, text)
-WHERE id = $1 AND doc_id = $2
-RETURNING id, doc_id, author_name, from_pos, to_pos, text, resolved, created_at`
+  resolved_by = CASE WHEN $3 AND NOT resolved THEN $6 ELSE resolved_by END,
+  resolved_by_name = CASE WHEN $3 AND NOT resolved THEN $7 ELSE resolved_by_name END,
+  resolved_at = CASE WHEN $3 AND NOT resolved THEN NOW() ELSE resolved_at END,
+  reopened_by = CASE WHEN NOT $3 AND resolved THEN $6 ELSE reopened_by END,
+  reopened_by_name = CASE WHEN NOT $3 AND resolved THEN $7 ELSE reopened_by_name END,
+  reopened_at = CASE WHEN NOT $3 AND resolved THEN NOW() ELSE reopened_at END,
+  text = COALESCE($4, text),
+  updated_at = CASE WHEN $4::text IS NULL OR $4 = text THEN updated_at ELSE NOW() END,
+  version = version + 1
+WHERE id = $1 AND doc_id = $2 AND deleted_at IS NULL
+  AND ($5::bigint IS NULL OR version = $5)
+  AND ` + commentableDoc("$2") + `
+RETURNING ` + commentColumns
 
-	row := r.pool.QueryRow(ctx, q, commentID, docID, resolved, text)
-	var out domain.Comment
-	if err := row.Scan(&out.ID, &out.DocID, &out.AuthorName, &out.FromPos, &out.ToPos, &out.Text, &out.Resolved, &out.CreatedAt); err != nil {
-		if err == pgx.ErrNoRows {
-			return domain.Comment{}, domain.ErrNotFound
+	out, err := scanComment(tx.QueryRow(ctx, q, commentID, docID, update.Resolved, update.Text, update.IfVersion, update.ActorID, update.Actor))
+	if err == domain.ErrNotFound {
+		return domain.Comment{}, r.rejectedWrite(ctx, docID, commentID, update.IfVersion)
+	}
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	if update.Text != nil && *update.Text != previous {
+		const revision = `
+INSERT INTO comment_revisions (id, comment_id, previous_text, text, edited_by, edited_by_name, created_at)
+VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5, $6)`
+		if _, err := tx.Exec(ctx, revision, out.ID, previous, out.Text, update.ActorID, update.Actor, out.UpdatedAt); err != nil {
+			return domain.Comment{}, err
+		}
+	}
+	if update.Resolved != nil && !out.IsReply() {
+		const cascade = `
+UPDATE doc_comments SET resolved = $2, version = version + 1
+WHERE parent_id = $1 AND resolved <> $2`
+		if _, err := tx.Exec(ctx, cascade, out.ID, *update.Resolved); err != nil {
+			return domain.Comment{}, err
 		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return domain.Comment{}, err
 	}
 	return out, nil
 }
+
+// ListRevisions returns the text edits of a comment, oldest first.
+func (r *CommentRepo) ListRevisions(ctx context.Context, docID string, commentID string) ([]domain.CommentRevision, error) {
+	const q = `
+SELECT v.id, v.comment_id, v.previous_text, v.text, v.edited_by, v.edited_by_name, v.created_at
+FROM comment_revisions v
+JOIN doc_comments c ON c.id = v.comment_id
+WHERE v.comment_id = $1 AND c.doc_id = $2
+ORDER BY v.created_at, v.id`
+
+	rows, err := r.pool.Query(ctx, q, commentID, docID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]domain.CommentRevision, 0)
+	for rows.Next() {
+		var v domain.CommentRevision
+		if err := rows.Scan(&v.ID, &v.CommentID, &v.PreviousText, &v.Text, &v.EditedBy, &v.EditedByName, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, v)
+	}
+	return revisions, rows.Err()
+}
+
+// ListAnchors returns the anchored ranges of the live root comments of a
+// document.
+func (r *CommentRepo) ListAnchors(ctx context.Context, docID string) ([]domain.CommentAnchor, error) {
+	const q = `
+SELECT id, from_anchor, to_anchor, from_pos, to_pos, orphaned
+FROM doc_comments
+WHERE doc_id = $1 AND parent_id IS NULL AND deleted_at IS NULL
+  AND from_anchor IS NOT NULL AND to_anchor IS NOT NULL`
+
+	rows, err := r.pool.Query(ctx, q, docID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	anchors := make([]domain.CommentAnchor, 0)
+	for rows.Next() {
+		var a domain.CommentAnchor
+		if err := rows.Scan(&a.CommentID, &a.FromAnchor, &a.ToAnchor, &a.FromPos, &a.ToPos, &a.Orphaned); err != nil {
+			return nil, err
+		}
+		anchors = append(anchors, a)
+	}
+	return anchors, rows.Err()
+}
+
+// UpdateAnchors stores resolved ranges on root comments and their replies.
+// Positions follow the text rather than user edits, so versions are kept.
+func (r *CommentRepo) UpdateAnchors(ctx context.Context, docID string, anchors []domain.CommentAnchor) error {
+	if len(anchors) == 0 {
+		return nil
+	}
+	const q = `
+UPDATE doc_comments
+SET from_pos = $3, to_pos = $4, orphaned = $5
+WHERE doc_id = $1 AND (id = $2 OR parent_id = $2)`
+
+	batch := &pgx.Batch{}
+	for _, a := range anchors {
+		batch.Queue(q, docID, a.CommentID, a.FromPos, a.ToPos, a.Orphaned)
+	}
+	return r.pool.SendBatch(ctx, batch).Close()
+}
+
+// AddReaction records a user's reaction to a live comment. Adding it again
+// changes nothing. Comments of read-only documents are rejected with
+// ErrReadOnly.
+func (r *CommentRepo) AddReaction(ctx context.Context, docID string, commentID string, userID string, emoji string) error {
+	q := `
+WITH target AS (
+  SELECT id FROM doc_comments
+  WHERE id = $1 AND doc_id = $2 AND deleted_at IS NULL
+    AND ` + commentableDoc("$2") + `
+), added AS (
+  INSERT INTO comment_reactions (comment_id, user_id, emoji, created_at)
+  SELECT id, $3, $4, NOW() FROM target
+  ON CONFLICT (comment_id, user_id, emoji) DO NOTHING
+)
+SELECT count(*) FROM target`
+
+	var found int
+	if err := r.pool.QueryRow(ctx, q, commentID, docID, userID, emoji).Scan(&found); err != nil {
+		return err
+	}
+	if found == 0 {
+		return r.rejectedWrite(ctx, docID, commentID, nil)
+	}
+	return nil
+}
+
+// RemoveReaction withdraws a user's reaction. Removing a reaction that does
+// not exist changes nothing.
+func (r *CommentRepo) RemoveReaction(ctx context.Context, docID string, commentID string, userID string, emoji string) error {
+	q := `
+WITH target AS (
+  SELECT id FROM doc_comments
+  WHERE id = $1 AND doc_id = $2 AND deleted_at IS NULL
+    AND ` + commentableDoc("$2") + `
+), removed AS (
+  DELETE FROM comment_reactions
+  WHERE comment_id IN (SELECT id FROM target) AND user_id = $3 AND emoji = $4
+)
+SELECT count(*) FROM target`
+
+	var found int
+	if err := r.pool.QueryRow(ctx, q, commentID, docID, userID, emoji).Scan(&found); err != nil {
+		return err
+	}
+	if found == 0 {
+		return r.rejectedWrite(ctx, docID, commentID, nil)
+	}
+	return nil
+}
+
+// Delete soft-deletes a live comment. A root with live replies stays listed
+// as a tombstone; other deleted comments are hidden.
+func (r *CommentRepo) Delete(ctx context.Context, docID string, commentID string, deletedBy *string) (domain.Comment, error) {
+	q := `
+UPDATE doc_comments
+SET deleted_at = NOW(), deleted_by = $3, version = version + 1
+WHERE id = $1 AND doc_id = $2 AND deleted_at IS NULL
+  AND ` + commentableDoc("$2") + `
+RETURNING ` + commentColumns
+
+	out, err := scanComment(r.pool.QueryRow(ctx, q, commentID, docID, deletedBy))
+	if err == domain.ErrNotFound {
+		return domain.Comment{}, r.rejectedWrite(ctx, docID, commentID, nil)
+	}
+	return out, err
+}
+
+// Purge permanently deletes a comment, deleted or not, with its replies, and
+// returns it as it was.
+func (r *CommentRepo) Purge(ctx context.Context, docID string, commentID string) (domain.Comment, error) {
+	const q = `
+DELETE FROM doc_comments
+WHERE id = $1 AND doc_id = $2
+  AND EXISTS (SELECT 1 FROM docs WHERE id = $2 AND deleted_at IS NULL)
+RETURNING ` + commentColumns
+
+	return scanComment(r.pool.QueryRow(ctx, q, commentID, docID))
+}
+
+// rejectedWrite explains why a comment write matched no row: the document
+// does not accept comments, the comment changed since ifVersion, or one of
+// them does not exist. An empty commentID checks the document only.
+func (r *CommentRepo) rejectedWrite(ctx context.Context, docID string, commentID string, ifVersion *int64) error {
+	doc, err := loadDocState(ctx, r.pool, docID)
+	if err != nil {
+		return err
+	}
+	if !doc.CommentsAllowed() {
+		return domain.ErrReadOnly
+	}
+	if ifVersion == nil {
+		return domain.ErrNotFound
+	}
+	current, err := r.GetByID(ctx, docID, commentID)
+	if err != nil {
+		return err
+	}
+	if current.Version != *ifVersion {
+		return &domain.VersionConflictError{Version: current.Version, Current: current}
+	}
+	return domain.ErrNotFound
+}
@@ -2,12 +2,32 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"collabdocs/internal/domain"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// documentColumns selects a document row. Queries using it must not alias
+// docs, as the tag subquery refers to the table by name.
+const documentColumns = `id, title, folder_id, created_at, updated_at, deleted_at, forked_from, is_template, properties, state, allow_comments, version,
+  ARRAY(
+    SELECT t.name FROM doc_tags dt JOIN tags t ON t.id = dt.tag_id
+    WHERE dt.doc_id = docs.id ORDER BY lower(t.name)
+  )`
+
+// targetFolder resolves the folder a new document is placed in: the folder
+// given as $3, or the root folder of the default workspace when $3 is NULL.
+const targetFolder = `
+SELECT f.id FROM folders f
+WHERE f.id = COALESCE($3::uuid, (
+  SELECT rf.id FROM folders rf JOIN workspaces w ON w.id = rf.workspace_id
+  WHERE w.is_default AND rf.parent_id IS NULL
+))`
+
 type DocumentRepo struct {
 	pool *pgxpool.Pool
 }
@@ -16,59 +36,435 @@ func NewDocumentRepo(pool *pgxpool.Pool) *DocumentRepo {
 	return &DocumentRepo{pool: pool}
 }
 
-func (r *DocumentRepo) Create(ctx context.Context, doc domain.Document) (domain.Document, error) {
-	const q = `
-INSERT INTO docs (id, title, created_at, updated_at)
-VALUES ($1, $2, $3, $4)
-RETURNING id, title, created_at, updated_at`
+// documentFields returns scan destinations matching documentColumns.
+func documentFields(d *domain.Document) []any {
+	return []any{&d.ID, &d.Title, &d.FolderID, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt, &d.ForkedFrom, &d.IsTemplate, &d.Properties, &d.State, &d.AllowComments, &d.Version, &d.Tags}
+}
 
-	row := r.pool.QueryRow(ctx, q, doc.ID, doc.Title, doc.CreatedAt, doc.UpdatedAt)
+func scanDocument(row pgx.Row) (domain.Document, error) {
 	var out domain.Document
-	if err := row.Scan(&out.ID, &out.Title, &out.CreatedAt, &out.UpdatedAt); err != nil {
+	if err := row.Scan(documentFields(&out)...); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Document{}, domain.ErrNotFound
+		}
+		return domain.Document{}, err
+	}
+	return out, nil
+}
+
+// Create inserts a document and makes ownerID its owner.
+func (r *DocumentRepo) Create(ctx context.Context, doc domain.Document, ownerID string) (domain.Document, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	const q = `
+INSERT INTO docs (id, title, folder_id, created_at, updated_at)
+SELECT $1, $2, f.id, $4, $5
+FROM (` + targetFolder + `) f
+RETURNING ` + documentColumns
+
+	out, err := scanDocument(tx.QueryRow(ctx, q, doc.ID, doc.Title, nullableString(doc.FolderID), doc.CreatedAt, doc.UpdatedAt))
+	if err != nil {
+		return domain.Document{}, err
+	}
+	if err := grantOwner(ctx, tx, out.ID, ownerID); err != nil {
+		return domain.Document{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Document{}, err
+	}
+	return out, nil
+}
+
+// CreateWithSnapshot inserts a document owned by ownerID together with its
+// initial snapshot and the text indexed for search.
+func (r *DocumentRepo) CreateWithSnapshot(ctx context.Context, doc domain.Document, ownerID string, snapshot []byte, contentText string) (domain.Document, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	const insertDoc = `
+INSERT INTO docs (id, title, folder_id, created_at, updated_at, content_text)
+SELECT $1, $2, f.id, $4, $5, $6
+FROM (` + targetFolder + `) f
+RETURNING ` + documentColumns
+
+	out, err := scanDocument(tx.QueryRow(ctx, insertDoc, doc.ID, doc.Title, nullableString(doc.FolderID), doc.CreatedAt, doc.UpdatedAt, withoutDelimiters.Replace(contentText)))
+	if err != nil {
+		return domain.Document{}, err
+	}
+
+	const insertSnapshot = `INSERT INTO doc_snapshots (doc_id, snapshot, updated_at) VALUES ($1, $2, NOW())`
+	if _, err := tx.Exec(ctx, insertSnapshot, out.ID, snapshot); err != nil {
+		return domain.Document{}, err
+	}
+	if err := grantOwner(ctx, tx, out.ID, ownerID); err != nil {
+		return domain.Document{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return domain.Document{}, err
 	}
 	return out, nil
 }
 
 func (r *DocumentRepo) GetByID(ctx context.Context, id string) (domain.Document, error) {
-	const q = `SELECT id, title, created_at, updated_at FROM docs WHERE id = $1`
-	row := r.pool.QueryRow(ctx, q, id)
-	var out domain.Document
-	if err := row.Scan(&out.ID, &out.Title, &out.CreatedAt, &out.UpdatedAt); err != nil {
-		if err == pgx.ErrNoRows {
-			return domain.Document{}, domain.ErrNotFound
+	const q = `SELECT ` + documentColumns + ` FROM docs WHERE id = $1 AND deleted_at IS NULL`
+	return scanDocument(r.pool.QueryRow(ctx, q, id))
+}
+
+var documentSortColumns = map[domain.DocumentSort]string{
+	domain.DocumentSortTitle:   "title",
+	domain.DocumentSortCreated: "created_at",
+	domain.DocumentSortUpdated: "updated_at",
+}
+
+func (r *DocumentRepo) List(ctx context.Context, query domain.DocumentListQuery) ([]domain.Document, int, error) {
+	var f sqlFilter
+	if query.ViewerID != "" {
+		f.where(accessCondition(&f, query.ViewerID))
+	}
+	if query.IDs != nil {
+		f.where("docs.id = ANY(" + f.arg(query.IDs) + "::uuid[])")
+	}
+	if query.Trashed {
+		f.where("deleted_at IS NOT NULL")
+	} else {
+		f.where("deleted_at IS NULL")
+	}
+	if query.IsTemplate != nil {
+		f.where("is_template = " + f.arg(*query.IsTemplate))
+	}
+	if query.FolderID != "" {
+		f.where("folder_id = " + f.arg(query.FolderID))
+	}
+	if len(query.Tags) > 0 {
+		f.where(tagCondition(&f, query.Tags, query.AllTags))
+	}
+	for _, p := range query.Properties {
+		f.where(propertyCondition(&f, p))
+	}
+	if query.TitlePrefix != "" {
+		f.where("title ILIKE " + f.arg(likePrefix(query.TitlePrefix)))
+	}
+	if query.CreatedAfter != nil {
+		f.where("created_at >= " + f.arg(*query.CreatedAfter))
+	}
+	if query.CreatedBefore != nil {
+		f.where("created_at < " + f.arg(*query.CreatedBefore))
+	}
+	if query.UpdatedAfter != nil {
+		f.where("updated_at >= " + f.arg(*query.UpdatedAfter))
+	}
+	if query.UpdatedBefore != nil {
+		f.where("updated_at < " + f.arg(*query.UpdatedBefore))
+	}
+
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM docs `+f.clause(), f.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := documentSortColumns[query.Sort]
+	if !ok {
+		return nil, 0, domain.ErrInvalidInput
+	}
+	cast := "timestamptz"
+	if query.Sort == domain.DocumentSortTitle {
+		cast = "text"
+	}
+	dir, cmp := "ASC", ">"
+	if query.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if query.After != nil {
+		f.where(fmt.Sprintf("(%s, id) %s (%s::%s, %s::uuid)", column, cmp, f.arg(query.After.Value), cast, f.arg(query.After.ID)))
+	}
+
+	q := fmt.Sprintf(`
+SELECT %s
+FROM docs
+%s
+ORDER BY %s %s, id %s
+LIMIT %s`, documentColumns, f.clause(), column, dir, dir, f.arg(query.Limit))
+
+	rows, err := r.pool.Query(ctx, q, f.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	docs := make([]domain.Document, 0)
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, 0, err
 		}
+		docs = append(docs, d)
+	}
+	return docs, total, rows.Err()
+}
+
+// Update applies a partial update. Set properties are merged into the stored
+// ones after the unset keys are removed.
+func (r *DocumentRepo) Update(ctx context.Context, id string, update domain.DocumentUpdate) (domain.Document, error) {
+	const q = `
+UPDATE docs
+SET title = COALESCE($2, title),
+    properties = (properties - $4::text[]) || $3::jsonb,
+    version = version + 1,
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL AND state = 'active' AND ($5::bigint IS NULL OR version = $5)
+RETURNING ` + documentColumns
+
+	set, err := json.Marshal(update.SetProperties)
+	if err != nil {
 		return domain.Document{}, err
 	}
-	return out, nil
+	if update.SetProperties == nil {
+		set = []byte("{}")
+	}
+	unset := update.UnsetProperties
+	if unset == nil {
+		unset = []string{}
+	}
+	out, err := scanDocument(r.pool.QueryRow(ctx, q, id, update.Title, string(set), unset, update.IfVersion))
+	if err == domain.ErrNotFound {
+		return domain.Document{}, r.rejectedWrite(ctx, id, update.IfVersion)
+	}
+	return out, err
 }
 
-func (r *DocumentRepo) UpdateTitle(ctx context.Context, id string, title string) (domain.Document, error) {
+// rejectedWrite explains why a guarded write matched no row: the document is
+// read-only, changed since ifVersion, or does not exist.
+func (r *DocumentRepo) rejectedWrite(ctx context.Context, id string, ifVersion *int64) error {
+	current, err := r.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if current.ReadOnly() {
+		return domain.ErrReadOnly
+	}
+	if ifVersion != nil && *ifVersion != current.Version {
+		return &domain.VersionConflictError{Version: current.Version, Current: current}
+	}
+	return domain.ErrNotFound
+}
+
+func (r *DocumentRepo) SetTemplate(ctx context.Context, id string, isTemplate bool) (domain.Document, error) {
 	const q = `
 UPDATE docs
-SET title = $2, updated_at = NOW()
-WHERE id = $1
-RETURNING id, title, created_at, updated_at`
+SET is_template = $2, version = version + 1, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING ` + documentColumns
 
-	row := r.pool.QueryRow(ctx, q, id, title)
-	var out domain.Document
-	if err := row.Scan(&out.ID, &out.Title, &out.CreatedAt, &out.UpdatedAt); err != nil {
-		if err == pgx.ErrNoRows {
-			return domain.Document{}, domain.ErrNotFound
+	return scanDocument(r.pool.QueryRow(ctx, q, id, isTemplate))
+}
+
+func (r *DocumentRepo) MoveToFolder(ctx context.Context, id string, folderID string) (domain.Document, error) {
+	const q = `
+UPDATE docs
+SET folder_id = $2, version = version + 1, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL AND EXISTS (SELECT 1 FROM folders WHERE id = $2)
+RETURNING ` + documentColumns
+
+	return scanDocument(r.pool.QueryRow(ctx, q, id, folderID))
+}
+
+// Duplicate copies the source document, its tags and properties, and
+// optionally its comments, into doc within a single transaction. snapshot is
+// the copy's content and contentText its indexed text; a nil contentText
+// keeps the source's. Deleted comments are not copied, nor are the replies of
+// deleted threads. Access grants are not copied; ownerID becomes the copy's
+// only owner.
+func (r *DocumentRepo) Duplicate(ctx context.Context, sourceID string, doc domain.Document, ownerID string, includeComments bool, snapshot []byte, contentText *string) (domain.Document, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	const insertDoc = `
+INSERT INTO docs (id, title, folder_id, created_at, updated_at, content_text, forked_from, properties)
+SELECT $2, $3, folder_id, $4, $5, COALESCE($6, content_text), id, properties
+FROM docs
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING ` + documentColumns
+
+	if contentText != nil {
+		text := withoutDelimiters.Replace(*contentText)
+		contentText = &text
+	}
+	out, err := scanDocument(tx.QueryRow(ctx, insertDoc, sourceID, doc.ID, doc.Title, doc.CreatedAt, doc.UpdatedAt, contentText))
+	if err != nil {
+		return domain.Document{}, err
+	}
+
+	if len(snapshot) > 0 {
+		const insertSnapshot = `INSERT INTO doc_snapshots (doc_id, snapshot, updated_at) VALUES ($1, $2, NOW())`
+		if _, err := tx.Exec(ctx, insertSnapshot, out.ID, snapshot); err != nil {
+			return domain.Document{}, err
 		}
+	}
+
+	const copyTags = `
+INSERT INTO doc_tags (doc_id, tag_id, created_at)
+SELECT $2, tag_id, NOW()
+FROM doc_tags
+WHERE doc_id = $1`
+	if _, err := tx.Exec(ctx, copyTags, sourceID, out.ID); err != nil {
+		return domain.Document{}, err
+	}
+	if err := grantOwner(ctx, tx, out.ID, ownerID); err != nil {
+		return domain.Document{}, err
+	}
+	out, err = scanDocument(tx.QueryRow(ctx, `SELECT `+documentColumns+` FROM docs WHERE id = $1`, out.ID))
+	if err != nil {
+		return domain.Document{}, err
+	}
+
+	if includeComments {
+		const copyComments = `
+WITH src AS (
+  SELECT c.*, uuid_generate_v4() AS new_id
+  FROM doc_comments c LEFT JOIN doc_comments root ON root.id = c.parent_id
+  WHERE c.doc_id = $1 AND c.deleted_at IS NULL AND root.deleted_at IS NULL
+), copied AS (
+  INSERT INTO doc_comments (id, doc_id, parent_id, author_id, author_name, from_pos, to_pos, from_anchor, to_anchor, orphaned, text, resolved,
+    resolved_by, resolved_by_name, resolved_at, reopened_by, reopened_by_name, reopened_at, created_at, updated_at)
+  SELECT c.new_id, $2, p.new_id, c.author_id, c.author_name, c.from_pos, c.to_pos, c.from_anchor, c.to_anchor, c.orphaned, c.text, c.resolved,
+    c.resolved_by, c.resolved_by_name, c.resolved_at, c.reopened_by, c.reopened_by_name, c.reopened_at, c.created_at, c.updated_at
+  FROM src c LEFT JOIN src p ON p.id = c.parent_id
+), revisions AS (
+  INSERT INTO comment_revisions (id, comment_id, previous_text, text, edited_by, edited_by_name, created_at)
+  SELECT uuid_generate_v4(), c.new_id, v.previous_text, v.text, v.edited_by, v.edited_by_name, v.created_at
+  FROM comment_revisions v JOIN src c ON c.id = v.comment_id
+)
+INSERT INTO comment_reactions (comment_id, user_id, emoji, created_at)
+SELECT c.new_id, r.user_id, r.emoji, r.created_at
+FROM comment_reactions r JOIN src c ON c.id = r.comment_id`
+		if _, err := tx.Exec(ctx, copyComments, sourceID, out.ID); err != nil {
+			return domain.Document{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return domain.Document{}, err
 	}
 	return out, nil
 }
 
-func (r *DocumentRepo) Delete(ctx context.Context, id string) error {
-	const q = `DELETE FROM docs WHERE id = $1`
-	res, err := r.pool.Exec(ctx, q, id)
+// Delete moves the document to the trash. Its snapshot, updates and comments
+// are kept until the document is purged. Read-only documents cannot be
+// deleted, and a non-nil ifVersion makes the delete conditional on the
+// document's version.
+func (r *DocumentRepo) Delete(ctx context.Context, id string, ifVersion *int64) error {
+	const q = `
+UPDATE docs SET deleted_at = NOW(), version = version + 1
+WHERE id = $1 AND deleted_at IS NULL AND state = 'active' AND ($2::bigint IS NULL OR version = $2)`
+	res, err := r.pool.Exec(ctx, q, id, ifVersion)
 	if err != nil {
 		return err
 	}
 	if res.RowsAffected() == 0 {
-		return domain.ErrNotFound
+		return r.rejectedWrite(ctx, id, ifVersion)
 	}
 	return nil
 }
+
+// SetState changes the document state and records the change in the audit
+// trail within one transaction.
+func (r *DocumentRepo) SetState(ctx context.Context, event domain.DocumentStateEvent) (domain.Document, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Document{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var from domain.DocumentState
+	err = tx.QueryRow(ctx, `SELECT state FROM docs WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, event.DocID).Scan(&from)
+	if err == pgx.ErrNoRows {
+		return domain.Document{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return domain.Document{}, err
+	}
+	if from != event.FromState {
+		return domain.Document{}, domain.ErrConflict
+	}
+
+	const update = `
+UPDATE docs
+SET state = $2, allow_comments = $3, version = version + 1, updated_at = NOW()
+WHERE id = $1
+RETURNING ` + documentColumns
+	out, err := scanDocument(tx.QueryRow(ctx, update, event.DocID, event.ToState, event.AllowComments))
+	if err != nil {
+		return domain.Document{}, err
+	}
+
+	const insertEvent = `
+INSERT INTO doc_state_events (id, doc_id, from_state, to_state, allow_comments, actor_id, actor, reason, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	if _, err := tx.Exec(ctx, insertEvent, event.ID, event.DocID, event.FromState, event.ToState, event.AllowComments, event.ActorID, event.Actor, event.Reason, event.CreatedAt); err != nil {
+		return domain.Document{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Document{}, err
+	}
+	return out, nil
+}
+
+// ListStateEvents returns the audit trail of a document, newest first.
+func (r *DocumentRepo) ListStateEvents(ctx context.Context, docID string) ([]domain.DocumentStateEvent, error) {
+	const q = `
+SELECT e.id, e.doc_id, e.from_state, e.to_state, e.allow_comments, e.actor_id, e.actor, e.reason, e.created_at
+FROM doc_state_events e
+JOIN docs d ON d.id = e.doc_id
+WHERE e.doc_id = $1 AND d.deleted_at IS NULL
+ORDER BY e.created_at DESC`
+
+	rows, err := r.pool.Query(ctx, q, docID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]domain.DocumentStateEvent, 0)
+	for rows.Next() {
+		var e domain.DocumentStateEvent
+		if err := rows.Scan(&e.ID, &e.DocID, &e.FromState, &e.ToState, &e.AllowComments, &e.ActorID, &e.Actor, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (r *DocumentRepo) Restore(ctx context.Context, id string) (domain.Document, error) {
+	const q = `
+UPDATE docs
+SET deleted_at = NULL, version = version + 1
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING ` + documentColumns
+
+	return scanDocument(r.pool.QueryRow(ctx, q, id))
+}
+
+// PurgeDeleted hard-deletes documents trashed before the cutoff. Snapshots,
+// updates and comments go with them through ON DELETE CASCADE.
+func (r *DocumentRepo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	const q = `DELETE FROM docs WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	res, err := r.pool.Exec(ctx, q, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
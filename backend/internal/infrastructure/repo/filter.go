@@ -0,0 +1,36 @@
+package repo
+
+import (
+	"strconv"
+	"strings"
+)
+
+// sqlFilter accumulates WHERE conditions and their positional arguments.
+type sqlFilter struct {
+	conds []string
+	args  []any
+}
+
+// arg registers a value and returns its placeholder.
+func (f *sqlFilter) arg(v any) string {
+	f.args = append(f.args, v)
+	return "$" + strconv.Itoa(len(f.args))
+}
+
+func (f *sqlFilter) where(cond string) {
+	f.conds = append(f.conds, cond)
+}
+
+func (f *sqlFilter) clause() string {
+	if len(f.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(f.conds, " AND ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePrefix builds a LIKE pattern matching values that start with prefix.
+func likePrefix(prefix string) string {
+	return likeEscaper.Replace(prefix) + "%"
+}
@@ -0,0 +1,334 @@
+package repo
+
+import (
+	"context"
+
+	"collabdocs/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const folderColumns = `id, workspace_id, parent_id, name, created_at, updated_at`
+
+const workspaceColumns = `w.id, w.name, w.is_default, COALESCE(w.owner_id::text, ''), f.id, w.created_at, w.updated_at`
+
+// subtreeCTE selects the folder $1 and all of its descendants.
+const subtreeCTE = `
+WITH RECURSIVE subtree AS (
+  SELECT id FROM folders WHERE id = $1
+  UNION ALL
+  SELECT c.id FROM folders c JOIN subtree s ON c.parent_id = s.id
+)`
+
+type FolderRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewFolderRepo(pool *pgxpool.Pool) *FolderRepo {
+	return &FolderRepo{pool: pool}
+}
+
+func scanFolder(row pgx.Row) (domain.Folder, error) {
+	var out domain.Folder
+	if err := row.Scan(&out.ID, &out.WorkspaceID, &out.ParentID, &out.Name, &out.CreatedAt, &out.UpdatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Folder{}, domain.ErrNotFound
+		}
+		return domain.Folder{}, err
+	}
+	return out, nil
+}
+
+func scanWorkspace(row pgx.Row) (domain.Workspace, error) {
+	var out domain.Workspace
+	if err := row.Scan(&out.ID, &out.Name, &out.IsDefault, &out.OwnerID, &out.RootFolderID, &out.CreatedAt, &out.UpdatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Workspace{}, domain.ErrNotFound
+		}
+		return domain.Workspace{}, err
+	}
+	return out, nil
+}
+
+// CreateWorkspace inserts a workspace and its root folder, with the owner as
+// its first member.
+func (r *FolderRepo) CreateWorkspace(ctx context.Context, workspace domain.Workspace, root domain.Folder) (domain.Workspace, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	const insertWorkspace = `
+INSERT INTO workspaces (id, name, owner_id, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.Exec(ctx, insertWorkspace, workspace.ID, workspace.Name, workspace.OwnerID, workspace.CreatedAt, workspace.UpdatedAt); err != nil {
+		return domain.Workspace{}, err
+	}
+
+	const insertOwner = `INSERT INTO workspace_members (workspace_id, user_id, created_at) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, insertOwner, workspace.ID, workspace.OwnerID, workspace.CreatedAt); err != nil {
+		return domain.Workspace{}, err
+	}
+
+	const insertRoot = `
+INSERT INTO folders (id, workspace_id, parent_id, name, created_at, updated_at)
+VALUES ($1, $2, NULL, $3, $4, $5)`
+	if _, err := tx.Exec(ctx, insertRoot, root.ID, workspace.ID, root.Name, root.CreatedAt, root.UpdatedAt); err != nil {
+		return domain.Workspace{}, err
+	}
+
+	out, err := scanWorkspace(tx.QueryRow(ctx, `
+SELECT `+workspaceColumns+`
+FROM workspaces w JOIN folders f ON f.workspace_id = w.id AND f.parent_id IS NULL
+WHERE w.id = $1`, workspace.ID))
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Workspace{}, err
+	}
+	return out, nil
+}
+
+// ListWorkspaces returns the default workspace and the workspaces the user is
+// a member of.
+func (r *FolderRepo) ListWorkspaces(ctx context.Context, userID string) ([]domain.Workspace, error) {
+	const q = `
+SELECT ` + workspaceColumns + `
+FROM workspaces w JOIN folders f ON f.workspace_id = w.id AND f.parent_id IS NULL
+WHERE w.is_default
+   OR EXISTS (SELECT 1 FROM workspace_members m WHERE m.workspace_id = w.id AND m.user_id = $1)
+ORDER BY w.is_default DESC, w.name, w.id`
+
+	rows, err := r.pool.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workspaces := make([]domain.Workspace, 0)
+	for rows.Next() {
+		w, err := scanWorkspace(rows)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, w)
+	}
+	return workspaces, rows.Err()
+}
+
+func (r *FolderRepo) GetWorkspace(ctx context.Context, id string) (domain.Workspace, error) {
+	const q = `
+SELECT ` + workspaceColumns + `
+FROM workspaces w JOIN folders f ON f.workspace_id = w.id AND f.parent_id IS NULL
+WHERE w.id = $1`
+	return scanWorkspace(r.pool.QueryRow(ctx, q, id))
+}
+
+func (r *FolderRepo) IsWorkspaceMember(ctx context.Context, workspaceID string, userID string) (bool, error) {
+	const q = `SELECT EXISTS (SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id = $2)`
+	var member bool
+	err := r.pool.QueryRow(ctx, q, workspaceID, userID).Scan(&member)
+	return member, err
+}
+
+func (r *FolderRepo) ListWorkspaceMembers(ctx context.Context, workspaceID string) ([]domain.User, error) {
+	const q = `
+SELECT u.id, u.email, u.name, u.password_hash, u.created_at, u.updated_at
+FROM workspace_members m JOIN users u ON u.id = m.user_id
+WHERE m.workspace_id = $1
+ORDER BY lower(u.name), u.id`
+
+	rows, err := r.pool.Query(ctx, q, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// AddWorkspaceMember adds a user to a workspace. Adding an existing member is
+// a no-op.
+func (r *FolderRepo) AddWorkspaceMember(ctx context.Context, workspaceID string, userID string) error {
+	const q = `
+INSERT INTO workspace_members (workspace_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING`
+	_, err := r.pool.Exec(ctx, q, workspaceID, userID)
+	return err
+}
+
+func (r *FolderRepo) RemoveWorkspaceMember(ctx context.Context, workspaceID string, userID string) error {
+	res, err := r.pool.Exec(ctx, `DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// CreateFolder inserts a folder under its parent, inheriting the parent's
+// workspace.
+func (r *FolderRepo) CreateFolder(ctx context.Context, folder domain.Folder) (domain.Folder, error) {
+	const q = `
+INSERT INTO folders (id, workspace_id, parent_id, name, created_at, updated_at)
+SELECT $1, p.workspace_id, p.id, $3, $4, $5
+FROM folders p
+WHERE p.id = $2
+RETURNING ` + folderColumns
+
+	return scanFolder(r.pool.QueryRow(ctx, q, folder.ID, folder.ParentID, folder.Name, folder.CreatedAt, folder.UpdatedAt))
+}
+
+func (r *FolderRepo) GetFolder(ctx context.Context, id string) (domain.Folder, error) {
+	const q = `SELECT ` + folderColumns + ` FROM folders WHERE id = $1`
+	return scanFolder(r.pool.QueryRow(ctx, q, id))
+}
+
+func (r *FolderRepo) ListSubfolders(ctx context.Context, parentID string) ([]domain.Folder, error) {
+	const q = `SELECT ` + folderColumns + ` FROM folders WHERE parent_id = $1 ORDER BY name, id`
+
+	rows, err := r.pool.Query(ctx, q, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := make([]domain.Folder, 0)
+	for rows.Next() {
+		f, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
+
+func (r *FolderRepo) RenameFolder(ctx context.Context, id string, name string) (domain.Folder, error) {
+	const q = `
+UPDATE folders
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING ` + folderColumns
+
+	return scanFolder(r.pool.QueryRow(ctx, q, id, name))
+}
+
+// MoveFolder re-parents a folder. When the new parent is in another
+// workspace the whole subtree moves with it in the same transaction. The
+// workspaces involved are locked so concurrent moves cannot form a cycle.
+func (r *FolderRepo) MoveFolder(ctx context.Context, id string, parentID string) (domain.Folder, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Folder{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	const lockWorkspaces = `
+SELECT w.id FROM workspaces w
+WHERE w.id IN (SELECT workspace_id FROM folders WHERE id IN ($1, $2))
+ORDER BY w.id
+FOR UPDATE`
+	if _, err := tx.Exec(ctx, lockWorkspaces, id, parentID); err != nil {
+		return domain.Folder{}, err
+	}
+
+	folder, err := scanFolder(tx.QueryRow(ctx, `SELECT `+folderColumns+` FROM folders WHERE id = $1`, id))
+	if err != nil {
+		return domain.Folder{}, err
+	}
+	if folder.ParentID == nil {
+		return domain.Folder{}, domain.ErrInvalidInput
+	}
+	parent, err := scanFolder(tx.QueryRow(ctx, `SELECT `+folderColumns+` FROM folders WHERE id = $1`, parentID))
+	if err != nil {
+		return domain.Folder{}, err
+	}
+
+	var cycle bool
+	if err := tx.QueryRow(ctx, subtreeCTE+` SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)`, id, parentID).Scan(&cycle); err != nil {
+		return domain.Folder{}, err
+	}
+	if cycle {
+		return domain.Folder{}, domain.ErrInvalidInput
+	}
+
+	if parent.WorkspaceID != folder.WorkspaceID {
+		const moveSubtree = subtreeCTE + `
+UPDATE folders SET workspace_id = $2, updated_at = NOW()
+WHERE id IN (SELECT id FROM subtree)`
+		if _, err := tx.Exec(ctx, moveSubtree, id, parent.WorkspaceID); err != nil {
+			return domain.Folder{}, err
+		}
+	}
+
+	const reparent = `
+UPDATE folders
+SET parent_id = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING ` + folderColumns
+	out, err := scanFolder(tx.QueryRow(ctx, reparent, id, parentID))
+	if err != nil {
+		return domain.Folder{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Folder{}, err
+	}
+	return out, nil
+}
+
+// DeleteFolder removes an empty folder. Trashed documents still filed in it
+// are moved to the workspace root so they can be restored later.
+func (r *FolderRepo) DeleteFolder(ctx context.Context, id string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	folder, err := scanFolder(tx.QueryRow(ctx, `SELECT `+folderColumns+` FROM folders WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return err
+	}
+	if folder.ParentID == nil {
+		return domain.ErrInvalidInput
+	}
+
+	const nonEmpty = `
+SELECT EXISTS (SELECT 1 FROM folders WHERE parent_id = $1)
+    OR EXISTS (SELECT 1 FROM docs WHERE folder_id = $1 AND deleted_at IS NULL)`
+	var busy bool
+	if err := tx.QueryRow(ctx, nonEmpty, id).Scan(&busy); err != nil {
+		return err
+	}
+	if busy {
+		return domain.ErrConflict
+	}
+
+	const rehomeTrash = `
+UPDATE docs SET folder_id = (
+  SELECT id FROM folders WHERE workspace_id = $2 AND parent_id IS NULL
+)
+WHERE folder_id = $1`
+	if _, err := tx.Exec(ctx, rehomeTrash, id, folder.WorkspaceID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM folders WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"context"
+
+	"collabdocs/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const groupColumns = `id, name, COALESCE(owner_id::text, ''), COALESCE(external_id, ''), created_at`
+
+type GroupRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewGroupRepo(pool *pgxpool.Pool) *GroupRepo {
+	return &GroupRepo{pool: pool}
+}
+
+func scanGroup(row pgx.Row) (domain.Group, error) {
+	var out domain.Group
+	if err := row.Scan(&out.ID, &out.Name, &out.OwnerID, &out.ExternalID, &out.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Group{}, domain.ErrNotFound
+		}
+		return domain.Group{}, err
+	}
+	return out, nil
+}
+
+// Create inserts a group with its owner as the first member.
+func (r *GroupRepo) Create(ctx context.Context, group domain.Group) (domain.Group, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Group{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	const insertGroup = `
+INSERT INTO groups (id, name, owner_id, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING ` + groupColumns
+
+	out, err := scanGroup(tx.QueryRow(ctx, insertGroup, group.ID, group.Name, group.OwnerID, group.CreatedAt))
+	if err != nil {
+		return domain.Group{}, err
+	}
+	const insertOwner = `INSERT INTO group_members (group_id, user_id, created_at) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, insertOwner, out.ID, out.OwnerID, out.CreatedAt); err != nil {
+		return domain.Group{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Group{}, err
+	}
+	return out, nil
+}
+
+// UpsertExternal returns the group mirroring an identity provider group,
+// creating it on first use and keeping its name current.
+func (r *GroupRepo) UpsertExternal(ctx context.Context, group domain.Group) (domain.Group, error) {
+	const q = `
+INSERT INTO groups (id, name, external_id, created_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (external_id) WHERE external_id IS NOT NULL DO UPDATE SET name = EXCLUDED.name
+RETURNING ` + groupColumns
+
+	return scanGroup(r.pool.QueryRow(ctx, q, group.ID, group.Name, group.ExternalID, group.CreatedAt))
+}
+
+func (r *GroupRepo) GetByID(ctx context.Context, id string) (domain.Group, error) {
+	return scanGroup(r.pool.QueryRow(ctx, `SELECT `+groupColumns+` FROM groups WHERE id = $1`, id))
+}
+
+// ListForUser returns the groups the user is a member of.
+func (r *GroupRepo) ListForUser(ctx context.Context, userID string) ([]domain.Group, error) {
+	const q = `
+SELECT g.id, g.name, COALESCE(g.owner_id::text, ''), COALESCE(g.external_id, ''), g.created_at
+FROM groups g JOIN group_members m ON m.group_id = g.id
+WHERE m.user_id = $1
+ORDER BY lower(g.name), g.id`
+
+	rows, err := r.pool.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]domain.Group, 0)
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (r *GroupRepo) ListMembers(ctx context.Context, groupID string) ([]domain.User, error) {
+	const q = `
+SELECT u.id, u.email, u.name, u.password_hash, u.created_at, u.updated_at
+FROM group_members m JOIN users u ON u.id = m.user_id
+WHERE m.group_id = $1
+ORDER BY lower(u.name), u.id`
+
+	rows, err := r.pool.Query(ctx, q, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// AddMember adds a user to a group. Adding an existing member is a no-op.
+func (r *GroupRepo) AddMember(ctx context.Context, groupID string, userID string) error {
+	const q = `
+INSERT INTO group_members (group_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING`
+	_, err := r.pool.Exec(ctx, q, groupID, userID)
+	return err
+}
+
+func (r *GroupRepo) RemoveMember(ctx context.Context, groupID string, userID string) error {
+	res, err := r.pool.Exec(ctx, `DELETE FROM group_members WHERE group_id = $1 AND user_id = $2`, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
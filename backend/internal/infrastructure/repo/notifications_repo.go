@@ -0,0 +1,120 @@
+package repo
+
+import (
+	"context"
+
+	"collabdocs/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const notificationColumns = `id, user_id, type, doc_id, comment_id, actor_id, actor_name, excerpt, created_at, read_at`
+
+type NotificationRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewNotificationRepo(pool *pgxpool.Pool) *NotificationRepo {
+	return &NotificationRepo{pool: pool}
+}
+
+func scanNotification(row pgx.Row) (domain.Notification, error) {
+	var out domain.Notification
+	if err := row.Scan(&out.ID, &out.UserID, &out.Type, &out.DocID, &out.CommentID, &out.ActorID, &out.ActorName, &out.Excerpt, &out.CreatedAt, &out.ReadAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Notification{}, domain.ErrNotFound
+		}
+		return domain.Notification{}, err
+	}
+	return out, nil
+}
+
+// Create stores a notification. A user is notified once per comment and
+// type; repeats return ErrConflict.
+func (r *NotificationRepo) Create(ctx context.Context, n domain.Notification) (domain.Notification, error) {
+	const q = `
+INSERT INTO notifications (id, user_id, type, doc_id, comment_id, actor_id, actor_name, excerpt, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (user_id, comment_id, type) DO NOTHING
+RETURNING ` + notificationColumns
+
+	out, err := scanNotification(r.pool.QueryRow(ctx, q, n.ID, n.UserID, n.Type, n.DocID, n.CommentID, n.ActorID, n.ActorName, n.Excerpt, n.CreatedAt))
+	if err == domain.ErrNotFound {
+		return domain.Notification{}, domain.ErrConflict
+	}
+	return out, err
+}
+
+// List returns a page of a user's notifications, newest first. Notifications
+// about trashed documents or deleted comments are left out.
+func (r *NotificationRepo) List(ctx context.Context, query domain.NotificationQuery) ([]domain.Notification, error) {
+	f := &sqlFilter{}
+	f.where("n.user_id = " + f.arg(query.UserID))
+	if query.UnreadOnly {
+		f.where("n.read_at IS NULL")
+	}
+	if query.After != nil {
+		f.where("(n.created_at, n.id) < (" + f.arg(query.After.CreatedAt) + ", " + f.arg(query.After.ID) + "::uuid)")
+	}
+	q := `
+SELECT n.id, n.user_id, n.type, n.doc_id, n.comment_id, n.actor_id, n.actor_name, n.excerpt, n.created_at, n.read_at
+FROM notifications n
+JOIN docs d ON d.id = n.doc_id AND d.deleted_at IS NULL
+JOIN doc_comments c ON c.id = n.comment_id AND c.deleted_at IS NULL
+` + f.clause() + `
+ORDER BY n.created_at DESC, n.id DESC
+LIMIT ` + f.arg(query.Limit)
+
+	rows, err := r.pool.Query(ctx, q, f.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Notification, 0)
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// CountUnread counts the unread notifications List would return.
+func (r *NotificationRepo) CountUnread(ctx context.Context, userID string) (int, error) {
+	const q = `
+SELECT count(*)
+FROM notifications n
+JOIN docs d ON d.id = n.doc_id AND d.deleted_at IS NULL
+JOIN doc_comments c ON c.id = n.comment_id AND c.deleted_at IS NULL
+WHERE n.user_id = $1 AND n.read_at IS NULL`
+
+	var count int
+	err := r.pool.QueryRow(ctx, q, userID).Scan(&count)
+	return count, err
+}
+
+// MarkRead marks one of the user's notifications read. Marking it again keeps
+// the first read time.
+func (r *NotificationRepo) MarkRead(ctx context.Context, userID string, id string) (domain.Notification, error) {
+	const q = `
+UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2
+RETURNING ` + notificationColumns
+
+	return scanNotification(r.pool.QueryRow(ctx, q, id, userID))
+}
+
+// MarkAllRead marks every unread notification of the user read and returns
+// how many there were.
+func (r *NotificationRepo) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	const q = `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`
+
+	res, err := r.pool.Exec(ctx, q, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,9 @@
+package repo
+
+// nullableString maps the empty string to SQL NULL.
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
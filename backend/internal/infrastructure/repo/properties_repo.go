@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"collabdocs/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const propertyColumns = `id, workspace_id, key, name, type, options, created_at, updated_at`
+
+var propertyOperators = map[domain.PropertyOp]string{
+	domain.PropertyEq:  "=",
+	domain.PropertyGt:  ">",
+	domain.PropertyGte: ">=",
+	domain.PropertyLt:  "<",
+	domain.PropertyLte: "<=",
+}
+
+type PropertyRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPropertyRepo(pool *pgxpool.Pool) *PropertyRepo {
+	return &PropertyRepo{pool: pool}
+}
+
+func scanProperty(row pgx.Row) (domain.PropertyDefinition, error) {
+	var out domain.PropertyDefinition
+	if err := row.Scan(&out.ID, &out.WorkspaceID, &out.Key, &out.Name, &out.Type, &out.Options, &out.CreatedAt, &out.UpdatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.PropertyDefinition{}, domain.ErrNotFound
+		}
+		return domain.PropertyDefinition{}, err
+	}
+	return out, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// propertyCondition matches documents whose property satisfies the filter.
+// Text comparisons only see string values and numeric ones only number
+// values; the CASE keeps strings from ever reaching the numeric cast.
+func propertyCondition(f *sqlFilter, p domain.PropertyFilter) string {
+	op := propertyOperators[p.Op]
+	key := f.arg(p.Key)
+	cond := fmt.Sprintf("(jsonb_typeof(properties->%s) = 'string' AND properties->>%s %s %s)", key, key, op, f.arg(p.Text))
+	if p.Number != nil {
+		cond = fmt.Sprintf("(%s OR CASE WHEN jsonb_typeof(properties->%s) = 'number' THEN (properties->>%s)::numeric END %s %s::numeric)",
+			cond, key, key, op, f.arg(*p.Number))
+	}
+	return cond
+}
+
+func (r *PropertyRepo) CreateDefinition(ctx context.Context, def domain.PropertyDefinition) (domain.PropertyDefinition, error) {
+	const q = `
+INSERT INTO property_definitions (id, workspace_id, key, name, type, options, created_at, updated_at)
+SELECT $1, w.id, $3, $4, $5, $6, $7, $8
+FROM workspaces w
+WHERE w.id = $2
+RETURNING ` + propertyColumns
+
+	out, err := scanProperty(r.pool.QueryRow(ctx, q, def.ID, def.WorkspaceID, def.Key, def.Name, def.Type, def.Options, def.CreatedAt, def.UpdatedAt))
+	if isUniqueViolation(err) {
+		return domain.PropertyDefinition{}, domain.ErrConflict
+	}
+	return out, err
+}
+
+func (r *PropertyRepo) ListDefinitions(ctx context.Context, workspaceID string) ([]domain.PropertyDefinition, error) {
+	const q = `SELECT ` + propertyColumns + ` FROM property_definitions WHERE workspace_id = $1 ORDER BY name, key`
+	return r.list(ctx, q, workspaceID)
+}
+
+// ListDefinitionsForFolder returns the schema of the workspace the folder
+// belongs to.
+func (r *PropertyRepo) ListDefinitionsForFolder(ctx context.Context, folderID string) ([]domain.PropertyDefinition, error) {
+	const q = `
+SELECT ` + propertyColumns + `
+FROM property_definitions
+WHERE workspace_id = (SELECT workspace_id FROM folders WHERE id = $1)
+ORDER BY name, key`
+	return r.list(ctx, q, folderID)
+}
+
+func (r *PropertyRepo) list(ctx context.Context, q string, args ...any) ([]domain.PropertyDefinition, error) {
+	rows, err := r.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	defs := make([]domain.PropertyDefinition, 0)
+	for rows.Next() {
+		d, err := scanProperty(rows)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, d)
+	}
+	return defs, rows.Err()
+}
+
+func (r *PropertyRepo) UpdateDefinition(ctx context.Context, workspaceID string, key string, name string, options []string) (domain.PropertyDefinition, error) {
+	const q = `
+UPDATE property_definitions
+SET name = $3, options = $4, updated_at = NOW()
+WHERE workspace_id = $1 AND key = $2
+RETURNING ` + propertyColumns
+
+	return scanProperty(r.pool.QueryRow(ctx, q, workspaceID, key, name, options))
+}
+
+// DeleteDefinition removes a property from the workspace schema and clears
+// its values from the workspace's documents.
+func (r *PropertyRepo) DeleteDefinition(ctx context.Context, workspaceID string, key string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, `DELETE FROM property_definitions WHERE workspace_id = $1 AND key = $2`, workspaceID, key)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	const clearValues = `
+UPDATE docs SET properties = properties - $2::text, version = version + 1
+WHERE properties ? $2::text AND folder_id IN (SELECT id FROM folders WHERE workspace_id = $1)`
+	if _, err := tx.Exec(ctx, clearValues, workspaceID, key); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
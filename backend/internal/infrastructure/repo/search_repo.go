@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	"collabdocs/internal/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Highlight delimiters handed to ts_headline. They are removed from the text
+// given to ts_headline, so the snippet can be escaped before they are
+// replaced with markup.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+// withoutDelimiters removes the highlight delimiters from text.
+var withoutDelimiters = strings.NewReplacer(highlightStart, "", highlightStop, "")
+
+type SearchRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSearchRepo(pool *pgxpool.Pool) *SearchRepo {
+	return &SearchRepo{pool: pool}
+}
+
+// IndexContent stores the text a document is searched by. It does not touch
+// updated_at, so reindexing leaves the order of document lists alone.
+func (r *SearchRepo) IndexContent(ctx context.Context, docID string, content string) error {
+	const q = `
+UPDATE docs
+SET content_text = $2
+WHERE id = $1 AND deleted_at IS NULL AND content_text IS DISTINCT FROM $2`
+
+	_, err := r.pool.Exec(ctx, q, docID, withoutDelimiters.Replace(content))
+	return err
+}
+
+func (r *SearchRepo) Search(ctx context.Context, query domain.SearchQuery) ([]domain.SearchResult, int, error) {
+	var f sqlFilter
+	tsquery := "websearch_to_tsquery('simple', " + f.arg(query.Query) + ")"
+	f.where("search_vector @@ q.query")
+	f.where("deleted_at IS NULL")
+	if query.ViewerID != "" {
+		f.where(accessCondition(&f, query.ViewerID))
+	}
+	if query.IDs != nil {
+		f.where("docs.id = ANY(" + f.arg(query.IDs) + "::uuid[])")
+	}
+	if query.UpdatedAfter != nil {
+		f.where("updated_at >= " + f.arg(*query.UpdatedAfter))
+	}
+	if query.UpdatedBefore != nil {
+		f.where("updated_at < " + f.arg(*query.UpdatedBefore))
+	}
+	if len(query.Tags) > 0 {
+		f.where(tagCondition(&f, query.Tags, query.AllTags))
+	}
+	for _, p := range query.Properties {
+		f.where(propertyCondition(&f, p))
+	}
+	with := "WITH q AS (SELECT " + tsquery + " AS query)\n"
+
+	var total int
+	if err := r.pool.QueryRow(ctx, with+"SELECT COUNT(*) FROM docs, q "+f.clause(), f.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return make([]domain.SearchResult, 0), 0, nil
+	}
+
+	opts := f.arg("StartSel=" + highlightStart + ", StopSel=" + highlightStop)
+	q := with + `SELECT ` + documentColumns + `,
+  ts_rank_cd(search_vector, q.query) AS rank,
+  ts_headline('simple', translate(title, ` + f.arg(highlightStart+highlightStop) + `, ''), q.query, ` + opts + ` || ', HighlightAll=true'),
+  ts_headline('simple', content_text, q.query, ` + opts + ` || ', MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "')
+FROM docs, q
+` + f.clause() + `
+ORDER BY rank DESC, updated_at DESC, id
+LIMIT ` + f.arg(query.Limit) + ` OFFSET ` + f.arg(query.Offset)
+
+	rows, err := r.pool.Query(ctx, q, f.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	results := make([]domain.SearchResult, 0)
+	for rows.Next() {
+		var res domain.SearchResult
+		var rank float32
+		dest := append(documentFields(&res.Document), &rank, &res.TitleHighlight, &res.Snippet)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, 0, err
+		}
+		res.Rank = float64(rank)
+		res.TitleHighlight = highlight(res.TitleHighlight)
+		res.Snippet = highlight(res.Snippet)
+		results = append(results, res)
+	}
+	return results, total, rows.Err()
+}
+
+func highlight(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, highlightStart, "<mark>")
+	return strings.ReplaceAll(s, highlightStop, "</mark>")
+}
@@ -0,0 +1,139 @@
+package repo
+
+import (
+	"context"
+
+	"collabdocs/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const shareLinkColumns = `id, doc_id, role, password_hash, expires_at, max_uses, use_count, created_by, created_at, revoked_at`
+
+type ShareLinkRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewShareLinkRepo(pool *pgxpool.Pool) *ShareLinkRepo {
+	return &ShareLinkRepo{pool: pool}
+}
+
+func scanShareLink(row pgx.Row) (domain.ShareLink, error) {
+	var (
+		out  domain.ShareLink
+		hash *string
+	)
+	if err := row.Scan(&out.ID, &out.DocID, &out.Role, &hash, &out.ExpiresAt, &out.MaxUses, &out.UseCount, &out.CreatedBy, &out.CreatedAt, &out.RevokedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.ShareLink{}, domain.ErrNotFound
+		}
+		return domain.ShareLink{}, err
+	}
+	if hash != nil {
+		out.PasswordHash = *hash
+		out.HasPassword = true
+	}
+	return out, nil
+}
+
+func (r *ShareLinkRepo) Create(ctx context.Context, link domain.ShareLink) (domain.ShareLink, error) {
+	const q = `
+INSERT INTO share_links (id, doc_id, role, password_hash, expires_at, max_uses, created_by, created_at)
+SELECT $1, id, $3, $4, $5, $6, $7, $8
+FROM docs
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING ` + shareLinkColumns
+
+	return scanShareLink(r.pool.QueryRow(ctx, q, link.ID, link.DocID, link.Role, nullableString(link.PasswordHash), link.ExpiresAt, link.MaxUses, link.CreatedBy, link.CreatedAt))
+}
+
+// GetByID returns a link of a live document.
+func (r *ShareLinkRepo) GetByID(ctx context.Context, id string) (domain.ShareLink, error) {
+	const q = `
+SELECT ` + shareLinkColumns + ` FROM share_links
+WHERE id = $1 AND EXISTS (SELECT 1 FROM docs WHERE docs.id = share_links.doc_id AND docs.deleted_at IS NULL)`
+
+	return scanShareLink(r.pool.QueryRow(ctx, q, id))
+}
+
+func (r *ShareLinkRepo) ListByDoc(ctx context.Context, docID string) ([]domain.ShareLink, error) {
+	const q = `SELECT ` + shareLinkColumns + ` FROM share_links WHERE doc_id = $1 ORDER BY created_at DESC, id`
+
+	rows, err := r.pool.Query(ctx, q, docID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]domain.ShareLink, 0)
+	for rows.Next() {
+		l, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// Revoke disables a link. Revoking it again keeps the first revocation time.
+func (r *ShareLinkRepo) Revoke(ctx context.Context, docID string, id string) (domain.ShareLink, error) {
+	const q = `
+UPDATE share_links SET revoked_at = COALESCE(revoked_at, NOW())
+WHERE id = $1 AND doc_id = $2
+RETURNING ` + shareLinkColumns
+
+	return scanShareLink(r.pool.QueryRow(ctx, q, id, docID))
+}
+
+// Consume counts one use of a link, unless it was revoked, expired or used
+// up in the meantime, in which case ErrConflict is returned.
+func (r *ShareLinkRepo) Consume(ctx context.Context, id string) (domain.ShareLink, error) {
+	const q = `
+UPDATE share_links SET use_count = use_count + 1
+WHERE id = $1 AND revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > NOW())
+  AND (max_uses IS NULL OR use_count < max_uses)
+RETURNING ` + shareLinkColumns
+
+	out, err := scanShareLink(r.pool.QueryRow(ctx, q, id))
+	if err == domain.ErrNotFound {
+		return domain.ShareLink{}, domain.ErrConflict
+	}
+	return out, err
+}
+
+func (r *ShareLinkRepo) LogAccess(ctx context.Context, entry domain.ShareLinkAccess) error {
+	const q = `
+INSERT INTO share_link_access (id, link_id, action, outcome, user_id, ip, user_agent, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.pool.Exec(ctx, q, entry.ID, entry.LinkID, entry.Action, entry.Outcome, entry.UserID, entry.IP, entry.UserAgent, entry.CreatedAt)
+	return err
+}
+
+// ListAccess returns the newest log entries of a link of the document.
+func (r *ShareLinkRepo) ListAccess(ctx context.Context, docID string, linkID string, limit int) ([]domain.ShareLinkAccess, error) {
+	const q = `
+SELECT a.id, a.link_id, a.action, a.outcome, a.user_id, a.ip, a.user_agent, a.created_at
+FROM share_link_access a JOIN share_links l ON l.id = a.link_id
+WHERE a.link_id = $1 AND l.doc_id = $2
+ORDER BY a.created_at DESC, a.id
+LIMIT $3`
+
+	rows, err := r.pool.Query(ctx, q, linkID, docID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]domain.ShareLinkAccess, 0)
+	for rows.Next() {
+		var e domain.ShareLinkAccess
+		if err := rows.Scan(&e.ID, &e.LinkID, &e.Action, &e.Outcome, &e.UserID, &e.IP, &e.UserAgent, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -3,7 +3,6 @@ package repo
 import (
 	"context"
 
-	"collabdocs/internal/domain"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -17,7 +16,11 @@ func NewSnapshotRepo(pool *pgxpool.Pool) *SnapshotRepo {
 }
 
 func (r *SnapshotRepo) GetSnapshot(ctx context.Context, docID string) ([]byte, error) {
-	const q = `SELECT snapshot FROM doc_snapshots WHERE doc_id = $1`
+	const q = `
+SELECT s.snapshot
+FROM doc_snapshots s
+JOIN docs d ON d.id = s.doc_id
+WHERE s.doc_id = $1 AND d.deleted_at IS NULL`
 	row := r.pool.QueryRow(ctx, q, docID)
 	var snapshot []byte
 	if err := row.Scan(&snapshot); err != nil {
@@ -29,15 +32,18 @@ func (r *SnapshotRepo) GetSnapshot(ctx context.Context, docID string) ([]byte, e
 	return snapshot, nil
 }
 
+// UpsertSnapshot stores the latest snapshot. Snapshots of missing documents
+// are dropped; read-only documents reject them with ErrReadOnly.
 func (r *SnapshotRepo) UpsertSnapshot(ctx context.Context, docID string, snapshot []byte) error {
-	const q = `
+	q := `
 INSERT INTO doc_snapshots (doc_id, snapshot, updated_at)
 SELECT $1, $2, NOW()
-WHERE EXISTS (SELECT 1 FROM docs WHERE id = $1)
+WHERE ` + editableDoc("$1") + `
 ON CONFLICT (doc_id) DO UPDATE SET snapshot = EXCLUDED.snapshot, updated_at = NOW()`
 
-	_, err := r.pool.Exec(ctx, q, docID, snapshot)
-	return err
+	res, err := r.pool.Exec(ctx, q, docID, snapshot)
+	if err != nil || res.RowsAffected() > 0 {
+		return err
+	}
+	return rejectReadOnly(ctx, r.pool, docID)
 }
-
-var _ = domain.ErrNotFound
@@ -0,0 +1,58 @@
+package repo
+
+import (
+	"context"
+
+	"collabdocs/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+// querier is the part of pgxpool.Pool and pgx.Tx used by shared helpers.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// editableDoc is a condition matching when the document whose ID is in the
+// given placeholder accepts content changes.
+func editableDoc(param string) string {
+	return `EXISTS (SELECT 1 FROM docs WHERE id = ` + param + ` AND deleted_at IS NULL AND state = 'active')`
+}
+
+// commentableDoc is a condition matching when the document whose ID is in the
+// given placeholder accepts comment changes.
+func commentableDoc(param string) string {
+	return `EXISTS (
+  SELECT 1 FROM docs WHERE id = ` + param + ` AND deleted_at IS NULL
+    AND (state = 'active' OR (state = 'locked' AND allow_comments))
+)`
+}
+
+// loadDocState returns ErrNotFound for missing or trashed documents and the
+// document's state otherwise.
+func loadDocState(ctx context.Context, q querier, docID string) (domain.Document, error) {
+	var doc domain.Document
+	err := q.QueryRow(ctx, `SELECT id, state, allow_comments FROM docs WHERE id = $1 AND deleted_at IS NULL`, docID).
+		Scan(&doc.ID, &doc.State, &doc.AllowComments)
+	if err == pgx.ErrNoRows {
+		return domain.Document{}, domain.ErrNotFound
+	}
+	return doc, err
+}
+
+// rejectReadOnly is called after a content write matched no document. It
+// returns ErrReadOnly if the document exists but is read-only, and nil when
+// it is missing so such writes keep being dropped silently.
+func rejectReadOnly(ctx context.Context, q querier, docID string) error {
+	doc, err := loadDocState(ctx, q, docID)
+	if err == domain.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if doc.ReadOnly() {
+		return domain.ErrReadOnly
+	}
+	return nil
+}
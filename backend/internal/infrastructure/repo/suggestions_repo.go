@@ -0,0 +1,186 @@
+package repo
+
+import (
+	"context"
+
+	"collabdocs/internal/domain"
+	"collabdocs/pkg/yjs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const suggestionColumns = `id, doc_id, author_id, author_name, description, from_pos, to_pos, update, status, decided_by, decided_by_name, decided_at, created_at`
+
+type SuggestionRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSuggestionRepo(pool *pgxpool.Pool) *SuggestionRepo {
+	return &SuggestionRepo{pool: pool}
+}
+
+func scanSuggestion(row pgx.Row) (domain.Suggestion, error) {
+	var out domain.Suggestion
+	if err := row.Scan(&out.ID, &out.DocID, &out.AuthorID, &out.AuthorName, &out.Description, &out.FromPos, &out.ToPos, &out.Update, &out.Status, &out.DecidedBy, &out.DecidedByName, &out.DecidedAt, &out.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Suggestion{}, domain.ErrNotFound
+		}
+		return domain.Suggestion{}, err
+	}
+	return out, nil
+}
+
+// ListByDoc returns the suggestions of a document, oldest first. A non-empty
+// status lists only the suggestions in that state.
+func (r *SuggestionRepo) ListByDoc(ctx context.Context, docID string, status string) ([]domain.Suggestion, error) {
+	const q = `
+SELECT ` + suggestionColumns + `
+FROM doc_suggestions
+WHERE doc_id = $1 AND ($2 = '' OR status = $2)
+  AND EXISTS (SELECT 1 FROM docs WHERE id = $1 AND deleted_at IS NULL)
+ORDER BY created_at, id`
+
+	rows, err := r.pool.Query(ctx, q, docID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := make([]domain.Suggestion, 0)
+	for rows.Next() {
+		s, err := scanSuggestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
+
+func (r *SuggestionRepo) GetByID(ctx context.Context, docID string, id string) (domain.Suggestion, error) {
+	return getSuggestion(ctx, r.pool, docID, id)
+}
+
+func getSuggestion(ctx context.Context, q querier, docID string, id string) (domain.Suggestion, error) {
+	const query = `
+SELECT ` + suggestionColumns + `
+FROM doc_suggestions
+WHERE id = $1 AND doc_id = $2
+  AND EXISTS (SELECT 1 FROM docs WHERE id = $2 AND deleted_at IS NULL)`
+
+	return scanSuggestion(q.QueryRow(ctx, query, id, docID))
+}
+
+// Create stores an open suggestion. Suggestions are accepted wherever
+// comments are, so documents locked without comments reject them with
+// ErrReadOnly.
+func (r *SuggestionRepo) Create(ctx context.Context, s domain.Suggestion) (domain.Suggestion, error) {
+	q := `
+INSERT INTO doc_suggestions (id, doc_id, author_id, author_name, description, from_pos, to_pos, update, status, created_at)
+SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+WHERE ` + commentableDoc("$2") + `
+RETURNING ` + suggestionColumns
+
+	out, err := scanSuggestion(r.pool.QueryRow(ctx, q, s.ID, s.DocID, s.AuthorID, s.AuthorName, s.Description, s.FromPos, s.ToPos, s.Update, domain.SuggestionOpen, s.CreatedAt))
+	if err != domain.ErrNotFound {
+		return out, err
+	}
+	doc, err := loadDocState(ctx, r.pool, s.DocID)
+	if err != nil {
+		return domain.Suggestion{}, err
+	}
+	if !doc.CommentsAllowed() {
+		return domain.Suggestion{}, domain.ErrReadOnly
+	}
+	return domain.Suggestion{}, domain.ErrNotFound
+}
+
+// Decide records the outcome of an open suggestion. Suggestions that were
+// already decided return ErrConflict.
+func (r *SuggestionRepo) Decide(ctx context.Context, docID string, id string, decision domain.SuggestionDecision) (domain.Suggestion, error) {
+	return decide(ctx, r.pool, docID, id, decision)
+}
+
+func decide(ctx context.Context, q querier, docID string, id string, decision domain.SuggestionDecision) (domain.Suggestion, error) {
+	const query = `
+UPDATE doc_suggestions
+SET status = $3, decided_by = $4, decided_by_name = $5, decided_at = $6
+WHERE id = $1 AND doc_id = $2 AND status = 'open'
+  AND EXISTS (SELECT 1 FROM docs WHERE id = $2 AND deleted_at IS NULL)
+RETURNING ` + suggestionColumns
+
+	out, err := scanSuggestion(q.QueryRow(ctx, query, id, docID, decision.Status, decision.ActorID, decision.Actor, decision.DecidedAt))
+	if err != domain.ErrNotFound {
+		return out, err
+	}
+	if _, err := getSuggestion(ctx, q, docID, id); err != nil {
+		return domain.Suggestion{}, err
+	}
+	return domain.Suggestion{}, domain.ErrConflict
+}
+
+// Accept marks an open suggestion accepted and merges its update into the
+// document's snapshot in one transaction, returning the suggestion and the
+// new snapshot. The snapshot row is locked while the stored snapshot, the
+// updates logged since it was saved and the suggestion are merged, so
+// concurrent snapshot writes are neither lost nor lose the suggestion. The
+// update is logged like a client's. Suggestions that were already decided
+// return ErrConflict and read-only documents ErrReadOnly.
+func (r *SuggestionRepo) Accept(ctx context.Context, docID string, id string, decision domain.SuggestionDecision) (domain.Suggestion, []byte, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Suggestion{}, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	out, err := decide(ctx, tx, docID, id, decision)
+	if err != nil {
+		return domain.Suggestion{}, nil, err
+	}
+
+	const lockSnapshot = `SELECT snapshot FROM doc_snapshots WHERE doc_id = $1 FOR UPDATE`
+	var snapshot []byte
+	if err := tx.QueryRow(ctx, lockSnapshot, docID).Scan(&snapshot); err != nil && err != pgx.ErrNoRows {
+		return domain.Suggestion{}, nil, err
+	}
+	pending, err := listPending(ctx, tx, docID)
+	if err != nil {
+		return domain.Suggestion{}, nil, err
+	}
+	updates := make([][]byte, 0, len(pending)+2)
+	if len(snapshot) > 0 {
+		updates = append(updates, snapshot)
+	}
+	updates = append(append(updates, pending...), out.Update)
+	merged := out.Update
+	if len(updates) > 1 {
+		if merged, err = yjs.MergeUpdates(updates...); err != nil {
+			return domain.Suggestion{}, nil, err
+		}
+	}
+
+	saveSnapshot := `
+INSERT INTO doc_snapshots (doc_id, snapshot, updated_at)
+SELECT $1, $2, NOW()
+WHERE ` + editableDoc("$1") + `
+ON CONFLICT (doc_id) DO UPDATE SET snapshot = EXCLUDED.snapshot, updated_at = NOW()`
+	res, err := tx.Exec(ctx, saveSnapshot, docID, merged)
+	if err != nil {
+		return domain.Suggestion{}, nil, err
+	}
+	if res.RowsAffected() == 0 {
+		if err := rejectReadOnly(ctx, tx, docID); err != nil {
+			return domain.Suggestion{}, nil, err
+		}
+		return domain.Suggestion{}, nil, domain.ErrNotFound
+	}
+	const logUpdate = `INSERT INTO doc_updates (doc_id, update, created_at) VALUES ($1, $2, NOW())`
+	if _, err := tx.Exec(ctx, logUpdate, docID, out.Update); err != nil {
+		return domain.Suggestion{}, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Suggestion{}, nil, err
+	}
+	return out, merged, nil
+}
@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"collabdocs/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// docTagNames selects the tags of document $1 in display order.
+const docTagNames = `
+SELECT ARRAY(
+  SELECT t.name FROM doc_tags dt JOIN tags t ON t.id = dt.tag_id
+  WHERE dt.doc_id = $1 ORDER BY lower(t.name)
+)`
+
+type TagRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewTagRepo(pool *pgxpool.Pool) *TagRepo {
+	return &TagRepo{pool: pool}
+}
+
+// tagCondition matches documents carrying any of the tags, or all of them
+// when all is set. Tag names compare case-insensitively.
+func tagCondition(f *sqlFilter, tags []string, all bool) string {
+	names := f.arg(tags)
+	match := `
+  SELECT %s FROM doc_tags dt JOIN tags t ON t.id = dt.tag_id
+  WHERE dt.doc_id = docs.id
+    AND lower(t.name) IN (SELECT lower(n) FROM unnest(` + names + `::text[]) n)`
+	if !all {
+		return "EXISTS (" + fmt.Sprintf(match, "1") + "\n)"
+	}
+	return "(" + fmt.Sprintf(match, "COUNT(*)") + "\n) = (SELECT COUNT(DISTINCT lower(n)) FROM unnest(" + names + "::text[]) n)"
+}
+
+// lockLiveDocument fails with ErrNotFound unless the document exists and is
+// not in the trash, and keeps it from being purged until tx ends.
+func lockLiveDocument(ctx context.Context, tx pgx.Tx, docID string) error {
+	var id string
+	err := tx.QueryRow(ctx, `SELECT id FROM docs WHERE id = $1 AND deleted_at IS NULL FOR SHARE`, docID).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return domain.ErrNotFound
+	}
+	return err
+}
+
+// touchDocument records a metadata change made outside the docs row.
+func touchDocument(ctx context.Context, tx pgx.Tx, docID string) error {
+	_, err := tx.Exec(ctx, `UPDATE docs SET version = version + 1, updated_at = NOW() WHERE id = $1`, docID)
+	return err
+}
+
+// AddTags attaches the named tags to a document, creating tags that do not
+// exist yet. Existing tags keep the spelling they were created with.
+func (r *TagRepo) AddTags(ctx context.Context, docID string, names []string) ([]string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockLiveDocument(ctx, tx, docID); err != nil {
+		return nil, err
+	}
+
+	const upsertTag = `
+INSERT INTO tags (id, name, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (lower(name)) DO UPDATE SET name = tags.name
+RETURNING id`
+	const attach = `
+INSERT INTO doc_tags (doc_id, tag_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING`
+	var attached int64
+	for _, name := range names {
+		var tagID string
+		if err := tx.QueryRow(ctx, upsertTag, uuid.New().String(), name).Scan(&tagID); err != nil {
+			return nil, err
+		}
+		res, err := tx.Exec(ctx, attach, docID, tagID)
+		if err != nil {
+			return nil, err
+		}
+		attached += res.RowsAffected()
+	}
+	if attached > 0 {
+		if err := touchDocument(ctx, tx, docID); err != nil {
+			return nil, err
+		}
+	}
+
+	var tags []string
+	if err := tx.QueryRow(ctx, docTagNames, docID).Scan(&tags); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// RemoveTag detaches a tag from a document. Removing a tag the document does
+// not carry is not an error. Tags left without documents are kept.
+func (r *TagRepo) RemoveTag(ctx context.Context, docID string, name string) ([]string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockLiveDocument(ctx, tx, docID); err != nil {
+		return nil, err
+	}
+
+	const detach = `
+DELETE FROM doc_tags
+WHERE doc_id = $1 AND tag_id IN (SELECT id FROM tags WHERE lower(name) = lower($2))`
+	res, err := tx.Exec(ctx, detach, docID, name)
+	if err != nil {
+		return nil, err
+	}
+	if res.RowsAffected() > 0 {
+		if err := touchDocument(ctx, tx, docID); err != nil {
+			return nil, err
+		}
+	}
+
+	var tags []string
+	if err := tx.QueryRow(ctx, docTagNames, docID).Scan(&tags); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// ListTags returns the tags used on live documents the user can view, with
+// the number of such documents carrying each.
+func (r *TagRepo) ListTags(ctx context.Context, viewerID string) ([]domain.Tag, error) {
+	q := `
+SELECT t.name, COUNT(*)
+FROM tags t
+JOIN doc_tags dt ON dt.tag_id = t.id
+JOIN docs ON docs.id = dt.doc_id AND docs.deleted_at IS NULL
+WHERE docs.id IN (SELECT doc_id FROM doc_acl WHERE ` + userGrants("$1::uuid") + `)
+GROUP BY t.id, t.name
+ORDER BY COUNT(*) DESC, lower(t.name)`
+
+	rows, err := r.pool.Query(ctx, q, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]domain.Tag, 0)
+	for rows.Next() {
+		var t domain.Tag
+		if err := rows.Scan(&t.Name, &t.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
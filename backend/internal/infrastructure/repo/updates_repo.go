@@ -14,11 +14,47 @@ func NewUpdateRepo(pool *pgxpool.Pool) *UpdateRepo {
 	return &UpdateRepo{pool: pool}
 }
 
+// AppendUpdate stores an incremental update. Updates to missing documents are
+// dropped; read-only documents reject them with ErrReadOnly.
 func (r *UpdateRepo) AppendUpdate(ctx context.Context, docID string, update []byte) error {
-	const q = `
+	q := `
 INSERT INTO doc_updates (doc_id, update, created_at)
 SELECT $1, $2, NOW()
-WHERE EXISTS (SELECT 1 FROM docs WHERE id = $1)`
-	_, err := r.pool.Exec(ctx, q, docID, update)
-	return err
+WHERE ` + editableDoc("$1")
+	res, err := r.pool.Exec(ctx, q, docID, update)
+	if err != nil || res.RowsAffected() > 0 {
+		return err
+	}
+	return rejectReadOnly(ctx, r.pool, docID)
+}
+
+// ListPending returns the updates stored after the document's snapshot was
+// last saved, oldest first. Without a snapshot every update is pending.
+func (r *UpdateRepo) ListPending(ctx context.Context, docID string) ([][]byte, error) {
+	return listPending(ctx, r.pool, docID)
+}
+
+func listPending(ctx context.Context, q querier, docID string) ([][]byte, error) {
+	const query = `
+SELECT u.update
+FROM doc_updates u
+WHERE u.doc_id = $1
+  AND u.created_at > COALESCE((SELECT updated_at FROM doc_snapshots WHERE doc_id = $1), '-infinity')
+ORDER BY u.id`
+
+	rows, err := q.Query(ctx, query, docID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	updates := make([][]byte, 0)
+	for rows.Next() {
+		var update []byte
+		if err := rows.Scan(&update); err != nil {
+			return nil, err
+		}
+		updates = append(updates, update)
+	}
+	return updates, rows.Err()
 }
@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"context"
+
+	"collabdocs/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const userColumns = `id, email, name, password_hash, created_at, updated_at`
+
+type UserRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewUserRepo(pool *pgxpool.Pool) *UserRepo {
+	return &UserRepo{pool: pool}
+}
+
+func scanUser(row pgx.Row) (domain.User, error) {
+	var out domain.User
+	if err := row.Scan(&out.ID, &out.Email, &out.Name, &out.PasswordHash, &out.CreatedAt, &out.UpdatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.User{}, domain.ErrNotFound
+		}
+		return domain.User{}, err
+	}
+	return out, nil
+}
+
+// Create inserts a user. Emails are unique regardless of case; a taken email
+// returns ErrConflict.
+func (r *UserRepo) Create(ctx context.Context, user domain.User) (domain.User, error) {
+	const q = `
+INSERT INTO users (id, email, name, password_hash, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING ` + userColumns
+
+	out, err := scanUser(r.pool.QueryRow(ctx, q, user.ID, user.Email, user.Name, user.PasswordHash, user.CreatedAt, user.UpdatedAt))
+	if isUniqueViolation(err) {
+		return domain.User{}, domain.ErrConflict
+	}
+	return out, err
+}
+
+func (r *UserRepo) GetByID(ctx context.Context, id string) (domain.User, error) {
+	const q = `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	return scanUser(r.pool.QueryRow(ctx, q, id))
+}
+
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (domain.User, error) {
+	const q = `SELECT ` + userColumns + ` FROM users WHERE lower(email) = lower($1)`
+	return scanUser(r.pool.QueryRow(ctx, q, email))
+}
+
+// ListByMentions returns the users a set of lowercase mention handles may
+// refer to: handles are matched against whole emails and against the part
+// before the @.
+func (r *UserRepo) ListByMentions(ctx context.Context, handles []string) ([]domain.User, error) {
+	const q = `
+SELECT ` + userColumns + ` FROM users
+WHERE lower(email) = ANY($1) OR lower(split_part(email, '@', 1)) = ANY($1)
+ORDER BY id`
+
+	rows, err := r.pool.Query(ctx, q, handles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// GetByIdentity returns the user linked to an identity provider account.
+func (r *UserRepo) GetByIdentity(ctx context.Context, issuer string, subject string) (domain.User, error) {
+	const q = `
+SELECT u.id, u.email, u.name, u.password_hash, u.created_at, u.updated_at
+FROM user_identities i JOIN users u ON u.id = i.user_id
+WHERE i.issuer = $1 AND i.subject = $2`
+	return scanUser(r.pool.QueryRow(ctx, q, issuer, subject))
+}
+
+// CreateWithIdentity inserts a user signing in through an identity provider
+// for the first time, linked to the provider account. A taken email returns
+// ErrConflict.
+func (r *UserRepo) CreateWithIdentity(ctx context.Context, user domain.User, issuer string, subject string) (domain.User, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.User{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	const insertUser = `
+INSERT INTO users (id, email, name, password_hash, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING ` + userColumns
+
+	out, err := scanUser(tx.QueryRow(ctx, insertUser, user.ID, user.Email, user.Name, user.PasswordHash, user.CreatedAt, user.UpdatedAt))
+	if isUniqueViolation(err) {
+		return domain.User{}, domain.ErrConflict
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+	const insertIdentity = `INSERT INTO user_identities (issuer, subject, user_id, created_at) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(ctx, insertIdentity, issuer, subject, out.ID, out.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return domain.User{}, domain.ErrConflict
+		}
+		return domain.User{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.User{}, err
+	}
+	return out, nil
+}
+
+// LinkIdentity links an existing user to an identity provider account. An
+// account already linked returns ErrConflict.
+func (r *UserRepo) LinkIdentity(ctx context.Context, userID string, issuer string, subject string) error {
+	const q = `INSERT INTO user_identities (issuer, subject, user_id, created_at) VALUES ($1, $2, $3, NOW())`
+	_, err := r.pool.Exec(ctx, q, issuer, subject, userID)
+	if isUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+func (r *UserRepo) UpdateName(ctx context.Context, id string, name string) (domain.User, error) {
+	const q = `
+UPDATE users SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING ` + userColumns
+	return scanUser(r.pool.QueryRow(ctx, q, id, name))
+}
+
+type SessionRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSessionRepo(pool *pgxpool.Pool) *SessionRepo {
+	return &SessionRepo{pool: pool}
+}
+
+func (r *SessionRepo) Create(ctx context.Context, session domain.Session) error {
+	const q = `
+INSERT INTO sessions (token_hash, user_id, created_at, expires_at)
+VALUES ($1, $2, $3, $4)`
+
+	_, err := r.pool.Exec(ctx, q, session.TokenHash, session.UserID, session.CreatedAt, session.ExpiresAt)
+	return err
+}
+
+// GetUser returns the user owning an unexpired session.
+func (r *SessionRepo) GetUser(ctx context.Context, tokenHash string) (domain.User, error) {
+	const q = `
+SELECT u.id, u.email, u.name, u.password_hash, u.created_at, u.updated_at
+FROM sessions s JOIN users u ON u.id = s.user_id
+WHERE s.token_hash = $1 AND s.expires_at > NOW()`
+
+	return scanUser(r.pool.QueryRow(ctx, q, tokenHash))
+}
+
+func (r *SessionRepo) Delete(ctx context.Context, tokenHash string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM sessions WHERE token_hash = $1`, tokenHash)
+	return err
+}
+
+func (r *SessionRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.pool.Exec(ctx, `DELETE FROM sessions WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,216 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"collabdocs/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const webhookColumns = `id, user_id, doc_id, url, events, secret, created_at`
+
+const deliveryColumns = `id, webhook_id, event_id, event, payload, status, attempts, last_status_code, last_error, next_attempt_at, delivered_at, redelivery_of, created_at`
+
+type WebhookRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookRepo(pool *pgxpool.Pool) *WebhookRepo {
+	return &WebhookRepo{pool: pool}
+}
+
+func scanWebhook(row pgx.Row) (domain.Webhook, error) {
+	var out domain.Webhook
+	if err := row.Scan(&out.ID, &out.UserID, &out.DocID, &out.URL, &out.Events, &out.Secret, &out.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Webhook{}, domain.ErrNotFound
+		}
+		return domain.Webhook{}, err
+	}
+	return out, nil
+}
+
+func deliveryDest(d *domain.WebhookDelivery) []any {
+	return []any{&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.LastStatusCode, &d.LastError, &d.NextAttemptAt, &d.DeliveredAt, &d.RedeliveryOf, &d.CreatedAt}
+}
+
+func scanDelivery(row pgx.Row) (domain.WebhookDelivery, error) {
+	var out domain.WebhookDelivery
+	if err := row.Scan(deliveryDest(&out)...); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.WebhookDelivery{}, domain.ErrNotFound
+		}
+		return domain.WebhookDelivery{}, err
+	}
+	return out, nil
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, webhook domain.Webhook) (domain.Webhook, error) {
+	const q = `
+INSERT INTO webhooks (id, user_id, doc_id, url, events, secret, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING ` + webhookColumns
+
+	return scanWebhook(r.pool.QueryRow(ctx, q, webhook.ID, webhook.UserID, webhook.DocID, webhook.URL, webhook.Events, webhook.Secret, webhook.CreatedAt))
+}
+
+// ListByUser returns a user's webhooks, newest first.
+func (r *WebhookRepo) ListByUser(ctx context.Context, userID string) ([]domain.Webhook, error) {
+	const q = `SELECT ` + webhookColumns + ` FROM webhooks WHERE user_id = $1 ORDER BY created_at DESC, id`
+	return r.list(ctx, q, userID)
+}
+
+func (r *WebhookRepo) GetByID(ctx context.Context, userID string, id string) (domain.Webhook, error) {
+	const q = `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1 AND user_id = $2`
+	return scanWebhook(r.pool.QueryRow(ctx, q, id, userID))
+}
+
+// Delete removes a webhook and its delivery log.
+func (r *WebhookRepo) Delete(ctx context.Context, userID string, id string) error {
+	res, err := r.pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// ListSubscribers returns the webhooks subscribed to an event of a
+// document whose users still hold a role on it.
+func (r *WebhookRepo) ListSubscribers(ctx context.Context, docID string, event string) ([]domain.Webhook, error) {
+	q := `
+SELECT ` + webhookColumns + `
+FROM webhooks
+WHERE $2 = ANY(events) AND (webhooks.doc_id IS NULL OR webhooks.doc_id = $1)
+  AND EXISTS (SELECT 1 FROM doc_acl WHERE doc_acl.doc_id = $1 AND ` + userGrants("webhooks.user_id") + `)`
+	return r.list(ctx, q, docID, event)
+}
+
+func (r *WebhookRepo) list(ctx context.Context, q string, args ...any) ([]domain.Webhook, error) {
+	rows, err := r.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]domain.Webhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// CreateDeliveries queues deliveries.
+func (r *WebhookRepo) CreateDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	const q = `
+INSERT INTO webhook_deliveries (id, webhook_id, event_id, event, payload, status, next_attempt_at, redelivery_of, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	batch := &pgx.Batch{}
+	for _, d := range deliveries {
+		batch.Queue(q, d.ID, d.WebhookID, d.EventID, d.Event, d.Payload, d.Status, d.NextAttemptAt, d.RedeliveryOf, d.CreatedAt)
+	}
+	return r.pool.SendBatch(ctx, batch).Close()
+}
+
+// ListDeliveries returns the latest deliveries of a user's webhook, newest
+// first.
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, userID string, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	const q = `
+SELECT ` + deliveryColumns + `
+FROM webhook_deliveries
+WHERE webhook_id = $1 AND EXISTS (SELECT 1 FROM webhooks WHERE id = $1 AND user_id = $2)
+ORDER BY created_at DESC, id DESC
+LIMIT $3`
+
+	rows, err := r.pool.Query(ctx, q, webhookID, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepo) GetDelivery(ctx context.Context, userID string, webhookID string, id string) (domain.WebhookDelivery, error) {
+	const q = `
+SELECT ` + deliveryColumns + `
+FROM webhook_deliveries
+WHERE id = $1 AND webhook_id = $2 AND EXISTS (SELECT 1 FROM webhooks WHERE id = $2 AND user_id = $3)`
+
+	return scanDelivery(r.pool.QueryRow(ctx, q, id, webhookID, userID))
+}
+
+// ClaimDue returns up to limit pending deliveries whose next attempt is due
+// and pushes that attempt back by lease, so that other instances skip them
+// while they are being sent.
+func (r *WebhookRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.DueDelivery, error) {
+	const q = `
+WITH due AS (
+  SELECT id FROM webhook_deliveries
+  WHERE status = 'pending' AND next_attempt_at <= $1
+  ORDER BY next_attempt_at
+  LIMIT $3
+  FOR UPDATE SKIP LOCKED
+),
+claimed AS (
+  UPDATE webhook_deliveries d SET next_attempt_at = $2
+  FROM due WHERE d.id = due.id
+  RETURNING d.*
+)
+SELECT c.id, c.webhook_id, c.event_id, c.event, c.payload, c.status, c.attempts, c.last_status_code, c.last_error,
+  c.next_attempt_at, c.delivered_at, c.redelivery_of, c.created_at, w.url, w.secret
+FROM claimed c JOIN webhooks w ON w.id = c.webhook_id
+ORDER BY c.created_at, c.id`
+
+	rows, err := r.pool.Query(ctx, q, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := make([]domain.DueDelivery, 0)
+	for rows.Next() {
+		var d domain.DueDelivery
+		if err := rows.Scan(append(deliveryDest(&d.Delivery), &d.URL, &d.Secret)...); err != nil {
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// RecordAttempt stores the outcome of sending a delivery.
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, id string, attempt domain.WebhookAttempt) error {
+	const q = `
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+  status = $2,
+  last_status_code = $3,
+  last_error = $4,
+  next_attempt_at = $5,
+  delivered_at = CASE WHEN $2 = 'delivered' THEN $6 ELSE delivered_at END
+WHERE id = $1`
+
+	_, err := r.pool.Exec(ctx, q, id, attempt.Status, attempt.StatusCode, attempt.Error, attempt.NextAttemptAt, attempt.At)
+	return err
+}
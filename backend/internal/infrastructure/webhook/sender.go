@@ -0,0 +1,141 @@
+// Package webhook posts webhook payloads over HTTP.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// maxResponseBytes bounds what is read from a subscriber's response before
+// it is discarded.
+const maxResponseBytes = 64 << 10
+
+// ErrTargetNotAllowed is returned for URLs and connections that would reach
+// a loopback, private, link-local or otherwise internal address.
+var ErrTargetNotAllowed = errors.New("webhook: target address not allowed")
+
+// internalPrefixes are ranges that are not reachable on the internet but
+// that the netip predicates do not cover.
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which embeds IPv4 addresses
+}
+
+// Sender posts payloads with a bounded timeout. Redirects are not followed:
+// a subscriber answering with one gets its status code recorded instead.
+// Unless private targets are allowed, every connection is checked after DNS
+// resolution, so a name that later resolves to an internal address is
+// refused too.
+type Sender struct {
+	client       *http.Client
+	allowPrivate bool
+}
+
+// NewSender creates a sender. allowPrivate lets webhooks reach internal
+// addresses, for local development and tests.
+func NewSender(timeout time.Duration, allowPrivate bool) *Sender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = checkConn
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the connection, so its address is what the dialer
+	// would check.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Sender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		allowPrivate: allowPrivate,
+	}
+}
+
+// CheckURL returns ErrTargetNotAllowed when the URL's host is, or resolves
+// to, an address webhooks may not reach.
+func (s *Sender) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if s.allowPrivate {
+		return nil
+	}
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !allowedAddr(addr) {
+			return ErrTargetNotAllowed
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !allowedAddr(addr) {
+			return ErrTargetNotAllowed
+		}
+	}
+	return nil
+}
+
+func (s *Sender) Send(ctx context.Context, url string, header http.Header, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	return resp.StatusCode, nil
+}
+
+// checkConn refuses connections to addresses webhooks may not reach. It runs
+// for every address tried, after DNS resolution.
+func checkConn(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !allowedAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrTargetNotAllowed, addrPort.Addr())
+	}
+	return nil
+}
+
+// allowedAddr reports whether addr is a public unicast address.
+func allowedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, p := range internalPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSend(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	s := NewSender(5*time.Second, true)
+	header := http.Header{}
+	header.Set("X-Webhook-Event", "doc.created")
+	code, err := s.Send(context.Background(), receiver.URL+"/hook", header, []byte(`{"id":"1"}`))
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", code, http.StatusAccepted)
+	}
+	if got.Method != http.MethodPost || got.URL.Path != "/hook" {
+		t.Errorf("request = %s %s, want POST /hook", got.Method, got.URL.Path)
+	}
+	if ct := got.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if ev := got.Header.Get("X-Webhook-Event"); ev != "doc.created" {
+		t.Errorf("X-Webhook-Event = %q", ev)
+	}
+	if string(body) != `{"id":"1"}` {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	var followed bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/moved", http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	code, err := NewSender(5*time.Second, true).Send(context.Background(), receiver.URL, nil, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if code != http.StatusTemporaryRedirect || followed {
+		t.Errorf("status = %d, followed = %v; want the redirect recorded", code, followed)
+	}
+}
+
+func TestSendRefusesInternalAddresses(t *testing.T) {
+	var hit bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer receiver.Close()
+
+	_, err := NewSender(5*time.Second, false).Send(context.Background(), receiver.URL, nil, []byte(`{}`))
+	if !errors.Is(err, ErrTargetNotAllowed) {
+		t.Fatalf("err = %v, want ErrTargetNotAllowed", err)
+	}
+	if hit {
+		t.Error("receiver on a loopback address was reached")
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://93.184.216.34/hook", true},
+		{"https://[2606:4700::1111]/hook", true},
+		{"http://127.0.0.1:8080/hook", false},
+		{"http://127.1.2.3/", false},
+		{"http://[::1]/", false},
+		{"http://localhost/", false},
+		{"http://0.0.0.0/", false},
+		{"http://[::]/", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://[fe80::1]/", false},
+		{"http://10.0.0.1/", false},
+		{"http://172.16.5.4/", false},
+		{"http://192.168.1.1/", false},
+		{"http://[fd00::1]/", false},
+		{"http://100.64.0.1/", false},
+		{"http://[::ffff:127.0.0.1]/", false},
+		{"http://[::ffff:10.0.0.1]/", false},
+		{"http://[64:ff9b::a00:1]/", false},
+		{"http://224.0.0.1/", false},
+		{"http://255.255.255.255/", false},
+	}
+	s := NewSender(5*time.Second, false)
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := s.CheckURL(context.Background(), tt.url)
+			if tt.allowed && err != nil {
+				t.Errorf("CheckURL: %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrTargetNotAllowed) {
+				t.Errorf("err = %v, want ErrTargetNotAllowed", err)
+			}
+		})
+	}
+
+	if err := NewSender(5*time.Second, true).CheckURL(context.Background(), "http://127.0.0.1/"); err != nil {
+		t.Errorf("with private targets allowed: %v", err)
+	}
+}
@@ -1,15 +1,36 @@
 package config
 
-import "github.com/ilyakaznacheev/cleanenv"
+import (
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
 
 // Config holds app configuration loaded from env.
 type Config struct {
-	AppPort       string `env:"APP_PORT" env-default:"8080"`
-	DBDSN         string `env:"DB_DSN" env-required:"true"`
-	CORSOrigins   string `env:"CORS_ORIGINS" env-default:"http://localhost:5173"`
-	LogLevel      string `env:"LOG_LEVEL" env-default:"info"`
-	WSMaxBinBytes int64  `env:"WS_MAX_BIN_BYTES" env-default:"1048576"`
-	WSMaxTextBytes int64 `env:"WS_MAX_TEXT_BYTES" env-default:"65536"`
+	AppPort            string        `env:"APP_PORT" env-default:"8080"`
+	DBDSN              string        `env:"DB_DSN" env-required:"true"`
+	CORSOrigins        string        `env:"CORS_ORIGINS" env-default:"http://localhost:5173"`
+	LogLevel           string        `env:"LOG_LEVEL" env-default:"info"`
+	WSMaxBinBytes      int64         `env:"WS_MAX_BIN_BYTES" env-default:"1048576"`
+	WSMaxTextBytes     int64         `env:"WS_MAX_TEXT_BYTES" env-default:"65536"`
+	TrashRetention     time.Duration `env:"TRASH_RETENTION" env-default:"720h"`
+	TrashPurgeInterval time.Duration `env:"TRASH_PURGE_INTERVAL" env-default:"1h"`
+	WebhookInterval    time.Duration `env:"WEBHOOK_DELIVERY_INTERVAL" env-default:"5s"`
+	WebhookPrivateIPs  bool          `env:"WEBHOOK_ALLOW_PRIVATE_TARGETS" env-default:"false"`
+	SessionTTL         time.Duration `env:"SESSION_TTL" env-default:"720h"`
+	AdminUserIDs       string        `env:"ADMIN_USER_IDS"`
+	CookieSecure       bool          `env:"COOKIE_SECURE" env-default:"false"`
+	ShareLinkSecret    string        `env:"SHARE_LINK_SECRET"`
+	ShareSessionTTL    time.Duration `env:"SHARE_SESSION_TTL" env-default:"12h"`
+	OIDCIssuer         string        `env:"OIDC_ISSUER"`
+	OIDCClientID       string        `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret   string        `env:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL    string        `env:"OIDC_REDIRECT_URL" env-default:"http://localhost:8080/auth/oidc/callback"`
+	OIDCScopes         string        `env:"OIDC_SCOPES" env-default:"openid email profile"`
+	OIDCGroupsClaim    string        `env:"OIDC_GROUPS_CLAIM" env-default:"groups"`
+	OIDCPostLoginURL   string        `env:"OIDC_POST_LOGIN_URL" env-default:"http://localhost:5173/"`
+	OIDCCacheTTL       time.Duration `env:"OIDC_CACHE_TTL" env-default:"1h"`
 }
 
 func Load() (*Config, error) {
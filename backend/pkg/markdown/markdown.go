@@ -0,0 +1,322 @@
+// Package markdown renders the Markdown subset used in comments to HTML
+// that is safe to insert into a page.
+//
+// The subset is paragraphs, hard line breaks, **bold**, *italic*, `code`,
+// fenced code blocks, [links](https://example.com) and bulleted or numbered
+// lists. The output is built from scratch rather than filtered: all input
+// text is escaped, so raw HTML such as script tags or event handler
+// attributes comes out as visible text, and the only elements produced are
+// p, br, strong, em, code, pre, a, ul, ol and li. Links keep their href only
+// when its scheme is in an allowlist.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// allowedSchemes are the link schemes kept in hrefs. Links with any other
+// scheme, or none, are rendered as their text.
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// listItem matches a list item: a bullet or a number followed by a dot or
+// a parenthesis, then the item's text.
+var listItem = regexp.MustCompile(`^ {0,3}(?:([-*+])|(\d{1,9})[.)])[ \t]+(.*)$`)
+
+// ToHTML renders text to sanitized HTML.
+func ToHTML(text string) string {
+	var b strings.Builder
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		b.WriteString("<p>")
+		for i, line := range paragraph {
+			if i > 0 {
+				b.WriteString("<br>")
+			}
+			inline(&b, strings.TrimSpace(line))
+		}
+		b.WriteString("</p>")
+		paragraph = nil
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+			i++
+		case strings.HasPrefix(strings.TrimLeft(line, " "), "```"):
+			flush()
+			i = codeBlock(&b, lines, i)
+		case listItem.MatchString(line):
+			flush()
+			i = list(&b, lines, i)
+		default:
+			paragraph = append(paragraph, line)
+			i++
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// codeBlock writes the fenced code block starting at lines[start] and
+// returns the index of the line after it. An unclosed fence runs to the end.
+func codeBlock(b *strings.Builder, lines []string, start int) int {
+	b.WriteString("<pre><code>")
+	i := start + 1
+	for ; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimLeft(lines[i], " "), "```") {
+			i++
+			break
+		}
+		if i > start+1 {
+			b.WriteString("\n")
+		}
+		b.WriteString(html.EscapeString(lines[i]))
+	}
+	b.WriteString("</code></pre>")
+	return i
+}
+
+// list writes the list starting at lines[start] and returns the index of
+// the line after it. The list ends at a blank line, a line that is not an
+// indented continuation, or an item of the other kind.
+func list(b *strings.Builder, lines []string, start int) int {
+	first := listItem.FindStringSubmatch(lines[start])
+	ordered := first[1] == ""
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+		b.WriteString("<ol")
+		if n, _ := strconv.Atoi(first[2]); n != 1 {
+			b.WriteString(` start="` + strconv.Itoa(n) + `"`)
+		}
+		b.WriteString(">")
+	} else {
+		b.WriteString("<ul>")
+	}
+
+	i := start
+	for i < len(lines) {
+		m := listItem.FindStringSubmatch(lines[i])
+		if m == nil || (m[1] == "") != ordered {
+			break
+		}
+		b.WriteString("<li>")
+		inline(b, strings.TrimSpace(m[3]))
+		i++
+		for i < len(lines) && isContinuation(lines[i]) {
+			b.WriteString("<br>")
+			inline(b, strings.TrimSpace(lines[i]))
+			i++
+		}
+		b.WriteString("</li>")
+	}
+	b.WriteString("</" + tag + ">")
+	return i
+}
+
+func isContinuation(line string) bool {
+	return strings.TrimSpace(line) != "" &&
+		(strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) &&
+		!listItem.MatchString(line)
+}
+
+// inline writes a line of text with its emphasis, code spans and links.
+func inline(b *strings.Builder, s string) {
+	for i := 0; i < len(s); {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 < len(s) && strings.IndexByte("\\`*_[]()#+-.!", s[i+1]) >= 0 {
+				b.WriteString(html.EscapeString(s[i+1 : i+2]))
+				i += 2
+				continue
+			}
+		case '`':
+			if n := codeSpan(b, s, i); n > 0 {
+				i += n
+				continue
+			}
+		case '*', '_':
+			if n := emphasis(b, s, i); n > 0 {
+				i += n
+				continue
+			}
+		case '[':
+			if n := link(b, s, i); n > 0 {
+				i += n
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(html.EscapeString(s[i : i+size]))
+		i += size
+	}
+}
+
+// codeSpan writes the code span opening at s[i] and returns its length, or
+// 0 when the backticks are not closed by a run of the same length.
+func codeSpan(b *strings.Builder, s string, i int) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == '`' {
+		n++
+	}
+	fence := s[i : i+n]
+	for j := i + n; j < len(s); {
+		k := strings.Index(s[j:], fence)
+		if k < 0 {
+			return 0
+		}
+		end := j + k
+		run := 0
+		for end+run < len(s) && s[end+run] == '`' {
+			run++
+		}
+		if run == n {
+			b.WriteString("<code>")
+			b.WriteString(html.EscapeString(strings.TrimSpace(s[i+n : end])))
+			b.WriteString("</code>")
+			return end + n - i
+		}
+		j = end + run
+	}
+	return 0
+}
+
+// emphasis writes the bold or italic span opening at s[i] and returns its
+// length, or 0 when the delimiter is not closed. Underscores inside words,
+// as in snake_case, are not delimiters.
+func emphasis(b *strings.Builder, s string, i int) int {
+	c := s[i]
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return 0
+	}
+	if strings.HasPrefix(s[i:], string([]byte{c, c})) {
+		delim := string([]byte{c, c})
+		if k := strings.Index(s[i+2:], delim); k > 0 {
+			end := i + 2 + k
+			// A longer run closes with its last two delimiters, so ***x***
+			// is bold italic.
+			for end+2 < len(s) && s[end+2] == c {
+				end++
+			}
+			if emphasized(s, i+2, end, end+2, c) {
+				b.WriteString("<strong>")
+				inline(b, s[i+2:end])
+				b.WriteString("</strong>")
+				return end + 2 - i
+			}
+		}
+		return 0
+	}
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != c {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == c {
+			j++
+			continue
+		}
+		if j > i+1 && emphasized(s, i+1, j, j+1, c) {
+			b.WriteString("<em>")
+			inline(b, s[i+1:j])
+			b.WriteString("</em>")
+			return j + 1 - i
+		}
+		return 0
+	}
+	return 0
+}
+
+// emphasized reports whether s[start:end] can be emphasized with the
+// delimiter c, which closes before s[after]: the text must not start or end
+// with a space, and a closing underscore must not be followed by a word
+// character.
+func emphasized(s string, start, end, after int, c byte) bool {
+	first, _ := utf8.DecodeRuneInString(s[start:end])
+	last, _ := utf8.DecodeLastRuneInString(s[start:end])
+	if unicode.IsSpace(first) || unicode.IsSpace(last) {
+		return false
+	}
+	return c != '_' || after >= len(s) || !isWordByte(s[after])
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 0x80 || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// link writes the link opening at s[i] and returns its length, or 0 when
+// the text is not followed by a destination in parentheses or contains
+// another link.
+func link(b *strings.Builder, s string, i int) int {
+	depth := 0
+	closing := -1
+	for j := i; j < len(s) && closing < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				closing = j
+			}
+		}
+	}
+	if closing < 0 || closing+1 >= len(s) || s[closing+1] != '(' {
+		return 0
+	}
+	// Parentheses in the destination must be balanced.
+	end := -1
+	depth = 0
+	for j := closing + 1; j < len(s) && end < 0; j++ {
+		switch s[j] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				end = j
+			}
+		}
+	}
+	text := s[i+1 : closing]
+	// Links cannot contain links.
+	if end < 0 || strings.Contains(text, "](") {
+		return 0
+	}
+	href, ok := safeURL(strings.TrimSpace(s[closing+2 : end]))
+	if !ok {
+		inline(b, text)
+		return end + 1 - i
+	}
+	b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer">`)
+	inline(b, text)
+	b.WriteString("</a>")
+	return end + 1 - i
+}
+
+// safeURL returns the link destination when its scheme is allowed.
+func safeURL(raw string) (string, bool) {
+	if raw == "" || strings.ContainsFunc(raw, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || !allowedSchemes[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+	return u.String(), true
+}
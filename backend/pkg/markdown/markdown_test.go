@@ -0,0 +1,112 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const relAttr = ` rel="nofollow noopener noreferrer"`
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"paragraphs and breaks", "one\ntwo\n\nthree", "<p>one<br>two</p><p>three</p>"},
+		{"code span", "`<b>code</b>`", "<p><code>&lt;b&gt;code&lt;/b&gt;</code></p>"},
+		{"code block", "```\n<script>\n```", "<pre><code>&lt;script&gt;</code></pre>"},
+		{"list", "- a\n- b", "<ul><li>a</li><li>b</li></ul>"},
+		{"ordered list start", "3. a\n4. b", `<ol start="3"><li>a</li><li>b</li></ol>`},
+		{"snake case", "snake_case_name", "<p>snake_case_name</p>"},
+
+		// Raw HTML is escaped, never passed through.
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"event handler", `<img src=x onerror="alert(1)">`, "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>"},
+		{"html in list", "- <b onclick=alert(1)>item</b>", "<ul><li>&lt;b onclick=alert(1)&gt;item&lt;/b&gt;</li></ul>"},
+
+		// Links outside the scheme allowlist keep only their text.
+		{"javascript href", "[x](javascript:alert(1))", "<p>x</p>"},
+		{"mixed case javascript href", "[x](JaVaScRiPt:alert(1))", "<p>x</p>"},
+		{"data href", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>"},
+		{"vbscript href", "[x](vbscript:msgbox(1))", "<p>x</p>"},
+		{"entity encoded colon", "[x](javascript&#58;alert(1))", "<p>x</p>"},
+		{"entity encoded scheme", "[x](&#106;avascript:alert(1))", "<p>x</p>"},
+		{"percent encoded scheme", "[x](java%73cript:alert(1))", "<p>x</p>"},
+		{"leading tab", "[x](\tjavascript:alert(1))", "<p>x</p>"},
+		{"scheme relative", "[x](//evil.example.com)", "<p>x</p>"},
+		{"relative", "[x](/docs)", "<p>x</p>"},
+		{"emphasis in rejected link", "[**x**](javascript:alert(1))", "<p><strong>x</strong></p>"},
+
+		// Allowed links are escaped inside the attribute.
+		{"https href", "[x](https://example.com/?a=1&b=2)", `<p><a href="https://example.com/?a=1&amp;b=2"` + relAttr + `>x</a></p>`},
+		{"mailto href", "[x](mailto:a@example.com)", `<p><a href="mailto:a@example.com"` + relAttr + `>x</a></p>`},
+		{"double quote breakout", `[x](https://example.com/"onmouseover="alert(1))`,
+			`<p><a href="https://example.com/%22onmouseover=%22alert%281%29"` + relAttr + `>x</a></p>`},
+		{"single quote breakout", `[x](https://example.com/'><script>alert(1)</script>)`,
+			`<p><a href="https://example.com/%27%3E%3Cscript%3Ealert%281%29%3C/script%3E"` + relAttr + `>x</a></p>`},
+
+		// Nesting.
+		{"bold italic", "***both***", "<p><strong><em>both</em></strong></p>"},
+		{"strong in em", "*em **strong** em*", "<p><em>em <strong>strong</strong> em</em></p>"},
+		{"link in strong", "**bold [link](https://example.com) inside**",
+			`<p><strong>bold <a href="https://example.com"` + relAttr + `>link</a> inside</strong></p>`},
+		{"emphasis in link", "[**bold *and italic* link**](https://example.com)",
+			`<p><a href="https://example.com"` + relAttr + `><strong>bold <em>and italic</em> link</strong></a></p>`},
+		{"link in link", "[outer [inner](https://a.example) text](https://b.example)",
+			`<p>[outer <a href="https://a.example"` + relAttr + `>inner</a> text](https://b.example)</p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToHTML(tt.in); got != tt.want {
+				t.Errorf("ToHTML(%q)\n got %s\nwant %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+var (
+	tagPattern  = regexp.MustCompile(`<(/?)([^\s>/]*)([^>]*)>`)
+	allowedTags = map[string]bool{"p": true, "br": true, "strong": true, "em": true, "code": true, "pre": true, "a": true, "ul": true, "ol": true, "li": true}
+	anchorAttrs = regexp.MustCompile(`^ href="(?:https?|mailto):[^"<>]*"` + relAttr + `$`)
+	listAttrs   = regexp.MustCompile(`^ start="\d+"$`)
+)
+
+// checkSafe reports what in out is not one of the elements and attributes
+// the renderer may produce.
+func checkSafe(t *testing.T, in string, out string) {
+	t.Helper()
+	for _, m := range tagPattern.FindAllStringSubmatch(out, -1) {
+		tag, attrs := m[2], m[3]
+		switch {
+		case !allowedTags[tag]:
+			t.Fatalf("ToHTML(%q) produced element %q: %s", in, tag, out)
+		case attrs == "" || m[1] == "/":
+		case tag == "a" && anchorAttrs.MatchString(attrs):
+		case tag == "ol" && listAttrs.MatchString(attrs):
+		default:
+			t.Fatalf("ToHTML(%q) produced attributes %q on %s: %s", in, attrs, tag, out)
+		}
+	}
+}
+
+func FuzzToHTML(f *testing.F) {
+	for _, seed := range []string{
+		"**bold** *em* `code` [x](https://example.com)",
+		"- [x](javascript:alert(1))\n1. <script>",
+		`[x](https://example.com/"onmouseover="alert(1))`,
+		"[*[a](https://a.example)*](mailto:a@example.com)",
+		"```\n</code></pre><script>\n```",
+		"***x*** __y__ _z_ \\*w\\*",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		out := ToHTML(in)
+		checkSafe(t, in, out)
+		if strings.Contains(strings.ToLower(out), "<script") {
+			t.Fatalf("ToHTML(%q) produced a script tag: %s", in, out)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package yjs
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+var (
+	ErrUnexpectedEOF = errors.New("yjs: unexpected end of update")
+	ErrMalformed     = errors.New("yjs: malformed update")
+)
+
+// decoder reads the lib0 primitives used by the Yjs v1 update format.
+type decoder struct {
+	buf []byte
+	pos int
+}
+
+func newDecoder(buf []byte) *decoder {
+	return &decoder{buf: buf}
+}
+
+func (d *decoder) hasContent() bool {
+	return d.pos < len(d.buf)
+}
+
+func (d *decoder) readUint8() (byte, error) {
+	if d.pos >= len(d.buf) {
+		return 0, ErrUnexpectedEOF
+	}
+	b := d.buf[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *decoder) readVarUint() (uint64, error) {
+	var num uint64
+	var shift uint
+	for {
+		b, err := d.readUint8()
+		if err != nil {
+			return 0, err
+		}
+		num |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return num, nil
+		}
+		shift += 7
+		if shift > 63 {
+			return 0, ErrMalformed
+		}
+	}
+}
+
+func (d *decoder) readVarInt() (int64, error) {
+	b, err := d.readUint8()
+	if err != nil {
+		return 0, err
+	}
+	num := int64(b & 0x3f)
+	sign := int64(1)
+	if b&0x40 != 0 {
+		sign = -1
+	}
+	shift := uint(6)
+	for b&0x80 != 0 {
+		if b, err = d.readUint8(); err != nil {
+			return 0, err
+		}
+		num |= int64(b&0x7f) << shift
+		shift += 7
+		if shift > 62 {
+			return 0, ErrMalformed
+		}
+	}
+	return sign * num, nil
+}
+
+func (d *decoder) readBytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.buf)-d.pos) {
+		return nil, ErrUnexpectedEOF
+	}
+	out := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return out, nil
+}
+
+func (d *decoder) readVarBytes() ([]byte, error) {
+	n, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	return d.readBytes(n)
+}
+
+func (d *decoder) readVarString() (string, error) {
+	b, err := d.readVarBytes()
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(b) {
+		return "", ErrMalformed
+	}
+	return string(b), nil
+}
+
+// skipAny consumes one lib0 "any" value and returns its raw encoding.
+func (d *decoder) skipAny() ([]byte, error) {
+	start := d.pos
+	if err := d.skipAnyValue(0); err != nil {
+		return nil, err
+	}
+	return d.buf[start:d.pos], nil
+}
+
+func (d *decoder) skipAnyValue(depth int) error {
+	if depth > 64 {
+		return ErrMalformed
+	}
+	tag, err := d.readUint8()
+	if err != nil {
+		return err
+	}
+	switch tag {
+	case 127, 126, 121, 120: // undefined, null, false, true
+		return nil
+	case 125: // varint
+		_, err = d.readVarInt()
+	case 124: // float32
+		_, err = d.readBytes(4)
+	case 123, 122: // float64, bigint64
+		_, err = d.readBytes(8)
+	case 119: // string
+		_, err = d.readVarString()
+	case 118: // object
+		var n uint64
+		if n, err = d.readVarUint(); err != nil {
+			return err
+		}
+		for i := uint64(0); i < n; i++ {
+			if _, err = d.readVarString(); err != nil {
+				return err
+			}
+			if err = d.skipAnyValue(depth + 1); err != nil {
+				return err
+			}
+		}
+	case 117: // array
+		var n uint64
+		if n, err = d.readVarUint(); err != nil {
+			return err
+		}
+		for i := uint64(0); i < n; i++ {
+			if err = d.skipAnyValue(depth + 1); err != nil {
+				return err
+			}
+		}
+	case 116: // Uint8Array
+		_, err = d.readVarBytes()
+	default:
+		return ErrMalformed
+	}
+	return err
+}
@@ -0,0 +1,430 @@
+package yjs
+
+import (
+	"sort"
+)
+
+// Item is an integrated struct of a document.
+type Item struct {
+	id          ID
+	origin      *ID
+	rightOrigin *ID
+	parent      *Type
+	parentSub   *string
+	content     content
+	typ         *Type // set when content is a nested type
+	left, right *Item
+	deleted     bool
+	gc          bool
+}
+
+func (it *Item) length() int {
+	return it.content.len()
+}
+
+func (it *Item) visible() bool {
+	return !it.deleted && !it.gc && it.content.countable()
+}
+
+// Type is a shared type of a document: a root type or a nested one such
+// as an XML element.
+type Type struct {
+	Ref   int
+	Name  string
+	item  *Item
+	start *Item
+	attrs map[string]*Item
+}
+
+func newType(ref int, name string, item *Item) *Type {
+	return &Type{Ref: ref, Name: name, item: item, attrs: make(map[string]*Item)}
+}
+
+// deleted reports whether the type or any of its ancestors was deleted.
+func (t *Type) deleted() bool {
+	for p := t; p != nil && p.item != nil; p = p.item.parent {
+		if p.item.deleted {
+			return true
+		}
+	}
+	return false
+}
+
+// Doc is a read-only reconstruction of a Yjs document.
+type Doc struct {
+	share map[string]*Type
+	store map[uint64][]*Item
+}
+
+// Decode integrates a Yjs v1 update, usually the output of
+// Y.encodeStateAsUpdate, into a document.
+func Decode(data []byte) (*Doc, error) {
+	u, err := decodeUpdate(data)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Doc{
+		share: make(map[string]*Type),
+		store: make(map[uint64][]*Item),
+	}
+	doc.integrateUpdate(u)
+	doc.applyDeletes(u.deletes)
+	return doc, nil
+}
+
+// Root returns the root type registered under name, or nil.
+func (d *Doc) Root(name string) *Type {
+	return d.share[name]
+}
+
+// RootNames lists the root types of the document in a stable order.
+func (d *Doc) RootNames() []string {
+	names := make([]string, 0, len(d.share))
+	for name := range d.share {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (d *Doc) root(name string) *Type {
+	t, ok := d.share[name]
+	if !ok {
+		t = newType(typeUnknown, "", nil)
+		d.share[name] = t
+	}
+	return t
+}
+
+// search returns the index of the first item of client that ends after clock.
+func (d *Doc) search(client, clock uint64) int {
+	items := d.store[client]
+	return sort.Search(len(items), func(i int) bool {
+		return items[i].id.Clock+uint64(items[i].length()) > clock
+	})
+}
+
+// find returns the index of the item containing id.
+func (d *Doc) find(id ID) (int, bool) {
+	items := d.store[id.Client]
+	i := d.search(id.Client, id.Clock)
+	if i == len(items) || items[i].id.Clock > id.Clock {
+		return 0, false
+	}
+	return i, true
+}
+
+func (d *Doc) getItem(id ID) *Item {
+	i, ok := d.find(id)
+	if !ok {
+		return nil
+	}
+	return d.store[id.Client][i]
+}
+
+func (d *Doc) insert(it *Item) {
+	items := d.store[it.id.Client]
+	i := sort.Search(len(items), func(i int) bool { return items[i].id.Clock > it.id.Clock })
+	items = append(items, nil)
+	copy(items[i+1:], items[i:])
+	items[i] = it
+	d.store[it.id.Client] = items
+}
+
+// split cuts the item at index i of its client so that a new item starts
+// diff units in, and returns the right half.
+func (d *Doc) split(client uint64, i int, diff int) *Item {
+	left := d.store[client][i]
+	clock := left.id.Clock + uint64(diff)
+	right := &Item{
+		id:          ID{Client: client, Clock: clock},
+		origin:      &ID{Client: client, Clock: clock - 1},
+		rightOrigin: left.rightOrigin,
+		parent:      left.parent,
+		parentSub:   left.parentSub,
+		content:     left.content.splitAt(diff),
+		left:        left,
+		right:       left.right,
+		deleted:     left.deleted,
+		gc:          left.gc,
+	}
+	if left.right != nil {
+		left.right.left = right
+	} else if left.parentSub != nil && left.parent != nil && left.parent.attrs[*left.parentSub] == left {
+		left.parent.attrs[*left.parentSub] = right
+	}
+	left.right = right
+
+	items := append(d.store[client], nil)
+	copy(items[i+2:], items[i+1:])
+	items[i+1] = right
+	d.store[client] = items
+	return right
+}
+
+// cleanStart returns the item starting exactly at id, splitting if needed.
+func (d *Doc) cleanStart(id ID) *Item {
+	i, ok := d.find(id)
+	if !ok {
+		return nil
+	}
+	it := d.store[id.Client][i]
+	if it.id.Clock == id.Clock {
+		return it
+	}
+	return d.split(id.Client, i, int(id.Clock-it.id.Clock))
+}
+
+// cleanEnd returns the item ending exactly at id, splitting if needed.
+func (d *Doc) cleanEnd(id ID) *Item {
+	i, ok := d.find(id)
+	if !ok {
+		return nil
+	}
+	it := d.store[id.Client][i]
+	if diff := int(id.Clock-it.id.Clock) + 1; diff != it.length() {
+		d.split(id.Client, i, diff)
+	}
+	return it
+}
+
+const (
+	unvisited = iota
+	visiting
+	done
+)
+
+// integrateUpdate integrates every struct of u, resolving dependencies on
+// other clients before the structs that reference them.
+func (d *Doc) integrateUpdate(u *update) {
+	state := make(map[*rawItem]int)
+
+	findRaw := func(id ID) *rawItem {
+		structs := u.structs[id.Client]
+		i := sort.Search(len(structs), func(i int) bool {
+			return structs[i].id.Clock+uint64(structs[i].length) > id.Clock
+		})
+		if i == len(structs) || structs[i].id.Clock > id.Clock {
+			return nil
+		}
+		return structs[i]
+	}
+
+	missing := func(raw *rawItem, index int) *rawItem {
+		if index > 0 {
+			if prev := u.structs[raw.id.Client][index-1]; state[prev] != done {
+				return prev
+			}
+		}
+		for _, dep := range []*ID{raw.origin, raw.rightOrigin, raw.parentID} {
+			if dep == nil {
+				continue
+			}
+			if r := findRaw(*dep); r != nil && state[r] != done {
+				return r
+			}
+		}
+		return nil
+	}
+
+	indexOf := func(raw *rawItem) int {
+		structs := u.structs[raw.id.Client]
+		return sort.Search(len(structs), func(i int) bool { return structs[i].id.Clock >= raw.id.Clock })
+	}
+
+	for _, client := range u.clients() {
+		for _, raw := range u.structs[client] {
+			if state[raw] == done {
+				continue
+			}
+			stack := []*rawItem{raw}
+			for len(stack) > 0 {
+				top := stack[len(stack)-1]
+				if state[top] == done {
+					stack = stack[:len(stack)-1]
+					continue
+				}
+				state[top] = visiting
+				if dep := missing(top, indexOf(top)); dep != nil && state[dep] != visiting {
+					stack = append(stack, dep)
+					continue
+				}
+				d.integrate(top)
+				state[top] = done
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+}
+
+func idEqual(a, b *ID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// integrate places a decoded struct into the document following the YATA
+// rules used by Yjs. Structs whose dependencies are unknown are dropped.
+func (d *Doc) integrate(raw *rawItem) {
+	if raw.gc {
+		d.insert(&Item{id: raw.id, content: content{ref: refDeleted, length: raw.length}, deleted: true, gc: true})
+		return
+	}
+	if d.getItem(raw.id) != nil {
+		return
+	}
+
+	it := &Item{
+		id:          raw.id,
+		origin:      raw.origin,
+		rightOrigin: raw.rightOrigin,
+		parentSub:   raw.parentSub,
+		content:     raw.content,
+	}
+	if raw.origin != nil {
+		if it.left = d.cleanEnd(*raw.origin); it.left == nil {
+			return
+		}
+	}
+	if raw.rightOrigin != nil {
+		if it.right = d.cleanStart(*raw.rightOrigin); it.right == nil {
+			return
+		}
+	}
+
+	var parent *Type
+	switch {
+	case (it.left != nil && it.left.gc) || (it.right != nil && it.right.gc):
+	case raw.parentKey != "":
+		parent = d.root(raw.parentKey)
+	case raw.parentID != nil:
+		p := d.getItem(*raw.parentID)
+		if p == nil {
+			return
+		}
+		parent = p.typ
+	case it.left != nil:
+		parent, it.parentSub = it.left.parent, it.left.parentSub
+	case it.right != nil:
+		parent, it.parentSub = it.right.parent, it.right.parentSub
+	}
+	if parent == nil {
+		d.insert(&Item{id: raw.id, content: content{ref: refDeleted, length: raw.length}, deleted: true, gc: true})
+		return
+	}
+	it.parent = parent
+	if it.content.ref == refType {
+		it.typ = newType(it.content.typeRef, it.content.typeName, it)
+	}
+
+	if (it.left == nil && (it.right == nil || it.right.left != nil)) || (it.left != nil && it.left.right != it.right) {
+		left := it.left
+		var o *Item
+		switch {
+		case left != nil:
+			o = left.right
+		case it.parentSub != nil:
+			o = parent.attrs[*it.parentSub]
+			for o != nil && o.left != nil {
+				o = o.left
+			}
+		default:
+			o = parent.start
+		}
+		conflicting := make(map[*Item]bool)
+		beforeOrigin := make(map[*Item]bool)
+		for o != nil && o != it.right {
+			beforeOrigin[o] = true
+			conflicting[o] = true
+			if idEqual(it.origin, o.origin) {
+				if o.id.Client < it.id.Client {
+					left = o
+					clear(conflicting)
+				} else if idEqual(it.rightOrigin, o.rightOrigin) {
+					break
+				}
+			} else if o.origin != nil && beforeOrigin[d.getItem(*o.origin)] {
+				if !conflicting[d.getItem(*o.origin)] {
+					left = o
+					clear(conflicting)
+				}
+			} else {
+				break
+			}
+			o = o.right
+		}
+		it.left = left
+	}
+
+	if it.left != nil {
+		it.right = it.left.right
+		it.left.right = it
+	} else {
+		var r *Item
+		if it.parentSub != nil {
+			r = parent.attrs[*it.parentSub]
+			for r != nil && r.left != nil {
+				r = r.left
+			}
+		} else {
+			r = parent.start
+			parent.start = it
+		}
+		it.right = r
+	}
+	if it.right != nil {
+		it.right.left = it
+	} else if it.parentSub != nil {
+		parent.attrs[*it.parentSub] = it
+		if it.left != nil {
+			d.delete(it.left)
+		}
+	}
+
+	d.insert(it)
+	if parent.deleted() || (it.parentSub != nil && it.right != nil) {
+		d.delete(it)
+	}
+}
+
+func (d *Doc) delete(it *Item) {
+	if it.deleted {
+		return
+	}
+	it.deleted = true
+	if it.typ == nil {
+		return
+	}
+	for n := it.typ.start; n != nil; n = n.right {
+		d.delete(n)
+	}
+	for _, n := range it.typ.attrs {
+		d.delete(n)
+	}
+}
+
+func (d *Doc) applyDeletes(deletes map[uint64][]deleteRange) {
+	for client, ranges := range deletes {
+		for _, r := range ranges {
+			end := r.clock + r.length
+			for i := d.search(client, r.clock); i < len(d.store[client]); i++ {
+				it := d.store[client][i]
+				if it.id.Clock >= end {
+					break
+				}
+				if it.deleted {
+					continue
+				}
+				if it.id.Clock < r.clock {
+					d.split(client, i, int(r.clock-it.id.Clock))
+					continue
+				}
+				if it.id.Clock+uint64(it.length()) > end {
+					d.split(client, i, int(end-it.id.Clock))
+				}
+				d.delete(it)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package yjs
+
+import "testing"
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"insert", textABC, "abc"},
+		{"delete", textADeleted, "ad"},
+		{"surrogate pair", textEmoji, "a😀b"},
+		{"xml blocks", prosemirror, "Hi\nBye"},
+		{"missing origin", textInsertX, ""},
+		{"delete set only", textDeleteB, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractText(tt.data)
+			if err != nil {
+				t.Fatalf("ExtractText: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExtractText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRoots(t *testing.T) {
+	doc, err := Decode(prosemirror)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.RootNames(); len(got) != 1 || got[0] != "prosemirror" {
+		t.Fatalf("RootNames = %v", got)
+	}
+	root := doc.Root("prosemirror")
+	if root.start == nil || root.start.typ == nil || root.start.typ.Name != "paragraph" || root.start.typ.Ref != TypeXMLElement {
+		t.Fatalf("first child is not a paragraph element")
+	}
+	if doc.Root("missing") != nil {
+		t.Error("Root of an unknown name is not nil")
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":             {},
+		"truncated header":  {0x01, 0x01},
+		"truncated content": textABC[:len(textABC)-3],
+		"unknown content":   {0x01, 0x01, 0x01, 0x00, 0x0f, 0x01, 0x01, 't', 0x00},
+		"empty string":      {0x01, 0x01, 0x01, 0x00, 0x04, 0x01, 0x01, 't', 0x00, 0x00},
+		"varuint overflow":  {0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(data); err == nil {
+				t.Error("Decode succeeded")
+			}
+		})
+	}
+}
+
+func TestConcurrentInsertsOrderByClient(t *testing.T) {
+	for _, order := range [][][]byte{{textConcurrentA, textConcurrentB}, {textConcurrentB, textConcurrentA}} {
+		merged, err := MergeUpdates(order...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := ExtractText(merged); got != "ab" {
+			t.Errorf("text = %q, want %q", got, "ab")
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package yjs
+
+import "unicode/utf16"
+
+// encoder writes the lib0 primitives used by the Yjs v1 update format.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) writeUint8(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *encoder) writeVarUint(n uint64) {
+	for n >= 0x80 {
+		e.buf = append(e.buf, byte(n)|0x80)
+		n >>= 7
+	}
+	e.buf = append(e.buf, byte(n))
+}
+
+func (e *encoder) writeVarBytes(b []byte) {
+	e.writeVarUint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) writeVarString(s string) {
+	e.writeVarBytes([]byte(s))
+}
+
+func (e *encoder) writeRaw(b []byte) {
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) writeID(id ID) {
+	e.writeVarUint(id.Client)
+	e.writeVarUint(id.Clock)
+}
+
+func (e *encoder) writeContent(c *content) {
+	switch c.ref {
+	case refDeleted:
+		e.writeVarUint(uint64(c.length))
+	case refJSON:
+		e.writeVarUint(uint64(len(c.elems)))
+		for _, el := range c.elems {
+			e.writeVarBytes(el)
+		}
+	case refBinary, refEmbed:
+		e.writeVarBytes(c.raw)
+	case refString:
+		e.writeVarString(string(utf16.Decode(c.str)))
+	case refFormat:
+		e.writeVarString(c.key)
+		e.writeVarBytes(c.raw)
+	case refType:
+		e.writeVarUint(uint64(c.typeRef))
+		if c.typeRef == TypeXMLElement || c.typeRef == TypeXMLHook {
+			e.writeVarString(c.typeName)
+		}
+	case refAny:
+		e.writeVarUint(uint64(len(c.elems)))
+		for _, el := range c.elems {
+			e.writeRaw(el)
+		}
+	case refDoc:
+		e.writeVarString(c.key)
+		e.writeRaw(c.raw)
+	}
+}
+
+// writeItem writes a struct. The parent is only written when neither origin
+// is known, as the receiver infers it from its neighbours otherwise.
+func (e *encoder) writeItem(origin, rightOrigin *ID, parentKey string, parentID *ID, parentSub *string, c *content) {
+	info := c.ref & 0x1f
+	if origin != nil {
+		info |= 0x80
+	}
+	if rightOrigin != nil {
+		info |= 0x40
+	}
+	if parentSub != nil {
+		info |= 0x20
+	}
+	e.writeUint8(info)
+	if origin != nil {
+		e.writeID(*origin)
+	}
+	if rightOrigin != nil {
+		e.writeID(*rightOrigin)
+	}
+	if origin == nil && rightOrigin == nil {
+		if parentID != nil {
+			e.writeVarUint(0)
+			e.writeID(*parentID)
+		} else {
+			e.writeVarUint(1)
+			e.writeVarString(parentKey)
+		}
+		if parentSub != nil {
+			e.writeVarString(*parentSub)
+		}
+	}
+	e.writeContent(c)
+}
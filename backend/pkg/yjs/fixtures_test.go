@@ -0,0 +1,109 @@
+package yjs
+
+// Updates as encoded by Yjs 13.6. Each fixture lists the script producing it
+// in a document whose clientID is set as noted; the bytes are the result of
+// Y.encodeStateAsUpdate (or of the update event for incremental ones).
+var (
+	// d.getText('t').insert(0, 'abc') with clientID 1.
+	textABC = []byte{
+		0x01,             // one client
+		0x01, 0x01, 0x00, // one struct of client 1 from clock 0
+		0x04, 0x01, 0x01, 't', // ContentString, parent is the root type "t"
+		0x03, 'a', 'b', 'c',
+		0x00, // empty delete set
+	}
+
+	// d.getText('t').insert(0, 'abcd'); d.getText('t').delete(1, 2) with
+	// clientID 1. The deleted item keeps its length as ContentDeleted.
+	textADeleted = []byte{
+		0x01,
+		0x03, 0x01, 0x00,
+		0x04, 0x01, 0x01, 't', 0x01, 'a',
+		0x81, 0x01, 0x00, 0x02, // ContentDeleted of 2, origin (1,0)
+		0x84, 0x01, 0x02, 0x01, 'd', // origin (1,2)
+		0x01, 0x01, 0x01, 0x01, 0x02, // client 1 deleted clock 1, length 2
+	}
+
+	// The update of d.getText('t').insert(2, 'c') by clientID 1 after
+	// textAB: it continues the client's clock.
+	textAB = []byte{
+		0x01, 0x01, 0x01, 0x00,
+		0x04, 0x01, 0x01, 't', 0x02, 'a', 'b',
+		0x00,
+	}
+	textABThenC = []byte{
+		0x01, 0x01, 0x01, 0x02,
+		0x84, 0x01, 0x01, 0x01, 'c', // origin (1,1)
+		0x00,
+	}
+
+	// The update of clientID 2 inserting 'X' between 'a' and 'b' of textAB.
+	textInsertX = []byte{
+		0x01, 0x01, 0x02, 0x00,
+		0xc4, 0x01, 0x00, 0x01, 0x01, 0x01, 'X', // origin (1,0), right origin (1,1)
+		0x00,
+	}
+
+	// The update deleting 'b' from textABC, by any client.
+	textDeleteB = []byte{0x00, 0x01, 0x01, 0x01, 0x01, 0x01}
+
+	// Concurrent inserts of 'a' by clientID 1 and 'b' by clientID 2 into an
+	// empty d.getText('t'); Yjs orders the lower client first.
+	textConcurrentA = []byte{0x01, 0x01, 0x01, 0x00, 0x04, 0x01, 0x01, 't', 0x01, 'a', 0x00}
+	textConcurrentB = []byte{0x01, 0x01, 0x02, 0x00, 0x04, 0x01, 0x01, 't', 0x01, 'b', 0x00}
+
+	// d.getText('t').insert(0, 'a😀b') with clientID 1. Strings are UTF-8 on
+	// the wire and counted in UTF-16 units, so the struct is 4 long.
+	textEmoji = []byte{
+		0x01, 0x01, 0x01, 0x00,
+		0x04, 0x01, 0x01, 't', 0x06, 'a', 0xf0, 0x9f, 0x98, 0x80, 'b',
+		0x00,
+	}
+
+	// A y-prosemirror document with clientID 1:
+	//
+	//	const frag = d.getXmlFragment('prosemirror')
+	//	const p1 = new Y.XmlElement('paragraph')
+	//	frag.insert(0, [p1])
+	//	p1.insert(0, [new Y.XmlText('Hi')])
+	//	const p2 = new Y.XmlElement('paragraph')
+	//	frag.insert(1, [p2])
+	//	p2.insert(0, [new Y.XmlText('Bye')])
+	prosemirror = []byte{
+		0x01,
+		0x06, 0x01, 0x00,
+		// (1,0) paragraph in the root fragment
+		0x07, 0x01, 0x0b, 'p', 'r', 'o', 's', 'e', 'm', 'i', 'r', 'r', 'o', 'r',
+		0x03, 0x09, 'p', 'a', 'r', 'a', 'g', 'r', 'a', 'p', 'h',
+		// (1,1) text node in the paragraph (1,0)
+		0x07, 0x00, 0x01, 0x00, 0x06,
+		// (1,2) "Hi" in the text node (1,1), clocks 2 and 3
+		0x04, 0x00, 0x01, 0x01, 0x02, 'H', 'i',
+		// (1,4) paragraph after (1,0)
+		0x87, 0x01, 0x00, 0x03, 0x09, 'p', 'a', 'r', 'a', 'g', 'r', 'a', 'p', 'h',
+		// (1,5) text node in the paragraph (1,4)
+		0x07, 0x00, 0x01, 0x04, 0x06,
+		// (1,6) "Bye" in the text node (1,5)
+		0x04, 0x00, 0x01, 0x05, 0x03, 'B', 'y', 'e',
+		0x00,
+	}
+
+	// Y.encodeRelativePosition(Y.createRelativePositionFromTypeIndex(t, 1))
+	// on textABC: the position before 'b'.
+	relBeforeB = []byte{0x00, 0x01, 0x01, 0x00}
+	// The same with assoc -1: the position after 'a'.
+	relAfterA = []byte{0x00, 0x01, 0x00, 0x41}
+)
+
+var fixtures = map[string][]byte{
+	"textABC":         textABC,
+	"textADeleted":    textADeleted,
+	"textAB":          textAB,
+	"textABThenC":     textABThenC,
+	"textInsertX":     textInsertX,
+	"textDeleteB":     textDeleteB,
+	"textConcurrentA": textConcurrentA,
+	"textConcurrentB": textConcurrentB,
+	"textEmoji":       textEmoji,
+	"prosemirror":     prosemirror,
+}
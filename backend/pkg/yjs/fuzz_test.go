@@ -0,0 +1,31 @@
+package yjs
+
+import "testing"
+
+// FuzzDecode checks that arbitrary input never panics the decoder or the
+// code working on what it decodes.
+func FuzzDecode(f *testing.F) {
+	for _, data := range fixtures {
+		f.Add(data)
+	}
+	f.Add(relBeforeB)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if doc, err := Decode(data); err == nil {
+			_ = doc.Text()
+			for _, name := range doc.RootNames() {
+				doc.ResolvePosition(RelativePosition{TypeName: name})
+			}
+			_, _ = MapText(data, func(s string) string { return s })
+		}
+		if merged, err := MergeUpdates(data, textABC); err == nil {
+			if _, err := Decode(merged); err != nil {
+				t.Errorf("merged update does not decode: %v", err)
+			}
+		}
+		if rp, err := DecodeRelativePosition(data); err == nil {
+			if doc, err := Decode(prosemirror); err == nil {
+				doc.ResolvePosition(rp)
+			}
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package yjs
+
+import "sort"
+
+// MergeUpdates combines Yjs v1 updates into a single update, like
+// Y.mergeUpdates. Structs found in several updates are written once, gaps
+// between the structs of a client are kept as skips and the delete sets are
+// united. Merging an update into a document's state yields the state the
+// document has once the update is applied.
+func MergeUpdates(updates ...[]byte) ([]byte, error) {
+	structs := make(map[uint64][]*rawItem)
+	deletes := make(map[uint64][]deleteRange)
+	for _, data := range updates {
+		u, err := decodeUpdate(data)
+		if err != nil {
+			return nil, err
+		}
+		for client, items := range u.structs {
+			structs[client] = append(structs[client], items...)
+		}
+		for client, ranges := range u.deletes {
+			deletes[client] = append(deletes[client], ranges...)
+		}
+	}
+
+	var e encoder
+	clients := sortedClients(structs)
+	e.writeVarUint(uint64(len(clients)))
+	for _, client := range clients {
+		items := mergeStructs(client, structs[client])
+		e.writeVarUint(uint64(len(items)))
+		e.writeVarUint(client)
+		e.writeVarUint(items[0].id.Clock)
+		for _, it := range items {
+			if it.gc {
+				e.writeUint8(it.content.ref)
+				e.writeVarUint(uint64(it.length))
+				continue
+			}
+			e.writeItem(it.origin, it.rightOrigin, it.parentKey, it.parentID, it.parentSub, &it.content)
+		}
+	}
+
+	clients = sortedClients(deletes)
+	e.writeVarUint(uint64(len(clients)))
+	for _, client := range clients {
+		ranges := mergeDeletes(deletes[client])
+		e.writeVarUint(client)
+		e.writeVarUint(uint64(len(ranges)))
+		for _, r := range ranges {
+			e.writeVarUint(r.clock)
+			e.writeVarUint(r.length)
+		}
+	}
+	return e.buf, nil
+}
+
+func sortedClients[T any](m map[uint64]T) []uint64 {
+	out := make([]uint64, 0, len(m))
+	for client := range m {
+		out = append(out, client)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// mergeStructs orders the structs of a client by clock, drops the parts
+// already covered by an earlier struct and fills the gaps with skips.
+func mergeStructs(client uint64, items []*rawItem) []*rawItem {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].id.Clock != items[j].id.Clock {
+			return items[i].id.Clock < items[j].id.Clock
+		}
+		return items[i].length > items[j].length
+	})
+	out := make([]*rawItem, 0, len(items))
+	var next uint64
+	for _, it := range items {
+		end := it.id.Clock + uint64(it.length)
+		if len(out) > 0 {
+			if end <= next {
+				continue
+			}
+			if it.id.Clock < next {
+				it = it.sliceFrom(next)
+			} else if it.id.Clock > next {
+				out = append(out, &rawItem{
+					id:      ID{Client: client, Clock: next},
+					length:  int(it.id.Clock - next),
+					gc:      true,
+					content: content{ref: refSkip},
+				})
+			}
+		}
+		out = append(out, it)
+		next = end
+	}
+	return out
+}
+
+// sliceFrom returns the part of the struct that starts at clock. Like a
+// split item in Yjs, the part refers to the element before it as its origin.
+func (r *rawItem) sliceFrom(clock uint64) *rawItem {
+	offset := int(clock - r.id.Clock)
+	right := &rawItem{id: ID{Client: r.id.Client, Clock: clock}, length: r.length - offset, gc: r.gc}
+	if !r.gc {
+		c := r.content
+		right.content = c.splitAt(offset)
+		right.origin = &ID{Client: r.id.Client, Clock: clock - 1}
+		right.rightOrigin = r.rightOrigin
+	}
+	return right
+}
+
+// mergeDeletes sorts delete ranges and joins the ones that overlap or touch.
+func mergeDeletes(ranges []deleteRange) []deleteRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].clock < ranges[j].clock })
+	out := make([]deleteRange, 0, len(ranges))
+	for _, r := range ranges {
+		if n := len(out); n > 0 && r.clock <= out[n-1].clock+out[n-1].length {
+			last := &out[n-1]
+			if end := r.clock + r.length; end > last.clock+last.length {
+				last.length = end - last.clock
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package yjs
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMergeUpdates(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates [][]byte
+		want    string
+	}{
+		{"single", [][]byte{textABC}, "abc"},
+		{"continued clock", [][]byte{textAB, textABThenC}, "abc"},
+		{"continued clock reversed", [][]byte{textABThenC, textAB}, "abc"},
+		{"other client between", [][]byte{textAB, textInsertX}, "aXb"},
+		{"other client first", [][]byte{textInsertX, textAB}, "aXb"},
+		{"delete set", [][]byte{textABC, textDeleteB}, "ac"},
+		{"covered structs", [][]byte{textABC, textAB, textABThenC}, "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := MergeUpdates(tt.updates...)
+			if err != nil {
+				t.Fatalf("MergeUpdates: %v", err)
+			}
+			got, err := ExtractText(merged)
+			if err != nil {
+				t.Fatalf("ExtractText: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("text = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeUpdatesIdempotent(t *testing.T) {
+	for name, data := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			once, err := MergeUpdates(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			twice, err := MergeUpdates(once, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(once, twice) {
+				t.Errorf("merging an update into itself changed it:\n%x\n%x", once, twice)
+			}
+			want, _ := ExtractText(data)
+			if got, _ := ExtractText(twice); got != want {
+				t.Errorf("text = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// Structs that depend on missing ones keep their clock when merged and are
+// integrated once the missing structs arrive.
+func TestMergeUpdatesGap(t *testing.T) {
+	gap, err := MergeUpdates(textABThenC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ExtractText(gap); got != "" {
+		t.Errorf("text before the gap is filled = %q", got)
+	}
+	filled, err := MergeUpdates(gap, textAB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ExtractText(filled); got != "abc" {
+		t.Errorf("text = %q, want %q", got, "abc")
+	}
+}
+
+func TestMergeUpdatesSkip(t *testing.T) {
+	// 'z' by client 1 at clock 5, after 'e' at clock 4, which is missing.
+	later := []byte{0x01, 0x01, 0x01, 0x05, 0x84, 0x01, 0x04, 0x01, 'z', 0x00}
+	merged, err := MergeUpdates(textABC, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := decodeUpdate(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structs := u.structs[1]
+	if len(structs) != 2 || structs[0].id.Clock != 0 || structs[1].id.Clock != 5 {
+		t.Fatalf("structs of client 1 are not at clocks 0 and 5")
+	}
+	if got, _ := ExtractText(merged); got != "abc" {
+		t.Errorf("text = %q, want %q", got, "abc")
+	}
+}
+
+func TestMergeUpdatesMalformed(t *testing.T) {
+	if _, err := MergeUpdates(textABC, []byte{0x01}); err == nil {
+		t.Error("MergeUpdates accepted a truncated update")
+	}
+}
@@ -0,0 +1,215 @@
+package yjs
+
+// RelativePosition is a position anchored to an item of a document rather
+// than to an offset, as produced by Y.encodeRelativePosition. It keeps
+// pointing at the same character however the text around it is edited.
+type RelativePosition struct {
+	// Item is the character the position sits before, or after when Assoc
+	// is negative.
+	Item *ID
+	// TypeName names a root type for positions at the start or end of it.
+	TypeName string
+	// Type is a nested type for positions at the start or end of it.
+	Type *ID
+	// Assoc is negative when the position sticks to the character on its
+	// left.
+	Assoc int
+}
+
+// DecodeRelativePosition reads the binary encoding of a relative position.
+func DecodeRelativePosition(data []byte) (RelativePosition, error) {
+	d := newDecoder(data)
+	var rp RelativePosition
+	kind, err := d.readVarUint()
+	if err != nil {
+		return rp, err
+	}
+	switch kind {
+	case 0:
+		if rp.Item, err = readID(d); err != nil {
+			return rp, err
+		}
+	case 1:
+		if rp.TypeName, err = d.readVarString(); err != nil {
+			return rp, err
+		}
+	case 2:
+		if rp.Type, err = readID(d); err != nil {
+			return rp, err
+		}
+	default:
+		return rp, ErrMalformed
+	}
+	if d.hasContent() {
+		assoc, err := d.readVarInt()
+		if err != nil {
+			return rp, err
+		}
+		rp.Assoc = int(assoc)
+	}
+	if d.hasContent() {
+		return rp, ErrMalformed
+	}
+	return rp, nil
+}
+
+// AbsolutePosition is a relative position resolved against a document.
+type AbsolutePosition struct {
+	// Pos is the offset from the start of the root type. Inside XML
+	// fragments it is counted the way ProseMirror counts positions, so it
+	// can be compared with editor selections.
+	Pos int
+	// Deleted reports that the anchored character, or a type containing it,
+	// has been deleted.
+	Deleted bool
+}
+
+// leafNodes are the editor nodes without content. They take up a single
+// position where other elements take an opening and a closing one.
+var leafNodes = map[string]bool{
+	"hardBreak":      true,
+	"horizontalRule": true,
+	"image":          true,
+}
+
+// ResolvePosition returns where a relative position currently points. It
+// reports false when the position refers to items the document does not
+// contain yet.
+func (d *Doc) ResolvePosition(rp RelativePosition) (AbsolutePosition, bool) {
+	var (
+		t       *Type
+		index   int
+		deleted bool
+	)
+	switch {
+	case rp.Item != nil:
+		right := d.getItem(*rp.Item)
+		if right == nil || right.parent == nil || right.parentSub != nil {
+			return AbsolutePosition{}, false
+		}
+		t = right.parent
+		deleted = right.deleted
+		if t.item == nil || !t.item.deleted {
+			if !right.deleted && right.content.countable() {
+				index = int(rp.Item.Clock - right.id.Clock)
+				if rp.Assoc < 0 {
+					index++
+				}
+			}
+			for n := right.left; n != nil; n = n.left {
+				if n.visible() {
+					index += n.length()
+				}
+			}
+		}
+	case rp.TypeName != "":
+		if t = d.share[rp.TypeName]; t == nil {
+			return AbsolutePosition{}, false
+		}
+	case rp.Type != nil:
+		it := d.getItem(*rp.Type)
+		if it == nil || it.typ == nil {
+			return AbsolutePosition{}, false
+		}
+		t = it.typ
+	default:
+		return AbsolutePosition{}, false
+	}
+	if rp.Item == nil && rp.Assoc >= 0 {
+		index = t.length()
+	}
+	return AbsolutePosition{Pos: t.position(index), Deleted: deleted || t.deleted()}, true
+}
+
+// length is the number of visible units in the type.
+func (t *Type) length() int {
+	n := 0
+	for it := t.start; it != nil; it = it.right {
+		if it.visible() {
+			n += it.length()
+		}
+	}
+	return n
+}
+
+// xml reports whether the type holds XML nodes. Root types carry no type
+// reference in updates, so they are recognised by their children.
+func (t *Type) xml() bool {
+	switch t.Ref {
+	case TypeXMLElement, TypeXMLFragment, TypeXMLText:
+		return true
+	case typeUnknown:
+		for it := t.start; it != nil; it = it.right {
+			if it.typ != nil && it.typ.Ref != typeUnknown {
+				return it.typ.xml()
+			}
+		}
+	}
+	return false
+}
+
+// nodeSize is the number of positions the node covers in the editor.
+func (t *Type) nodeSize() int {
+	switch t.Ref {
+	case TypeXMLText:
+		return t.length()
+	case TypeXMLElement:
+		if t.start == nil && leafNodes[t.Name] {
+			return 1
+		}
+		return 2 + t.contentSize()
+	default:
+		return 1
+	}
+}
+
+func (t *Type) contentSize() int {
+	size := 0
+	for it := t.start; it != nil; it = it.right {
+		size += it.size()
+	}
+	return size
+}
+
+func (it *Item) size() int {
+	if !it.visible() {
+		return 0
+	}
+	if it.typ != nil {
+		return it.typ.nodeSize()
+	}
+	return it.length()
+}
+
+// position maps an index within the type to an offset from the start of its
+// root, following y-prosemirror: entering an element costs one position and
+// the outermost fragment has no opening of its own.
+func (t *Type) position(index int) int {
+	if !t.xml() {
+		return index
+	}
+	pos := 0
+	if t.Ref == TypeXMLText {
+		pos = index
+	} else if t.item == nil || !t.item.deleted {
+		i := 0
+		for n := t.start; n != nil && i < index; n = n.right {
+			if n.visible() {
+				i += n.length()
+				pos += n.size()
+			}
+		}
+		pos++
+	}
+	for t.item != nil && t.item.parent != nil {
+		parent := t.item.parent
+		if parent.item == nil || !parent.item.deleted {
+			pos++
+			for n := parent.start; n != nil && n.typ != t; n = n.right {
+				pos += n.size()
+			}
+		}
+		t = parent
+	}
+	return pos - 1
+}
@@ -0,0 +1,82 @@
+package yjs
+
+import "testing"
+
+func TestDecodeRelativePosition(t *testing.T) {
+	rp, err := DecodeRelativePosition(relAfterA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rp.Item == nil || *rp.Item != (ID{Client: 1, Clock: 0}) || rp.Assoc != -1 {
+		t.Errorf("DecodeRelativePosition = %+v", rp)
+	}
+	for name, data := range map[string][]byte{
+		"empty":        {},
+		"unknown kind": {0x03, 0x00},
+		"trailing":     {0x00, 0x01, 0x01, 0x00, 0x00},
+	} {
+		if _, err := DecodeRelativePosition(data); err == nil {
+			t.Errorf("%s: DecodeRelativePosition succeeded", name)
+		}
+	}
+}
+
+func TestResolvePosition(t *testing.T) {
+	// 'X' by client 2 at the start of textABC.
+	insertX := []byte{0x01, 0x01, 0x02, 0x00, 0x44, 0x01, 0x00, 0x01, 'X', 0x00}
+	withX, err := MergeUpdates(textABC, insertX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutB, err := MergeUpdates(textABC, textDeleteB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		rel         []byte
+		want        int
+		wantDeleted bool
+	}{
+		{"before b", textABC, relBeforeB, 1, false},
+		{"after a", textABC, relAfterA, 1, false},
+		{"shifted by insert", withX, relBeforeB, 2, false},
+		{"anchor deleted", withoutB, relBeforeB, 1, true},
+		{"type end", textABC, []byte{0x01, 0x01, 't', 0x00}, 3, false},
+		{"type start", textABC, []byte{0x01, 0x01, 't', 0x41}, 0, false},
+		// Before the 'y' of "Bye", counted as in ProseMirror: the fragment
+		// has no opening and each paragraph has two.
+		{"xml", prosemirror, []byte{0x00, 0x01, 0x07, 0x00}, 6, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Decode(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rp, err := DecodeRelativePosition(tt.rel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := doc.ResolvePosition(rp)
+			if !ok {
+				t.Fatal("ResolvePosition did not resolve")
+			}
+			if got.Pos != tt.want || got.Deleted != tt.wantDeleted {
+				t.Errorf("ResolvePosition = %+v, want pos %d deleted %v", got, tt.want, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestResolvePositionUnknownItem(t *testing.T) {
+	doc, err := Decode(textABC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc.ResolvePosition(RelativePosition{Item: &ID{Client: 9, Clock: 0}}); ok {
+		t.Error("position on an unknown item resolved")
+	}
+}
@@ -0,0 +1,108 @@
+package yjs
+
+import (
+	"math/rand/v2"
+	"sort"
+	"unicode/utf16"
+)
+
+// MapText rebuilds the document as a fresh update owned by a single new
+// client, passing every contiguous run of text through fn. Deleted content
+// and editing history are not carried over, which makes the result suitable
+// as the initial state of a new document.
+func MapText(data []byte, fn func(string) string) ([]byte, error) {
+	doc, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	w := &rewriter{client: uint64(rand.Uint32()), fn: fn}
+	for _, name := range doc.RootNames() {
+		w.writeType(doc.share[name], name, nil)
+	}
+	return w.bytes(), nil
+}
+
+type rewriter struct {
+	client  uint64
+	clock   uint64
+	count   uint64
+	structs encoder
+	fn      func(string) string
+}
+
+// put appends an item and returns the ID of its last element.
+func (w *rewriter) put(origin *ID, parentKey string, parentID *ID, parentSub *string, c *content) ID {
+	w.structs.writeItem(origin, nil, parentKey, parentID, parentSub, c)
+	w.count++
+	w.clock += uint64(c.len())
+	return ID{Client: w.client, Clock: w.clock - 1}
+}
+
+func (w *rewriter) writeType(t *Type, key string, parentID *ID) {
+	var last *ID
+	var run []uint16
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		text := w.fn(string(utf16.Decode(run)))
+		run = nil
+		if text == "" {
+			return
+		}
+		c := content{ref: refString, str: utf16.Encode([]rune(text))}
+		end := w.put(last, key, parentID, nil, &c)
+		last = &end
+	}
+
+	for n := t.start; n != nil; n = n.right {
+		if n.deleted || n.gc {
+			continue
+		}
+		if n.content.ref == refString {
+			run = append(run, n.content.str...)
+			continue
+		}
+		flush()
+		c := n.content
+		end := w.put(last, key, parentID, nil, &c)
+		last = &end
+		if n.typ != nil {
+			w.writeType(n.typ, "", &end)
+		}
+	}
+	flush()
+
+	keys := make([]string, 0, len(t.attrs))
+	for k := range t.attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		n := t.attrs[k]
+		if n.deleted || n.gc {
+			continue
+		}
+		sub := k
+		c := n.content
+		id := w.put(nil, key, parentID, &sub, &c)
+		if n.typ != nil {
+			w.writeType(n.typ, "", &id)
+		}
+	}
+}
+
+func (w *rewriter) bytes() []byte {
+	var e encoder
+	if w.count == 0 {
+		e.writeVarUint(0)
+	} else {
+		e.writeVarUint(1)
+		e.writeVarUint(w.count)
+		e.writeVarUint(w.client)
+		e.writeVarUint(0)
+		e.writeRaw(w.structs.buf)
+	}
+	e.writeVarUint(0) // empty delete set
+	return e.buf
+}
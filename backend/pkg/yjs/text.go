@@ -0,0 +1,51 @@
+package yjs
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// Text returns the visible text of the type and its descendants. Nested XML
+// elements are treated as blocks and separated by newlines.
+func (t *Type) Text() string {
+	var buf []uint16
+	t.appendText(&buf)
+	return strings.TrimSpace(string(utf16.Decode(buf)))
+}
+
+func (t *Type) appendText(buf *[]uint16) {
+	for n := t.start; n != nil; n = n.right {
+		if n.deleted || n.gc {
+			continue
+		}
+		switch n.content.ref {
+		case refString:
+			*buf = append(*buf, n.content.str...)
+		case refType:
+			n.typ.appendText(buf)
+			if n.typ.Ref == TypeXMLElement && len(*buf) > 0 && (*buf)[len(*buf)-1] != '\n' {
+				*buf = append(*buf, '\n')
+			}
+		}
+	}
+}
+
+// Text returns the visible text of every root type of the document.
+func (d *Doc) Text() string {
+	parts := make([]string, 0, len(d.share))
+	for _, name := range d.RootNames() {
+		if text := d.share[name].Text(); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ExtractText decodes a Yjs update and returns the plain text it contains.
+func ExtractText(data []byte) (string, error) {
+	doc, err := Decode(data)
+	if err != nil {
+		return "", err
+	}
+	return doc.Text(), nil
+}
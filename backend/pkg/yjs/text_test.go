@@ -0,0 +1,49 @@
+package yjs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMapText(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"text", textABC, "ABC"},
+		{"deleted content dropped", textADeleted, "AD"},
+		{"xml", prosemirror, "HI\nBYE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := MapText(tt.data, strings.ToUpper)
+			if err != nil {
+				t.Fatalf("MapText: %v", err)
+			}
+			got, err := ExtractText(out)
+			if err != nil {
+				t.Fatalf("ExtractText: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("text = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// A rewritten document is a plain update: it merges and decodes like any
+// other.
+func TestMapTextRoundTrip(t *testing.T) {
+	out, err := MapText(prosemirror, func(s string) string { return s })
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := MergeUpdates(out, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ExtractText(merged); got != "Hi\nBye" {
+		t.Errorf("text = %q, want %q", got, "Hi\nBye")
+	}
+}
@@ -0,0 +1,333 @@
+package yjs
+
+import (
+	"sort"
+	"unicode/utf16"
+)
+
+// Content references as written in the info byte of an item.
+const (
+	refGC      = 0
+	refDeleted = 1
+	refJSON    = 2
+	refBinary  = 3
+	refString  = 4
+	refEmbed   = 5
+	refFormat  = 6
+	refType    = 7
+	refAny     = 8
+	refDoc     = 9
+	refSkip    = 10
+)
+
+// Shared type references carried by ContentType.
+const (
+	TypeArray       = 0
+	TypeMap         = 1
+	TypeText        = 2
+	TypeXMLElement  = 3
+	TypeXMLFragment = 4
+	TypeXMLHook     = 5
+	TypeXMLText     = 6
+	typeUnknown     = -1
+)
+
+// ID identifies a single element inserted by a client.
+type ID struct {
+	Client uint64
+	Clock  uint64
+}
+
+// content is the payload of an item. Values that the server never
+// interprets are kept in their raw encoding so they can be written back.
+type content struct {
+	ref      byte
+	str      []uint16 // refString, UTF-16 code units as counted by Yjs
+	length   int      // refDeleted
+	elems    [][]byte // refJSON (raw strings) and refAny (raw values)
+	raw      []byte   // refBinary, refEmbed, refFormat value, refDoc
+	key      string   // refFormat key, refDoc guid
+	typeRef  int
+	typeName string // XML node name or hook name
+}
+
+func (c *content) len() int {
+	switch c.ref {
+	case refString:
+		return len(c.str)
+	case refDeleted:
+		return c.length
+	case refJSON, refAny:
+		return len(c.elems)
+	default:
+		return 1
+	}
+}
+
+func (c *content) countable() bool {
+	return c.ref != refDeleted && c.ref != refFormat
+}
+
+// splitAt cuts the content after offset units and returns the right part.
+func (c *content) splitAt(offset int) content {
+	right := *c
+	switch c.ref {
+	case refString:
+		right.str = append([]uint16(nil), c.str[offset:]...)
+		c.str = c.str[:offset:offset]
+	case refDeleted:
+		right.length = c.length - offset
+		c.length = offset
+	case refJSON, refAny:
+		right.elems = append([][]byte(nil), c.elems[offset:]...)
+		c.elems = c.elems[:offset:offset]
+	}
+	return right
+}
+
+func (c *content) text() string {
+	return string(utf16.Decode(c.str))
+}
+
+// rawItem is a struct exactly as decoded from an update.
+type rawItem struct {
+	id          ID
+	length      int
+	gc          bool // GC struct: a deleted range without content
+	origin      *ID
+	rightOrigin *ID
+	parentKey   string
+	parentID    *ID
+	parentSub   *string
+	content     content
+}
+
+type deleteRange struct {
+	clock  uint64
+	length uint64
+}
+
+// update is the decoded form of a Yjs v1 update.
+type update struct {
+	structs map[uint64][]*rawItem
+	deletes map[uint64][]deleteRange
+}
+
+func decodeUpdate(buf []byte) (*update, error) {
+	d := newDecoder(buf)
+	u := &update{
+		structs: make(map[uint64][]*rawItem),
+		deletes: make(map[uint64][]deleteRange),
+	}
+
+	numClients, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < numClients; i++ {
+		numStructs, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		client, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		clock, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		for j := uint64(0); j < numStructs; j++ {
+			item, skip, err := readStruct(d, ID{Client: client, Clock: clock})
+			if err != nil {
+				return nil, err
+			}
+			if !skip {
+				u.structs[client] = append(u.structs[client], item)
+			}
+			clock += uint64(item.length)
+		}
+	}
+
+	if err := u.readDeleteSet(d); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func readStruct(d *decoder, id ID) (*rawItem, bool, error) {
+	info, err := d.readUint8()
+	if err != nil {
+		return nil, false, err
+	}
+	item := &rawItem{id: id}
+
+	switch info & 0x1f {
+	case refGC, refSkip:
+		n, err := d.readVarUint()
+		if err != nil {
+			return nil, false, err
+		}
+		item.length = int(n)
+		item.gc = true
+		return item, info&0x1f == refSkip, nil
+	}
+
+	if info&0x80 != 0 {
+		if item.origin, err = readID(d); err != nil {
+			return nil, false, err
+		}
+	}
+	if info&0x40 != 0 {
+		if item.rightOrigin, err = readID(d); err != nil {
+			return nil, false, err
+		}
+	}
+	if info&0xc0 == 0 {
+		isKey, err := d.readVarUint()
+		if err != nil {
+			return nil, false, err
+		}
+		if isKey == 1 {
+			if item.parentKey, err = d.readVarString(); err != nil {
+				return nil, false, err
+			}
+		} else if item.parentID, err = readID(d); err != nil {
+			return nil, false, err
+		}
+		if info&0x20 != 0 {
+			sub, err := d.readVarString()
+			if err != nil {
+				return nil, false, err
+			}
+			item.parentSub = &sub
+		}
+	}
+
+	if item.content, err = readContent(d, info&0x1f); err != nil {
+		return nil, false, err
+	}
+	item.length = item.content.len()
+	if item.length == 0 {
+		return nil, false, ErrMalformed
+	}
+	return item, false, nil
+}
+
+func readID(d *decoder) (*ID, error) {
+	client, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	clock, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	return &ID{Client: client, Clock: clock}, nil
+}
+
+func readContent(d *decoder, ref byte) (content, error) {
+	c := content{ref: ref, typeRef: typeUnknown}
+	var err error
+	switch ref {
+	case refDeleted:
+		var n uint64
+		n, err = d.readVarUint()
+		c.length = int(n)
+	case refJSON:
+		var n uint64
+		if n, err = d.readVarUint(); err != nil {
+			return c, err
+		}
+		for i := uint64(0); i < n; i++ {
+			var b []byte
+			if b, err = d.readVarBytes(); err != nil {
+				return c, err
+			}
+			c.elems = append(c.elems, b)
+		}
+	case refBinary:
+		c.raw, err = d.readVarBytes()
+	case refString:
+		var s string
+		s, err = d.readVarString()
+		c.str = utf16.Encode([]rune(s))
+	case refEmbed:
+		c.raw, err = d.readVarBytes()
+	case refFormat:
+		if c.key, err = d.readVarString(); err != nil {
+			return c, err
+		}
+		c.raw, err = d.readVarBytes()
+	case refType:
+		var t uint64
+		if t, err = d.readVarUint(); err != nil {
+			return c, err
+		}
+		c.typeRef = int(t)
+		if c.typeRef == TypeXMLElement || c.typeRef == TypeXMLHook {
+			c.typeName, err = d.readVarString()
+		}
+	case refAny:
+		var n uint64
+		if n, err = d.readVarUint(); err != nil {
+			return c, err
+		}
+		for i := uint64(0); i < n; i++ {
+			var b []byte
+			if b, err = d.skipAny(); err != nil {
+				return c, err
+			}
+			c.elems = append(c.elems, b)
+		}
+	case refDoc:
+		if c.key, err = d.readVarString(); err != nil {
+			return c, err
+		}
+		c.raw, err = d.skipAny()
+	default:
+		return c, ErrMalformed
+	}
+	return c, err
+}
+
+func (u *update) readDeleteSet(d *decoder) error {
+	if !d.hasContent() {
+		return nil
+	}
+	numClients, err := d.readVarUint()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < numClients; i++ {
+		client, err := d.readVarUint()
+		if err != nil {
+			return err
+		}
+		n, err := d.readVarUint()
+		if err != nil {
+			return err
+		}
+		for j := uint64(0); j < n; j++ {
+			clock, err := d.readVarUint()
+			if err != nil {
+				return err
+			}
+			length, err := d.readVarUint()
+			if err != nil {
+				return err
+			}
+			u.deletes[client] = append(u.deletes[client], deleteRange{clock: clock, length: length})
+		}
+	}
+	return nil
+}
+
+func (u *update) clients() []uint64 {
+	out := make([]uint64, 0, len(u.structs))
+	for client := range u.structs {
+		out = append(out, client)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
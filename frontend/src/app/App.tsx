@@ -1,13 +1,28 @@
-import { BrowserRouter, Routes, Route } from "react-router-dom";
+import { BrowserRouter, Routes, Route, Navigate, useLocation } from "react-router-dom";
 import { HomePage } from "./components/home-page";
 import { EditorPage } from "./components/editor-page";
+import { LoginPage } from "./components/login-page";
+import { useCurrentUser } from "@/features/auth/api";
+
+// RequireAuth sends visitors without a session to the login page and back.
+function RequireAuth({ children }: { children: React.ReactElement }) {
+  const location = useLocation();
+  const { data: user, isLoading } = useCurrentUser();
+  if (isLoading) return null;
+  if (!user) {
+    const next = encodeURIComponent(location.pathname + location.search);
+    return <Navigate to={`/login?next=${next}`} replace />;
+  }
+  return children;
+}
 
 export default function App() {
   return (
     <BrowserRouter>
       <Routes>
-        <Route path="/" element={<HomePage />} />
-        <Route path="/doc/:documentId" element={<EditorPage />} />
+        <Route path="/login" element={<LoginPage />} />
+        <Route path="/" element={<RequireAuth><HomePage /></RequireAuth>} />
+        <Route path="/doc/:documentId" element={<RequireAuth><EditorPage /></RequireAuth>} />
       </Routes>
     </BrowserRouter>
   );
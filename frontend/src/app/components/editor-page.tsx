@@ -27,6 +27,7 @@ import toast from "react-hot-toast";
 import { CommentsPanel } from "./comments-panel";
 import { ExportMenu } from "./export-menu";
 import { GuestJoinModal } from "./guest-join-modal";
+import { useCurrentUser } from "@/features/auth/api";
 import { useDoc, useUpdateDoc } from "@/features/docs/api";
 import {
   Comment,
@@ -173,6 +174,7 @@ export function EditorPage() {
   const ydoc = useMemo(() => new Y.Doc(), [documentId]);
   const awareness = useMemo(() => new Awareness(ydoc), [ydoc]);
 
+  const { data: currentUser } = useCurrentUser();
   const { data: doc, isLoading: docLoading, error: docError } = useDoc(
     documentId
   );
@@ -223,7 +225,7 @@ export function EditorPage() {
     toast.error(message);
   }, []);
 
-  const { isConnected, sendPresence, sendCommentAdd, sendCommentUpdate, sendSnapshot } =
+  const { isConnected, sendPresence, sendSnapshot } =
     useDocSocket({
       docId: documentId,
       name: displayName ?? undefined,
@@ -272,6 +274,12 @@ export function EditorPage() {
 
   useEffect(() => {
     if (!documentId) return;
+    // Signed-in users appear under their account name
+    if (currentUser) {
+      setDisplayName(currentUser.name);
+      setGuestModalOpen(false);
+      return;
+    }
     if (isOwner(documentId)) {
       if (!displayName) {
         setDisplayName("Owner");
@@ -283,7 +291,7 @@ export function EditorPage() {
     if (!displayName) {
       setGuestModalOpen(true);
     }
-  }, [displayName, documentId]);
+  }, [currentUser, displayName, documentId]);
 
   useEffect(() => {
     if (!doc) return;
@@ -444,11 +452,7 @@ export function EditorPage() {
         toPos: selection.to,
         text,
       });
-      queryClient.setQueryData<Comment[]>(["comments", documentId], (prev) => {
-        if (!prev) return [comment];
-        return [comment, ...prev];
-      });
-      sendCommentAdd(comment);
+      handleCommentAdd(comment);
     } catch (error) {
       toast.error("Failed to add comment");
       console.error(error);
@@ -463,11 +467,7 @@ export function EditorPage() {
         commentId,
         resolved: true,
       });
-      queryClient.setQueryData<Comment[]>(["comments", documentId], (prev) => {
-        if (!prev) return [updated];
-        return prev.map((item) => (item.id === updated.id ? updated : item));
-      });
-      sendCommentUpdate(updated);
+      handleCommentUpdate(updated);
     } catch (error) {
       toast.error("Failed to resolve comment");
       console.error(error);
@@ -1,7 +1,8 @@
-import { FileText, Plus, Trash2, Users } from "lucide-react";
+import { FileText, LogOut, Plus, Trash2, Users } from "lucide-react";
 import { useEffect, useMemo, useState } from "react";
 import { useNavigate } from "react-router-dom";
 import toast from "react-hot-toast";
+import { useCurrentUser, useLogout } from "@/features/auth/api";
 import { useCreateDoc } from "@/features/docs/api";
 import {
   loadRecentDocs,
@@ -99,6 +100,8 @@ function DocumentCard({
 export function HomePage() {
   const navigate = useNavigate();
   const createDoc = useCreateDoc();
+  const { data: user } = useCurrentUser();
+  const logout = useLogout();
   const [recentDocs, setRecentDocs] = useState<RecentDoc[]>([]);
 
   useEffect(() => {
@@ -144,14 +147,24 @@ export function HomePage() {
       <nav className="bg-white border-b border-gray-200 shadow-sm">
         <div className="max-w-7xl mx-auto px-8 h-16 flex items-center justify-between">
           <div className="text-xl font-semibold text-gray-900">CollabDocs</div>
-          <button
-            onClick={handleCreate}
-            disabled={createDoc.isPending}
-            className="flex items-center gap-2 px-4 py-2 bg-indigo-600 text-white rounded-lg hover:bg-indigo-700 transition-colors shadow-sm hover:shadow-md font-medium"
-          >
-            <Plus className="w-4 h-4" />
-            {createDoc.isPending ? "Creating..." : "New Document"}
-          </button>
+          <div className="flex items-center gap-3">
+            {user && <span className="text-sm text-gray-600">{user.name}</span>}
+            <button
+              onClick={() => logout.mutate(undefined, { onSuccess: () => navigate("/login") })}
+              className="p-2 rounded-lg text-gray-500 hover:text-gray-900 hover:bg-gray-100 transition-colors"
+              title="Sign out"
+            >
+              <LogOut className="w-4 h-4" />
+            </button>
+            <button
+              onClick={handleCreate}
+              disabled={createDoc.isPending}
+              className="flex items-center gap-2 px-4 py-2 bg-indigo-600 text-white rounded-lg hover:bg-indigo-700 transition-colors shadow-sm hover:shadow-md font-medium"
+            >
+              <Plus className="w-4 h-4" />
+              {createDoc.isPending ? "Creating..." : "New Document"}
+            </button>
+          </div>
         </div>
       </nav>
 
@@ -0,0 +1,115 @@
+import { useState } from "react";
+import { Navigate, useNavigate, useSearchParams } from "react-router-dom";
+import toast from "react-hot-toast";
+import { useCurrentUser, useLogin, useRegister } from "@/features/auth/api";
+
+const inputClassName =
+  "w-full px-4 py-3 border border-gray-300 rounded-lg focus:outline-none focus:ring-2 focus:ring-indigo-500/50 focus:border-indigo-500 transition-all text-gray-900 placeholder:text-gray-400";
+
+// safeNext only follows redirects within the app.
+function safeNext(next: string | null) {
+  if (!next || !next.startsWith("/") || next.startsWith("//")) return "/";
+  return next;
+}
+
+export function LoginPage() {
+  const navigate = useNavigate();
+  const [searchParams] = useSearchParams();
+  const next = safeNext(searchParams.get("next"));
+  const { data: user } = useCurrentUser();
+  const login = useLogin();
+  const register = useRegister();
+  const [mode, setMode] = useState<"login" | "register">("login");
+  const [email, setEmail] = useState("");
+  const [name, setName] = useState("");
+  const [password, setPassword] = useState("");
+
+  if (user) {
+    return <Navigate to={next} replace />;
+  }
+
+  const isPending = login.isPending || register.isPending;
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    try {
+      if (mode === "register") {
+        await register.mutateAsync({ email: email.trim(), name: name.trim(), password });
+      }
+      await login.mutateAsync({ email: email.trim(), password });
+      navigate(next, { replace: true });
+    } catch (error) {
+      toast.error(mode === "register" ? "Could not create the account" : "Wrong email or password");
+      console.error(error);
+    }
+  };
+
+  return (
+    <div className="min-h-screen bg-gray-50 flex items-center justify-center px-4">
+      <div className="bg-white rounded-xl shadow-2xl w-full max-w-md border border-gray-200">
+        <div className="px-6 pt-6 pb-3">
+          <h2 className="text-xl font-semibold text-gray-900 mb-1.5">
+            {mode === "login" ? "Sign in to CollabDocs" : "Create your account"}
+          </h2>
+          <p className="text-sm text-gray-500">
+            Your name will be visible to other collaborators.
+          </p>
+        </div>
+
+        <form onSubmit={handleSubmit} className="px-6 pb-6 space-y-4">
+          <input
+            type="email"
+            value={email}
+            onChange={(e) => setEmail(e.target.value)}
+            placeholder="Email"
+            autoComplete="email"
+            className={inputClassName}
+            autoFocus
+            required
+          />
+          {mode === "register" && (
+            <input
+              type="text"
+              value={name}
+              onChange={(e) => setName(e.target.value)}
+              placeholder="Name"
+              autoComplete="name"
+              maxLength={40}
+              className={inputClassName}
+              required
+            />
+          )}
+          <input
+            type="password"
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+            placeholder="Password"
+            autoComplete={mode === "login" ? "current-password" : "new-password"}
+            minLength={mode === "register" ? 8 : undefined}
+            className={inputClassName}
+            required
+          />
+
+          <button
+            type="submit"
+            disabled={isPending}
+            className="w-full px-4 py-3 bg-indigo-600 text-white rounded-lg hover:bg-indigo-700 hover:shadow-md transition-all disabled:bg-gray-300 disabled:cursor-not-allowed disabled:shadow-none font-semibold shadow-sm"
+          >
+            {mode === "login" ? "Sign in" : "Create account"}
+          </button>
+
+          <p className="text-sm text-gray-500 text-center">
+            {mode === "login" ? "No account yet? " : "Already have an account? "}
+            <button
+              type="button"
+              onClick={() => setMode(mode === "login" ? "register" : "login")}
+              className="text-indigo-600 hover:text-indigo-700 font-medium"
+            >
+              {mode === "login" ? "Create one" : "Sign in"}
+            </button>
+          </p>
+        </form>
+      </div>
+    </div>
+  );
+}
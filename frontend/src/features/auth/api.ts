@@ -0,0 +1,70 @@
+import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import { isAxiosError } from "axios";
+import { z } from "zod";
+import { api } from "@/lib/api";
+
+const userSchema = z.object({
+  id: z.string(),
+  email: z.string(),
+  name: z.string(),
+});
+
+export type User = z.infer<typeof userSchema>;
+
+const userResponseSchema = z.object({
+  user: userSchema,
+});
+
+export function useCurrentUser() {
+  return useQuery({
+    queryKey: ["me"],
+    retry: false,
+    staleTime: Infinity,
+    queryFn: async (): Promise<User | null> => {
+      try {
+        const response = await api.get("/auth/me");
+        return userResponseSchema.parse(response.data).user;
+      } catch (error) {
+        if (isAxiosError(error) && error.response?.status === 401) {
+          return null;
+        }
+        throw error;
+      }
+    },
+  });
+}
+
+export function useLogin() {
+  const queryClient = useQueryClient();
+  return useMutation({
+    mutationFn: async (input: { email: string; password: string }) => {
+      const response = await api.post("/auth/login", input);
+      return userResponseSchema.parse(response.data).user;
+    },
+    onSuccess: (user) => {
+      queryClient.setQueryData(["me"], user);
+    },
+  });
+}
+
+export function useRegister() {
+  return useMutation({
+    mutationFn: async (input: { email: string; name: string; password: string }) => {
+      const response = await api.post("/auth/register", input);
+      return userResponseSchema.parse(response.data).user;
+    },
+  });
+}
+
+export function useLogout() {
+  const queryClient = useQueryClient();
+  return useMutation({
+    mutationFn: async () => {
+      await api.post("/auth/logout");
+    },
+    onSuccess: () => {
+      queryClient.clear();
+      queryClient.setQueryData(["me"], null);
+    },
+  });
+}
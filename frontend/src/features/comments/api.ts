@@ -17,6 +17,7 @@ export type Comment = z.infer<typeof commentSchema>;
 
 const commentsResponseSchema = z.object({
   comments: z.array(commentSchema),
+  nextCursor: z.string().optional(),
 });
 
 const commentResponseSchema = z.object({
@@ -28,8 +29,18 @@ export function useComments(docId?: string) {
     queryKey: ["comments", docId],
     enabled: Boolean(docId),
     queryFn: async () => {
-      const response = await api.get(`/docs/${docId}/comments`);
-      return commentsResponseSchema.parse(response.data).comments;
+      // The sidebar shows every comment, so follow the pages to the end.
+      const comments: Comment[] = [];
+      let cursor: string | undefined;
+      do {
+        const response = await api.get(`/docs/${docId}/comments`, {
+          params: { limit: 200, cursor },
+        });
+        const page = commentsResponseSchema.parse(response.data);
+        comments.push(...page.comments);
+        cursor = page.nextCursor;
+      } while (cursor);
+      return comments;
     },
   });
 }
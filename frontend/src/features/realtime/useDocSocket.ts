@@ -11,6 +11,8 @@ export type PresencePayload = {
   cursor?: { from: number; to: number };
 };
 
+// Comment events are pushed by the server after REST calls; clients never
+// send them.
 type CommentAddPayload = {
   type: "comment:add";
   comment: Comment;
@@ -128,20 +130,6 @@ export function useDocSocket({
     [send]
   );
 
-  const sendCommentAdd = useCallback(
-    (comment: Comment) => {
-      send(JSON.stringify({ type: "comment:add", comment }));
-    },
-    [send]
-  );
-
-  const sendCommentUpdate = useCallback(
-    (comment: Comment) => {
-      send(JSON.stringify({ type: "comment:update", comment }));
-    },
-    [send]
-  );
-
   const sendSnapshot = useCallback(
     (snapshot: Uint8Array) => {
       const dataB64 = encodeUint8ArrayToBase64(snapshot);
@@ -270,8 +258,6 @@ export function useDocSocket({
   return {
     isConnected,
     sendPresence,
-    sendCommentAdd,
-    sendCommentUpdate,
     sendSnapshot,
   };
 }
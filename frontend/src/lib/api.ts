@@ -7,8 +7,10 @@ if (!baseURL) {
   console.warn("VITE_API_BASE_URL is not set");
 }
 
+// The session lives in an HTTP-only cookie set by POST /auth/login.
 export const api = axios.create({
   baseURL,
+  withCredentials: true,
   headers: {
     "Content-Type": "application/json",
   },